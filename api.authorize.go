@@ -0,0 +1,58 @@
+package gou
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthorizeScopeClaim 路径权限范围校验读取的令牌字段 (空格分隔的字符串或数组)
+var AuthorizeScopeClaim = "scope"
+
+// AuthorizeRoles 读取当前请求用户的角色, 默认读取令牌 roles 字段 (字符串或数组)
+// 角色保存在数据表中时可替换, 如按令牌 sub 查询 user_roles 模型
+var AuthorizeRoles = func(c *gin.Context, claims map[string]interface{}) []string {
+	roles := []string{}
+	switch value := claims["roles"].(type) {
+	case string:
+		roles = append(roles, strings.Fields(value)...)
+	case []interface{}:
+		for _, role := range value {
+			roles = append(roles, fmt.Sprintf("%v", role))
+		}
+	}
+	return roles
+}
+
+// authorize 路径鉴权中间件 (在鉴权中间件之后), 令牌须具备 scopes 中全部权限范围, 以及 roles 中任一角色, 否则返回 403
+// 未通过 JWTGuard 鉴权时返回 401
+func (p Path) authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := JWTClaims(c)
+		if claims == nil {
+			jwtAbort(c, http.StatusUnauthorized, "缺少 Bearer 令牌")
+			return
+		}
+
+		if missing := jwtMissingScopes(claims, AuthorizeScopeClaim, p.Scopes); len(missing) > 0 {
+			jwtAbort(c, http.StatusForbidden, fmt.Sprintf("令牌缺少权限范围: %s", strings.Join(missing, ", ")))
+			return
+		}
+
+		if len(p.Roles) == 0 {
+			return
+		}
+		granted := map[string]bool{}
+		for _, role := range AuthorizeRoles(c, claims) {
+			granted[role] = true
+		}
+		for _, role := range p.Roles {
+			if granted[role] {
+				return
+			}
+		}
+		jwtAbort(c, http.StatusForbidden, fmt.Sprintf("需要角色: %s", strings.Join(p.Roles, ", ")))
+	}
+}
@@ -0,0 +1,237 @@
+package gou
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/maps"
+)
+
+// ResponseSerializer 响应数据序列化方法
+type ResponseSerializer func(data interface{}) ([]byte, error)
+
+// responseSerializers 已注册的响应数据序列化方法 (Content-Type => 序列化方法)
+var responseSerializers = map[string]ResponseSerializer{
+	"application/xml": serializeXML,
+	"text/csv":        serializeCSV,
+}
+
+// responseFormats ?format= 参数的简写
+var responseFormats = map[string]string{
+	"json": "application/json",
+	"xml":  "application/xml",
+	"csv":  "text/csv",
+}
+
+// RegisterSerializer 注册响应数据序列化方法, format 为 ?format= 参数的简写 (可选)
+// 路径配置 out.formats 中列出的类型可按 ?format= 参数或 Accept 请求头协商输出
+func RegisterSerializer(contentType string, serializer ResponseSerializer, format ...string) {
+	responseSerializers[contentType] = serializer
+	for _, name := range format {
+		responseFormats[name] = contentType
+	}
+}
+
+// negotiate 按 ?format= 参数或 Accept 请求头协商响应数据类型, 未配置 out.formats 或无匹配类型时返回空字符串 (默认输出)
+func (out Out) negotiate(c *gin.Context) string {
+	if len(out.Formats) == 0 {
+		return ""
+	}
+
+	allows := map[string]bool{}
+	for _, format := range out.Formats {
+		if contentType, has := responseFormats[format]; has {
+			format = contentType
+		}
+		allows[format] = true
+	}
+
+	if format := c.Query("format"); format != "" {
+		if contentType, has := responseFormats[format]; has {
+			format = contentType
+		}
+		if allows[format] {
+			return format
+		}
+		return ""
+	}
+
+	for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+		contentType := strings.TrimSpace(strings.Split(accept, ";")[0])
+		if allows[contentType] {
+			return contentType
+		}
+	}
+	return ""
+}
+
+// serialize 按响应数据类型序列化, JSON 或未注册序列化方法的类型返回 false
+func serialize(contentType string, data interface{}) ([]byte, bool, error) {
+	serializer, has := responseSerializers[contentType]
+	if contentType == "application/json" || !has {
+		return nil, false, nil
+	}
+	res, err := serializer(data)
+	return res, true, err
+}
+
+// serializeCSV 数据记录清单 (或分页数据中的记录) 转换为 CSV, 首行为字段名称
+func serializeCSV(data interface{}) ([]byte, error) {
+	rows := responseRows(data)
+	columns := []string{}
+	exists := map[string]bool{}
+	for _, row := range rows {
+		for name := range row {
+			if !exists[name] {
+				exists[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(columns)
+	for _, row := range rows {
+		values := []string{}
+		for _, name := range columns {
+			values = append(values, responseText(row[name]))
+		}
+		writer.Write(values)
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// responseRows 响应数据转换为记录清单, 分页数据读取 data 字段
+func responseRows(data interface{}) []map[string]interface{} {
+	switch v := data.(type) {
+	case maps.MapStr:
+		return responseRows(map[string]interface{}(v))
+	case map[string]interface{}:
+		name := "data"
+		if format, has := PaginateFormat["data"]; has {
+			name = format
+		}
+		if items, has := v[name]; has {
+			return responseRows(items)
+		}
+		return []map[string]interface{}{v}
+	case []maps.MapStr:
+		rows := []map[string]interface{}{}
+		for _, row := range v {
+			rows = append(rows, row)
+		}
+		return rows
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		rows := []map[string]interface{}{}
+		for _, item := range v {
+			rows = append(rows, responseRows(item)...)
+		}
+		return rows
+	}
+	return []map[string]interface{}{}
+}
+
+// responseText 字段数值转换为文本, 对象和数组转换为 JSON
+func responseText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case map[string]interface{}, maps.MapStr, []interface{}, []maps.MapStr, []map[string]interface{}:
+		text, _ := jsoniter.Marshal(v)
+		return string(text)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// serializeXML 响应数据转换为 XML, 根节点为 response, 数组元素节点为 item
+func serializeXML(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	err := writeXML(&buf, "response", data)
+	return buf.Bytes(), err
+}
+
+// writeXML 写入 XML 节点
+func writeXML(buf *bytes.Buffer, name string, value interface{}) error {
+	if row, ok := value.(maps.MapStr); ok {
+		value = map[string]interface{}(row)
+	}
+
+	name = xmlName(name)
+	buf.WriteString("<" + name + ">")
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		keys := []string{}
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeXML(buf, key, v[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}, []maps.MapStr, []map[string]interface{}:
+		for _, item := range responseItems(v) {
+			if err := writeXML(buf, "item", item); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := xml.EscapeText(buf, []byte(responseText(v))); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</" + name + ">")
+	return nil
+}
+
+// responseItems 数组转换为 []interface{}
+func responseItems(value interface{}) []interface{} {
+	items := []interface{}{}
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []maps.MapStr:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// xmlName 字段名称转换为有效的 XML 节点名称 (非法字符替换为 _)
+func xmlName(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		valid := r == '_' || r == '-' || r == '.' || r > 0x7f ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !valid || (i == 0 && (r == '-' || r == '.' || (r >= '0' && r <= '9'))) {
+			runes[i] = '_'
+		}
+	}
+	if len(runes) == 0 {
+		return "_"
+	}
+	return string(runes)
+}
@@ -1,12 +1,18 @@
 package gou
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
+	"regexp"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -20,9 +26,14 @@ import (
 	"github.com/yaoapp/xun"
 )
 
+// DefaultMaxBodySize 请求数据默认大小上限 (10MB), 可通过服务配置或路径配置 max_body_size 修改
+var DefaultMaxBodySize int64 = 10 << 20
+
 // APIs 已加载API列表
 var APIs = map[string]*API{}
 
+var reRouteParam = regexp.MustCompile(`([:*])[^/]+`)
+
 // LoadAPIReturn 加载API
 func LoadAPIReturn(source string, name string) (api *API, err error) {
 	defer func() { err = exception.Catch(recover()) }()
@@ -33,8 +44,10 @@ func LoadAPIReturn(source string, name string) (api *API, err error) {
 // LoadAPI 加载API
 func LoadAPI(source string, name string) *API {
 	var input io.Reader = nil
+	format := ""
 	if strings.HasPrefix(source, "file://") {
 		filename := strings.TrimPrefix(source, "file://")
+		format = helper.FileFormat(filename)
 		file, err := os.Open(filename)
 		if err != nil {
 			exception.Err(err, 400).Throw()
@@ -46,7 +59,11 @@ func LoadAPI(source string, name string) *API {
 	}
 
 	http := HTTP{}
-	err := helper.UnmarshalFile(input, &http)
+	content, err := helper.ReadFile(input)
+	if err != nil {
+		exception.Err(err, 400).Throw()
+	}
+	err = helper.UnmarshalFileFormat(bytes.NewReader(helper.ExpandEnv(content)), format, &http)
 	if err != nil {
 		exception.Err(err, 400).Ctx(maps.Map{"name": name}).Throw()
 	}
@@ -61,17 +78,34 @@ func LoadAPI(source string, name string) *API {
 		uniquePathCheck[unique] = true
 	}
 
-	APIs[name] = &API{
+	// 指定接口版本的 API 以 {名称}@{版本} 注册, 同名不同版本的定义可同时载入
+	http.APIVersion = strings.Trim(http.APIVersion, "/")
+	if strings.Contains(http.APIVersion, "/") {
+		exception.New("%s 接口版本 %s 格式错误 (不能包含 /)", 400, name, http.APIVersion).Throw()
+	}
+	key := apiKey(name, http.APIVersion)
+	APIs[key] = &API{
 		Name:   name,
 		Source: source,
 		HTTP:   http,
 		Type:   "http",
 	}
-	return APIs[name]
+	return APIs[key]
+}
+
+// apiKey API 注册名称, 指定接口版本时为 {名称}@{版本}
+func apiKey(name string, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
 }
 
-// SelectAPI 读取已加载API
-func SelectAPI(name string) *API {
+// SelectAPI 读取已加载API, 可指定接口版本 SelectAPI("user", "v2") 等同于 SelectAPI("user@v2")
+func SelectAPI(name string, version ...string) *API {
+	if len(version) > 0 {
+		name = apiKey(name, version[0])
+	}
 	api, has := APIs[name]
 	if !has {
 		exception.New(
@@ -84,8 +118,16 @@ func SelectAPI(name string) *API {
 
 // ServeHTTP  启动HTTP服务
 func ServeHTTP(server Server, shutdown *chan bool, onShutdown func(Server), middlewares ...gin.HandlerFunc) {
-	router := gin.Default()
-	ServeHTTPCustomRouter(router, server, shutdown, onShutdown, middlewares...)
+	ServeHTTPCustomRouter(NewRouter(server), server, shutdown, onShutdown, middlewares...)
+}
+
+// NewRouter 创建路由器, 默认不添加 gin 的 Logger 和 Recovery 中间件 (错误处理由 SetHTTPRoutes 添加)
+// server.Gin 为 true 时使用 gin.Default() 创建
+func NewRouter(server Server) *gin.Engine {
+	if server.Gin {
+		return gin.Default()
+	}
+	return gin.New()
 }
 
 // ServeHTTPCustomRouter 启动HTTP服务, 自定义路由器
@@ -128,6 +170,13 @@ func ServeHTTPCustomRouter(router *gin.Engine, server Server, shutdown *chan boo
 
 // SetHTTPRoutes 设定路由
 func SetHTTPRoutes(router *gin.Engine, server Server, middlewares ...gin.HandlerFunc) {
+	// 请求数据大小上限 (路由中按路径配置生效)
+	if server.MaxBodySize > 0 {
+		router.Use(func(c *gin.Context) {
+			c.Set("__max_body_size", server.MaxBodySize)
+		})
+	}
+
 	// 添加中间件
 	for _, handler := range middlewares {
 		router.Use(handler)
@@ -155,6 +204,12 @@ func SetHTTPRoutes(router *gin.Engine, server Server, middlewares ...gin.Handler
 				"code":    code,
 				"message": err.Message,
 			})
+		} else if err, ok := recovered.(error); ok { // 模型错误 (ErrNotFound 404, ErrValidation 400, ErrConflict 409)
+			code = ErrorCode(err)
+			c.JSON(code, xun.R{
+				"code":    code,
+				"message": err.Error(),
+			})
 		} else {
 			c.JSON(code, xun.R{
 				"code":    code,
@@ -165,12 +220,103 @@ func SetHTTPRoutes(router *gin.Engine, server Server, middlewares ...gin.Handler
 		c.AbortWithStatus(code)
 	}))
 
-	// 加载API
+	// 数据库连接就绪检查 (ConnectWithRetry 连接中返回 503)
+	router.Use(dbReadiness)
+
+	// 加载API (路由冲突时 gin 会直接 panic, 提前检查)
+	if err := checkRoutes(server.Root); err != nil {
+		exception.Err(err, 500).Throw()
+	}
 	for _, api := range APIs {
 		api.HTTP.Routes(router, server.Root, server.Allows...)
 	}
 }
 
+// checkRoutes 检查全部 API 的路由是否冲突 (请求方法和路径相同, 路由参数名称不同视为同一路径)
+// 路径包含接口版本前缀, 不同版本的相同路径 (/v1/user, /v2/user) 不冲突
+func checkRoutes(root string) error {
+	names := []string{}
+	for name := range APIs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	routes := map[string]string{}
+	for _, name := range names {
+		api := APIs[name]
+		for _, p := range api.HTTP.Paths {
+			route := path.Join(api.HTTP.prefix(root), p.Path)
+			unique := strings.ToUpper(p.Method) + " " + reRouteParam.ReplaceAllString(route, "$1")
+			if prev, has := routes[unique]; has {
+				return fmt.Errorf("路由冲突: %s %s 在 %s 和 %s 中重复定义", strings.ToUpper(p.Method), route, prev, api.describe())
+			}
+			routes[unique] = api.describe()
+		}
+	}
+	return nil
+}
+
+// describe API 名称及来源文件, 用于错误信息
+func (api *API) describe() string {
+	if strings.HasPrefix(api.Source, "file://") {
+		return fmt.Sprintf("API %s (%s)", api.Name, strings.TrimPrefix(api.Source, "file://"))
+	}
+	return fmt.Sprintf("API %s", api.Name)
+}
+
+// bodyLimit 限制请求数据大小 (路径配置, 服务配置, DefaultMaxBodySize), 超出时返回 413
+func (p Path) bodyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := p.MaxBodySize
+		if limit <= 0 {
+			limit = c.GetInt64("__max_body_size")
+		}
+		if limit <= 0 {
+			limit = DefaultMaxBodySize
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, xun.R{
+				"code":    http.StatusRequestEntityTooLarge,
+				"message": fmt.Sprintf("请求数据超过大小上限 (%d 字节)", limit),
+			})
+			return
+		}
+		c.Request.Body = &bodyLimitReader{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, limit), limit: limit}
+	}
+}
+
+// errBodyTooLarge 请求数据超过大小上限
+var errBodyTooLarge = errors.New("请求数据超过大小上限")
+
+// bodyLimitReader 统计已读取的请求数据大小, 达到上限后读取失败时返回 errBodyTooLarge
+type bodyLimitReader struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (r *bodyLimitReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+	if err != nil && err != io.EOF && r.read >= r.limit {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// readBody 读取请求数据, 超出大小上限时返回 413
+func readBody(c *gin.Context) []byte {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		if errors.Is(err, errBodyTooLarge) {
+			exception.New("请求数据超过大小上限", http.StatusRequestEntityTooLarge).Throw()
+		}
+		panic(err)
+	}
+	return body
+}
+
 // SetHTTPGuards 加载中间件
 func SetHTTPGuards(guards map[string]gin.HandlerFunc) {
 	HTTPGuards = guards
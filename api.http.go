@@ -21,18 +21,20 @@ import (
 // HTTPGuards 支持的中间件
 var HTTPGuards = map[string]gin.HandlerFunc{}
 
-// Routes 配置转换为路由
+// Routes 配置转换为路由, 挂载于 /{root}/{api_version}/{group}
+// 中间件 (guard) 按定义文件设置, 各版本互不继承; 不同版本须分别声明
 func (http HTTP) Routes(router *gin.Engine, root string, allows ...string) {
-	var group gin.IRoutes = router
-	if http.Group != "" {
-		root = path.Join(root, "/", http.Group)
-	}
-	group = router.Group(root)
+	var group gin.IRoutes = router.Group(http.prefix(root))
 	for _, path := range http.Paths {
 		http.Route(group, path, allows...)
 	}
 }
 
+// prefix 路由前缀 /{root}/{api_version}/{group}
+func (http HTTP) prefix(root string) string {
+	return path.Join("/", root, http.APIVersion, http.Group)
+}
+
 // Route 路径配置转换为路由
 func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 	getArgs := http.parseIn(path.In)
@@ -47,23 +49,37 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 		http.crossDomain(path.Path, allowsMap, router)
 	}
 
+	// 请求数据大小上限 (在中间件和数据解析之前)
+	handlers = append(handlers, path.bodyLimit())
+
 	// 中间件
 	http.guard(&handlers, path.Guard, http.Guard)
 
+	// 权限范围和角色
+	if len(path.Scopes) > 0 || len(path.Roles) > 0 {
+		handlers = append(handlers, path.authorize())
+	}
+
+	// 幂等键 (在中间件之后, 按会话区分幂等键)
+	if path.idempotent() {
+		handlers = append(handlers, path.idempotency())
+	}
+
+	// 请求事务 (在幂等键之后, 重放的响应不开启事务)
+	if path.Transaction {
+		handlers = append(handlers, RequestTransaction)
+	}
+
 	// API响应逻辑
 	handlers = append(handlers, func(c *gin.Context) {
 
 		if strings.HasPrefix(strings.ToLower(c.GetHeader("content-type")), "application/json") {
-			bytes, err := ioutil.ReadAll(c.Request.Body)
-			if err != nil {
-				panic(err)
-			}
-
+			bytes := readBody(c)
 			if bytes == nil || len(bytes) == 0 {
 				c.Set("__payloads", map[string]interface{}{})
 			} else {
 				payloads := map[string]interface{}{}
-				err = jsoniter.Unmarshal(bytes, &payloads)
+				err := jsoniter.Unmarshal(bytes, &payloads)
 				if err != nil {
 					panic(err)
 				}
@@ -79,7 +95,13 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 		// 	args = append(args, c)
 		// }
 
-		var process = NewProcess(path.Process, args...)
+		// 模型列表接口分批写入 JSON 数组
+		if path.streaming(c) {
+			streamResponse(c, path.processModel("get"), args, path.Out.Status)
+			return
+		}
+
+		var process = NewProcess(path.Process, args...).WithContext(c.Request.Context())
 		if sid, has := c.Get("__sid"); has { // 设定会话ID
 			if sid, ok := sid.(string); ok {
 				process.WithSID(sid)
@@ -95,6 +117,12 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 		var status int = path.Out.Status
 		var contentType string = path.Out.Type
 
+		// 模型创建接口: 返回 201, Location 指向新记录, 响应数据为新记录
+		if model := path.createModel(); model != "" && resp != nil && (status == 0 || status == 201) {
+			resp = createdResponse(c, model, resp)
+			status = 201
+		}
+
 		if contentType != "" {
 			c.Writer.Header().Set("Content-Type", contentType)
 		}
@@ -122,6 +150,19 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 			return
 		}
 
+		// 内容协商
+		if format := path.Out.negotiate(c); format != "" {
+			data, ok, err := serialize(format, resp)
+			if err != nil {
+				exception.Err(err, 500).Throw()
+			}
+			if ok {
+				c.Data(status, format+"; charset=utf-8", data)
+				c.Done()
+				return
+			}
+		}
+
 		switch resp.(type) {
 		case maps.Map, map[string]interface{}, []interface{}, []maps.Map, []map[string]interface{}:
 			c.JSON(status, resp)
@@ -142,6 +183,31 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 	http.method(path.Method, path.Path, router, handlers...)
 }
 
+// createModel 模型创建接口 (处理器为 models.<模型>.Create) 的模型名称, 其他接口返回空字符串
+func (p Path) createModel() string {
+	return p.processModel("create")
+}
+
+// processModel 处理器为 models.<模型>.<method> 时返回模型名称, 其他处理器返回空字符串
+func (p Path) processModel(method string) string {
+	process := strings.ToLower(p.Process)
+	if !strings.HasPrefix(process, "models.") || !strings.HasSuffix(process, "."+method) {
+		return ""
+	}
+	return p.Process[len("models.") : len(p.Process)-len(method)-1]
+}
+
+// createdResponse 设置 Location 响应头 ({请求路径}/{主键}), 返回新创建的记录
+func createdResponse(c *gin.Context, model string, id interface{}) interface{} {
+	mod := Select(model).WithContext(c.Request.Context()) // 请求事务中读取未提交的新记录
+	c.Writer.Header().Set("Location", fmt.Sprintf("%s/%v", strings.TrimSuffix(c.Request.URL.Path, "/"), id))
+	row := mod.namingOut(mod.MustFind(id, QueryParam{}))
+	if row, ok := row.(maps.MapStr); ok {
+		return map[string]interface{}(row)
+	}
+	return row
+}
+
 // 加载特定中间件
 func (http HTTP) guard(handlers *[]gin.HandlerFunc, guard string, defaults string) {
 
@@ -183,11 +249,7 @@ func (http HTTP) parseIn(in []string) func(c *gin.Context) []interface{} {
 
 		if v == ":body" {
 			getValues = append(getValues, func(c *gin.Context) interface{} {
-				bytes, err := ioutil.ReadAll(c.Request.Body)
-				if err != nil {
-					panic(err)
-				}
-				return string(bytes)
+				return string(readBody(c))
 			})
 			continue
 		} else if v == ":fullpath" {
@@ -0,0 +1,167 @@
+package gou
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/xun"
+)
+
+// IdempotencyHeader 幂等键请求头
+var IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyTTL 幂等键响应结果的保存时长
+var IdempotencyTTL = 24 * time.Hour
+
+// IdempotencyStore 幂等键响应结果存储 (可替换为 Redis 等共享存储, 多实例部署时使用)
+var IdempotencyStore IdempotentStore = NewMemoryIdempotentStore()
+
+// IdempotentStore 幂等键响应结果存储接口
+type IdempotentStore interface {
+	Get(key string) (*IdempotentResponse, bool)
+	Set(key string, response *IdempotentResponse, ttl time.Duration)
+}
+
+// IdempotentResponse 已保存的响应结果
+type IdempotentResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+	Hash        string `json:"hash,omitempty"` // 请求数据摘要 (SHA-256)
+}
+
+// MemoryIdempotentStore 进程内幂等键响应结果存储
+type MemoryIdempotentStore struct {
+	items map[string]memoryIdempotentItem
+	lock  sync.Mutex
+}
+
+type memoryIdempotentItem struct {
+	response *IdempotentResponse
+	expires  time.Time
+}
+
+// NewMemoryIdempotentStore 创建进程内幂等键响应结果存储
+func NewMemoryIdempotentStore() *MemoryIdempotentStore {
+	return &MemoryIdempotentStore{items: map[string]memoryIdempotentItem{}}
+}
+
+// Get 读取响应结果, 已过期返回 false
+func (store *MemoryIdempotentStore) Get(key string) (*IdempotentResponse, bool) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	item, has := store.items[key]
+	if !has {
+		return nil, false
+	}
+	if time.Now().After(item.expires) {
+		delete(store.items, key)
+		return nil, false
+	}
+	return item.response, true
+}
+
+// Set 保存响应结果 (同时清理已过期的响应结果)
+func (store *MemoryIdempotentStore) Set(key string, response *IdempotentResponse, ttl time.Duration) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	now := time.Now()
+	for name, item := range store.items {
+		if now.After(item.expires) {
+			delete(store.items, name)
+		}
+	}
+	store.items[key] = memoryIdempotentItem{response: response, expires: now.Add(ttl)}
+}
+
+// idempotentPending 正在处理的幂等键
+var idempotentPending sync.Map
+
+// idempotentWriter 记录响应数据
+type idempotentWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotentWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotentWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent 路径是否支持幂等键 (路径配置 idempotent 或模型 Create 处理器)
+func (p Path) idempotent() bool {
+	return p.Idempotent || p.createModel() != ""
+}
+
+// idempotency 幂等键中间件, 请求头含 Idempotency-Key 时, 相同幂等键的重复请求返回首次请求的响应结果 (不再运行处理器)
+// 首次请求处理中收到重复请求返回 409, 相同幂等键的请求数据不同时返回 422; 仅保存成功 (2xx) 的响应结果, 失败后可使用相同幂等键重试
+// 幂等键按会话区分, 无会话 (未登录) 的请求按客户端 IP 区分
+func (p Path) idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		owner := c.GetString("__sid")
+		if owner == "" {
+			owner = "ip:" + c.ClientIP()
+		}
+		key = fmt.Sprintf("%s %s %s %s", owner, c.Request.Method, c.Request.URL.Path, key)
+
+		body := readBody(c)
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		hash := fmt.Sprintf("%x", sha256.Sum256(body))
+
+		if response, has := IdempotencyStore.Get(key); has {
+			if response.Hash != hash {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, xun.R{
+					"code":    http.StatusUnprocessableEntity,
+					"message": "幂等键已用于其他请求数据",
+				})
+				return
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(response.Status, response.ContentType, response.Body)
+			c.Abort()
+			return
+		}
+
+		if _, pending := idempotentPending.LoadOrStore(key, true); pending {
+			c.AbortWithStatusJSON(http.StatusConflict, xun.R{
+				"code":    http.StatusConflict,
+				"message": "相同幂等键的请求正在处理中",
+			})
+			return
+		}
+		defer idempotentPending.Delete(key)
+
+		writer := &idempotentWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		status := writer.Status()
+		if c.IsAborted() || status < 200 || status >= 300 {
+			return
+		}
+		IdempotencyStore.Set(key, &IdempotentResponse{
+			Status:      status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+			Hash:        hash,
+		}, IdempotencyTTL)
+	}
+}
@@ -0,0 +1,422 @@
+package gou
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // SHA256 签名算法
+	_ "crypto/sha512" // SHA384, SHA512 签名算法
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun"
+)
+
+// JWTRefresh JWKS 公钥缓存时长 (令牌 kid 未知时, 超过 1 分钟即重新读取)
+var JWTRefresh = time.Hour
+
+// JWTClient 读取 JWKS 的 HTTP 客户端
+var JWTClient = &http.Client{Timeout: 10 * time.Second}
+
+// JWTConfig JWT 鉴权配置
+type JWTConfig struct {
+	Secret     string        `json:"secret,omitempty"`       // HMAC 密钥 (HS256, HS384, HS512)
+	PublicKey  string        `json:"public_key,omitempty"`   // PEM 格式公钥或证书 (RS256, RS384, RS512, ES256, ES384, ES512)
+	JWKS       string        `json:"jwks,omitempty"`         // JWKS 地址, 按令牌 kid 选择公钥
+	Issuer     string        `json:"issuer,omitempty"`       // 签发者 (iss), 设置后校验
+	Audience   string        `json:"audience,omitempty"`     // 接收方 (aud), 设置后校验
+	Leeway     time.Duration `json:"leeway,omitempty"`       // 允许的时钟偏差 (exp, nbf)
+	Scopes     []string      `json:"scopes,omitempty"`       // 必须具备的权限范围 (全部具备), 不满足返回 403
+	ScopeClaim string        `json:"scope_claim,omitempty"`  // 权限范围字段, 默认 scope (空格分隔的字符串或数组)
+	AllowNoExp bool          `json:"allow_no_exp,omitempty"` // 允许不含过期时间 (exp) 的令牌, 默认拒绝
+}
+
+// jwtVerifier JWT 校验器
+type jwtVerifier struct {
+	config     JWTConfig
+	publicKey  crypto.PublicKey
+	keys       map[string]crypto.PublicKey // JWKS 公钥 (kid => 公钥)
+	fetched    time.Time                   // 最近一次读取成功的时间
+	checked    time.Time                   // 最近一次读取的时间 (含失败)
+	err        error                       // 最近一次读取的错误
+	refreshing chan struct{}               // 正在读取 JWKS, 读取完成后关闭
+	lock       sync.Mutex
+}
+
+// JWTGuard 创建 JWT 鉴权中间件, 校验 Authorization: Bearer 令牌, 失败返回 401
+// 校验通过后令牌数据 (claims) 保存在上下文 __jwt_claims 中, 可使用 JWTClaims 读取
+// 注册: AddHTTPGuard("jwt", JWTGuard(config)); 配置无效时抛出异常
+func JWTGuard(config JWTConfig) gin.HandlerFunc {
+	verifier := &jwtVerifier{config: config, keys: map[string]crypto.PublicKey{}}
+	if config.Secret == "" && config.PublicKey == "" && config.JWKS == "" {
+		exception.New("JWT 鉴权配置错误: secret, public_key, jwks 至少设置一项", 500).Throw()
+	}
+	if config.PublicKey != "" {
+		key, err := parsePublicKey(config.PublicKey)
+		if err != nil {
+			exception.Err(err, 500).Throw()
+		}
+		verifier.publicKey = key
+	}
+
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(c.GetHeader("Authorization"))
+		if len(token) < 7 || !strings.EqualFold(token[:7], "bearer ") {
+			jwtAbort(c, http.StatusUnauthorized, "缺少 Bearer 令牌")
+			return
+		}
+
+		claims, err := verifier.verify(strings.TrimSpace(token[7:]))
+		if err != nil {
+			jwtAbort(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		c.Set("__jwt_claims", claims)
+
+		if missing := jwtMissingScopes(claims, config.ScopeClaim, config.Scopes); len(missing) > 0 {
+			jwtAbort(c, http.StatusForbidden, fmt.Sprintf("令牌缺少权限范围: %s", strings.Join(missing, ", ")))
+		}
+	}
+}
+
+// JWTScopes 创建权限范围校验中间件 (在 JWTGuard 之后使用), 令牌缺少任一权限范围返回 403
+// 如: AddHTTPGuard("jwt-admin", JWTScopes("scope", "admin")), 路径配置 "guard": "jwt,jwt-admin"
+func JWTScopes(claim string, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := JWTClaims(c)
+		if claims == nil {
+			jwtAbort(c, http.StatusUnauthorized, "缺少 Bearer 令牌")
+			return
+		}
+		if missing := jwtMissingScopes(claims, claim, scopes); len(missing) > 0 {
+			jwtAbort(c, http.StatusForbidden, fmt.Sprintf("令牌缺少权限范围: %s", strings.Join(missing, ", ")))
+		}
+	}
+}
+
+// JWTClaims 读取 JWTGuard 校验通过的令牌数据, 未校验返回 nil
+func JWTClaims(c *gin.Context) map[string]interface{} {
+	claims, has := c.Get("__jwt_claims")
+	if !has {
+		return nil
+	}
+	res, _ := claims.(map[string]interface{})
+	return res
+}
+
+// jwtAbort 返回鉴权失败信息
+func jwtAbort(c *gin.Context, code int, message string) {
+	if code == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", "Bearer")
+	}
+	c.AbortWithStatusJSON(code, xun.R{
+		"code":    code,
+		"message": message,
+	})
+}
+
+// jwtMissingScopes 令牌缺少的权限范围
+func jwtMissingScopes(claims map[string]interface{}, claim string, scopes []string) []string {
+	if claim == "" {
+		claim = "scope"
+	}
+	granted := map[string]bool{}
+	switch value := claims[claim].(type) {
+	case string:
+		for _, scope := range strings.Fields(value) {
+			granted[scope] = true
+		}
+	case []interface{}:
+		for _, scope := range value {
+			granted[fmt.Sprintf("%v", scope)] = true
+		}
+	}
+
+	missing := []string{}
+	for _, scope := range scopes {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// verify 校验令牌签名和 exp, nbf, iss, aud, 返回令牌数据
+func (verifier *jwtVerifier) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("令牌格式错误")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := jwtDecode(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("令牌格式错误")
+	}
+	claims := map[string]interface{}{}
+	if err := jwtDecode(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("令牌格式错误")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("令牌格式错误")
+	}
+
+	if err := verifier.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	config := verifier.config
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok && !config.AllowNoExp {
+		return nil, fmt.Errorf("令牌缺少过期时间")
+	}
+	if ok && now.After(time.Unix(int64(exp), 0).Add(config.Leeway)) {
+		return nil, fmt.Errorf("令牌已过期")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("令牌尚未生效")
+	}
+	if config.Issuer != "" && claims["iss"] != config.Issuer {
+		return nil, fmt.Errorf("令牌签发者错误")
+	}
+	if config.Audience != "" && !jwtAudience(claims["aud"], config.Audience) {
+		return nil, fmt.Errorf("令牌接收方错误")
+	}
+	return claims, nil
+}
+
+// verifySignature 按签名算法校验签名
+func (verifier *jwtVerifier) verifySignature(alg string, kid string, signed string, signature []byte) error {
+	var hash crypto.Hash
+	switch {
+	case strings.HasSuffix(alg, "256"):
+		hash = crypto.SHA256
+	case strings.HasSuffix(alg, "384"):
+		hash = crypto.SHA384
+	case strings.HasSuffix(alg, "512"):
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("不支持的签名算法 %s", alg)
+	}
+
+	if strings.HasPrefix(alg, "HS") {
+		if verifier.config.Secret == "" {
+			return fmt.Errorf("不支持的签名算法 %s", alg)
+		}
+		mac := hmac.New(hash.New, []byte(verifier.config.Secret))
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("令牌签名错误")
+		}
+		return nil
+	}
+
+	key, err := verifier.key(kid)
+	if err != nil {
+		return err
+	}
+	digest := hash.New()
+	digest.Write([]byte(signed))
+	sum := digest.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") || rsa.VerifyPKCS1v15(pub, hash, sum, signature) != nil {
+			return fmt.Errorf("令牌签名错误")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return fmt.Errorf("令牌签名错误")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, sum, r, s) {
+			return fmt.Errorf("令牌签名错误")
+		}
+		return nil
+	}
+	return fmt.Errorf("不支持的签名算法 %s", alg)
+}
+
+// key 读取校验公钥, 优先使用配置的公钥, 否则按 kid 从 JWKS 中读取
+func (verifier *jwtVerifier) key(kid string) (crypto.PublicKey, error) {
+	if verifier.publicKey != nil {
+		return verifier.publicKey, nil
+	}
+	if verifier.config.JWKS == "" {
+		return nil, fmt.Errorf("未设置校验公钥")
+	}
+
+	verifier.lock.Lock()
+	key, has := verifier.keys[kid]
+	stale := (!has || time.Since(verifier.fetched) > JWTRefresh) && time.Since(verifier.checked) > time.Minute
+	if !stale && (has || verifier.refreshing == nil) {
+		err := verifier.err
+		verifier.lock.Unlock()
+		if !has {
+			return nil, jwksMissing(kid, err)
+		}
+		return key, nil
+	}
+
+	// 同一时间仅一个请求读取 JWKS, 不持有锁读取
+	wait := verifier.refreshing
+	if wait == nil {
+		wait = make(chan struct{})
+		verifier.refreshing = wait
+		go verifier.refresh(wait)
+	}
+	verifier.lock.Unlock()
+
+	// 已缓存的公钥在后台刷新期间继续使用
+	if has {
+		return key, nil
+	}
+
+	<-wait
+	verifier.lock.Lock()
+	key, has = verifier.keys[kid]
+	err := verifier.err
+	verifier.lock.Unlock()
+	if !has {
+		return nil, jwksMissing(kid, err)
+	}
+	return key, nil
+}
+
+// refresh 读取 JWKS 并替换公钥, 读取失败时保留已缓存的公钥 (1 分钟后重试)
+func (verifier *jwtVerifier) refresh(done chan struct{}) {
+	keys, err := fetchJWKS(verifier.config.JWKS)
+	verifier.lock.Lock()
+	defer verifier.lock.Unlock()
+	verifier.checked = time.Now()
+	verifier.err = err
+	if err == nil {
+		verifier.keys = keys
+		verifier.fetched = verifier.checked
+	} else {
+		log.Warn("JWKS %s: %s", verifier.config.JWKS, err)
+	}
+	verifier.refreshing = nil
+	close(done)
+}
+
+// jwksMissing 公钥不存在错误 (读取 JWKS 失败时返回读取错误)
+func jwksMissing(kid string, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("JWKS 中不存在公钥 %s", kid)
+}
+
+// fetchJWKS 读取 JWKS 公钥 (支持 RSA, EC)
+func fetchJWKS(url string) (map[string]crypto.PublicKey, error) {
+	resp, err := JWTClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS 读取失败 (%s): %d", url, resp.StatusCode)
+	}
+
+	jwks := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}{}
+	if err := jsoniter.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+			curve, has := curves[jwk.Crv]
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if !has || errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+// parsePublicKey 解析 PEM 格式公钥 (PKIX, PKCS1) 或证书
+func parsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("JWT 公钥格式错误")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("JWT 公钥格式错误")
+	}
+	return cert.PublicKey, nil
+}
+
+// jwtDecode 解码令牌 header 或 payload
+func jwtDecode(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return jsoniter.Unmarshal(data, v)
+}
+
+// jwtAudience 令牌接收方 (aud, 字符串或数组) 是否包含 audience
+func jwtAudience(aud interface{}, audience string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == audience
+	case []interface{}:
+		for _, item := range value {
+			if item == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package gou
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// OpenAPIInfo OpenAPI 文档信息 (info 节点)
+var OpenAPIInfo = map[string]interface{}{
+	"title":   "API",
+	"version": "1.0.0",
+}
+
+var reOpenAPIPathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// GenerateOpenAPI 根据已加载的 API 和模型生成 OpenAPI 3 文档 (JSON)
+// 处理器为 models.<模型>.<方法> 时, 按模型字段推断请求数据和返回数据结构, 已加载的模型输出到 components.schemas
+func GenerateOpenAPI() ([]byte, error) {
+	paths := map[string]map[string]interface{}{}
+	tags := []map[string]interface{}{}
+
+	names := []string{}
+	for name := range APIs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		api := APIs[name]
+		tag := api.HTTP.Name
+		if tag == "" {
+			tag = api.Name
+		}
+		tags = append(tags, map[string]interface{}{"name": tag, "description": api.HTTP.Description})
+
+		for _, p := range api.HTTP.Paths {
+			route := openAPIPath(path.Join(api.HTTP.prefix(""), p.Path))
+			if _, has := paths[route]; !has {
+				paths[route] = map[string]interface{}{}
+			}
+			paths[route][strings.ToLower(p.Method)] = api.HTTP.openAPIOperation(p, tag)
+		}
+	}
+
+	schemas := map[string]interface{}{}
+	for _, name := range ListModels() {
+		schemas[name] = Models[name].openAPISchema()
+	}
+
+	doc := map[string]interface{}{
+		"openapi":    "3.0.3",
+		"info":       OpenAPIInfo,
+		"tags":       tags,
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// openAPIPath 路由路径转换为 OpenAPI 路径 (/user/:id => /user/{id})
+func openAPIPath(route string) string {
+	return reOpenAPIPathParam.ReplaceAllString(route, "{$1}")
+}
+
+// openAPIOperation 路径配置转换为 OpenAPI Operation
+func (http HTTP) openAPIOperation(p Path, tag string) map[string]interface{} {
+	mod, method := openAPIModel(p.Process)
+	operation := map[string]interface{}{
+		"tags":        []string{tag},
+		"summary":     p.Label,
+		"description": p.Description,
+		"operationId": strings.Trim(strings.ReplaceAll(path.Join(http.Group, p.Path, p.Method), "/", "."), "."),
+		"x-process":   p.Process,
+	}
+
+	guard := p.Guard
+	if guard == "" {
+		guard = http.Guard
+	}
+	if guard != "" && guard != "-" {
+		operation["x-guard"] = guard
+	}
+
+	// 路由参数
+	parameters := []map[string]interface{}{}
+	for _, match := range reOpenAPIPathParam.FindAllStringSubmatch(p.Path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+
+	// 请求参数
+	payload := map[string]interface{}{}
+	form := map[string]interface{}{}
+	var body map[string]interface{}
+	for _, in := range p.In {
+		switch in {
+		case ":payload":
+			body = map[string]interface{}{"application/json": map[string]interface{}{"schema": mod.openAPIRef(method, false)}}
+			continue
+		case ":body":
+			body = map[string]interface{}{"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+			continue
+		case ":params", ":query-param":
+			parameters = append(parameters, map[string]interface{}{
+				"name":        "params",
+				"in":          "query",
+				"description": "查询参数 (select, where.<字段>.<条件>, order, with 等)",
+				"style":       "form",
+				"explode":     true,
+				"schema":      map[string]interface{}{"type": "object", "additionalProperties": true},
+			})
+			continue
+		}
+
+		arg := strings.Split(in, ".")
+		if len(arg) != 2 {
+			continue
+		}
+		switch arg[0] {
+		case "$query":
+			parameters = append(parameters, map[string]interface{}{
+				"name":   arg[1],
+				"in":     "query",
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		case "$payload":
+			payload[arg[1]] = mod.openAPIProperty(arg[1])
+		case "$form":
+			form[arg[1]] = map[string]interface{}{"type": "string"}
+		case "$file":
+			form[arg[1]] = map[string]interface{}{"type": "string", "format": "binary"}
+		}
+	}
+
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if body == nil && len(payload) > 0 {
+		body = map[string]interface{}{"application/json": map[string]interface{}{
+			"schema": map[string]interface{}{"type": "object", "properties": payload},
+		}}
+	} else if body == nil && len(form) > 0 {
+		contentType := "application/x-www-form-urlencoded"
+		for _, prop := range form {
+			if prop.(map[string]interface{})["format"] == "binary" {
+				contentType = "multipart/form-data"
+			}
+		}
+		body = map[string]interface{}{contentType: map[string]interface{}{
+			"schema": map[string]interface{}{"type": "object", "properties": form},
+		}}
+	}
+	if body != nil {
+		operation["requestBody"] = map[string]interface{}{"content": body}
+	}
+
+	// 返回数据
+	status := p.Out.Status
+	if status == 0 {
+		status = 200
+	}
+	contentType := p.Out.Type
+	if contentType == "" {
+		contentType = p.Out.Headers["Content-Type"]
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	response := map[string]interface{}{"description": statusText(status)}
+	if schema := mod.openAPIRef(method, true); schema != nil {
+		response["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+	}
+	operation["responses"] = map[string]interface{}{fmt.Sprintf("%d", status): response}
+	return operation
+}
+
+// statusText HTTP 状态码说明
+func statusText(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return fmt.Sprintf("%d", status)
+	}
+	return text
+}
+
+// openAPIModel 读取处理器绑定的模型 (models.<模型>.<方法>), 未绑定模型返回 nil
+func openAPIModel(process string) (*Model, string) {
+	namer := strings.Split(process, ".")
+	last := len(namer) - 1
+	if last < 2 || strings.ToLower(namer[0]) != "models" {
+		return nil, ""
+	}
+	mod, has := Models[strings.Join(namer[1:last], ".")]
+	if !has {
+		return nil, ""
+	}
+	return mod, strings.ToLower(namer[last])
+}
+
+// openAPIRef 按模型处理器推断请求数据 (out=false) 或返回数据 (out=true) 结构
+func (mod *Model) openAPIRef(method string, out bool) map[string]interface{} {
+	if mod == nil {
+		if out {
+			return nil
+		}
+		return map[string]interface{}{"type": "object"}
+	}
+
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + mod.Name}
+	if !out {
+		return ref
+	}
+
+	switch method {
+	case "find":
+		return ref
+	case "get":
+		return map[string]interface{}{"type": "array", "items": ref}
+	case "paginate":
+		properties := map[string]interface{}{}
+		for _, field := range []string{"data", "total", "page", "pagesize", "pagecnt", "last_page", "next", "prev", "from", "to"} {
+			name := field
+			if format, has := PaginateFormat[field]; has {
+				name = format
+			}
+			if name == "" { // 设置为空时不输出
+				continue
+			}
+			properties[name] = map[string]interface{}{"type": "integer"}
+			if field == "data" {
+				properties[name] = map[string]interface{}{"type": "array", "items": ref}
+			}
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	case "create", "save", "updatewhere", "deletewhere", "destroywhere", "deletemany", "destroymany":
+		return map[string]interface{}{"type": "integer"}
+	case "eachsave", "eachsaveafterdelete":
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}}
+	}
+	return nil
+}
+
+// openAPIProperty 模型字段的数据结构, 未绑定模型或字段不存在时为字符串
+func (mod *Model) openAPIProperty(name string) map[string]interface{} {
+	if mod == nil {
+		return map[string]interface{}{"type": "string"}
+	}
+	column, has := mod.Columns[name]
+	if !has {
+		return map[string]interface{}{"type": "string"}
+	}
+	return column.openAPISchema()
+}
+
+// openAPISchema 模型数据结构 (不含隐藏字段)
+func (mod *Model) openAPISchema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, column := range mod.MetaData.Columns {
+		if column.Hidden {
+			continue
+		}
+		properties[column.Name] = column.openAPISchema()
+		if !column.Nullable && column.Default == nil && column.Name != mod.PrimaryKey && column.DefaultRaw == "" {
+			required = append(required, column.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"title":      mod.MetaData.Name,
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPISchema 字段数据结构
+func (column Column) openAPISchema() map[string]interface{} {
+	schema := column.schemaType()
+	if column.Label != "" {
+		schema["title"] = column.Label
+	}
+	if column.Comment != "" {
+		schema["description"] = column.Comment
+	}
+	if column.Default != nil {
+		schema["default"] = column.Default
+	}
+	if column.Nullable {
+		schema["nullable"] = true
+	}
+	if column.writeOnly() {
+		schema["writeOnly"] = true
+	}
+	return schema
+}
@@ -0,0 +1,107 @@
+package gou
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+)
+
+// StreamChunkSize 流式响应每批读取的记录数
+var StreamChunkSize = 500
+
+// errStreamLimit 已达到查询参数的数量限制
+var errStreamLimit = errors.New("stream limit")
+
+// streaming 是否以流式响应输出 (out.stream 开启, 处理器为 models.<模型>.Get, 且未协商为其他数据类型)
+func (p Path) streaming(c *gin.Context) bool {
+	if !p.Out.Stream || p.processModel("get") == "" {
+		return false
+	}
+	format := p.Out.negotiate(c)
+	return format == "" || format == "application/json"
+}
+
+// streamResponse 按查询参数分批读取数据 (Model.Each), 逐批写入 JSON 数组, 输出结构与 models.<模型>.Get 一致
+// 首批数据读取失败按正常流程返回错误; 写入开始后读取失败将中断连接 (响应数据不完整, 不是有效的 JSON)
+func streamResponse(c *gin.Context, model string, args []interface{}, status int) {
+	mod := Select(model)
+	if len(args) < 1 {
+		exception.New("参数错误: 需要 1 个参数, 实际 0 个", 400).Throw()
+	}
+	param, ok := AnyToQueryParam(args[0])
+	if !ok {
+		exception.New("第1个查询参数错误 %v", 400, args[0]).Throw()
+	}
+	mod.namingParam(&param)
+
+	limit := param.Limit
+	size := StreamChunkSize
+	if limit > 0 && limit < size {
+		size = limit
+	}
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	started := false
+	written := 0
+	err := streamEach(mod, param, size, func(rows []maps.MapStr) error {
+		if !started {
+			started = true
+			c.Writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			c.Status(status)
+			c.Writer.WriteString("[")
+		}
+		for _, row := range rows {
+			if limit > 0 && written >= limit {
+				return errStreamLimit
+			}
+			data, err := jsoniter.Marshal(mod.namingOut(row))
+			if err != nil {
+				return err
+			}
+			if written > 0 {
+				c.Writer.WriteString(",")
+			}
+			c.Writer.Write(data)
+			written++
+		}
+		c.Writer.Flush()
+		if limit > 0 && written >= limit {
+			return errStreamLimit
+		}
+		return nil
+	})
+
+	if err != nil && err != errStreamLimit {
+		if !started {
+			throwError(err)
+		}
+		log.Error("流式响应中断 %s: %s", c.Request.URL.Path, err)
+		c.Abort()
+		return
+	}
+
+	if !started {
+		c.Data(status, "application/json; charset=utf-8", []byte("[]"))
+		return
+	}
+	c.Writer.WriteString("]")
+	c.Writer.Flush()
+}
+
+// streamEach 分批读取数据, 读取异常转换为错误
+func streamEach(mod *Model, param QueryParam, size int, handler func(rows []maps.MapStr) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Catch(r)
+		}
+	}()
+	return mod.Each(param, size, handler)
+}
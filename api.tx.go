@@ -0,0 +1,72 @@
+package gou
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun"
+)
+
+// RequestTransaction 请求事务中间件 (需显式启用: 路径配置 transaction 或注册为中间件, 如 AddHTTPGuard("transaction", RequestTransaction))
+// 为请求开启数据库事务并绑定到请求上下文 (c.Request.Context()), 处理器中通过 Model.WithContext 读取的模型使用该事务
+// 响应状态码为 2xx 时提交, 其他状态码或处理器异常 (panic) 时回滚; 事务持续到请求处理结束, 耗时较长的接口 (如上传、流式响应) 不宜启用
+// 响应数据在事务结束后发送 (处理期间缓存在内存中), 提交失败时不发送处理器的响应, 返回 500
+func RequestTransaction(c *gin.Context) {
+	writer := &txWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	defer func() { c.Writer = writer.ResponseWriter }() // 异常时由错误处理中间件直接响应
+
+	err := transaction(func(tx *Tx) error {
+		c.Request = c.Request.WithContext(WithTx(c.Request.Context(), tx))
+		c.Next()
+		if status := c.Writer.Status(); status < 200 || status >= 300 {
+			return fmt.Errorf("请求响应状态码 %d, 回滚事务", status)
+		}
+		return nil
+	})
+
+	c.Writer = writer.ResponseWriter
+	if status := c.Writer.Status(); err != nil && status >= 200 && status < 300 {
+		log.Error("请求事务提交失败 %s: %s", c.Request.URL.Path, err)
+		c.Writer.Header().Del("Content-Type")
+		c.JSON(http.StatusInternalServerError, xun.R{
+			"code":    http.StatusInternalServerError,
+			"message": "请求事务提交失败",
+		})
+		return
+	}
+
+	c.Writer.WriteHeaderNow()
+	c.Writer.Write(writer.body.Bytes())
+}
+
+// txWriter 缓存响应数据, 事务结束后发送
+type txWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *txWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *txWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// WriteHeaderNow 状态码随响应数据在事务结束后发送
+func (w *txWriter) WriteHeaderNow() {}
+
+// Flush 响应数据在事务结束后发送
+func (w *txWriter) Flush() {}
+
+func (w *txWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *txWriter) Written() bool {
+	return w.body.Len() > 0
+}
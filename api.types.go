@@ -14,6 +14,7 @@ type HTTP struct {
 	Version     string `json:"version"`
 	Description string `json:"description,omitempty"`
 	Group       string `json:"group,omitempty"`
+	APIVersion  string `json:"api_version,omitempty"` // 接口版本 (如 v1), 路由挂载于 /{root}/{api_version}/{group}; version 为定义文件版本, 不影响路由
 	Guard       string `json:"guard,omitempty"`
 	Paths       []Path `json:"paths,omitempty"`
 }
@@ -28,6 +29,11 @@ type Path struct {
 	Guard       string   `json:"guard,omitempty"`
 	In          []string `json:"in,omitempty"`
 	Out         Out      `json:"out,omitempty"`
+	MaxBodySize int64    `json:"max_body_size,omitempty"` // 请求数据大小上限 (字节), 未设置时使用服务配置 (如上传文件接口可单独调大)
+	Idempotent  bool     `json:"idempotent,omitempty"`    // 支持 Idempotency-Key 请求头 (模型 Create 处理器默认支持)
+	Scopes      []string `json:"scopes,omitempty"`        // 必须具备的权限范围 (全部具备, 如 user:write), 读取 JWTGuard 校验的令牌
+	Roles       []string `json:"roles,omitempty"`         // 许可的角色 (具备任一角色即可), 读取 AuthorizeRoles
+	Transaction bool     `json:"transaction,omitempty"`   // 请求事务 (RequestTransaction), 2xx 响应提交, 否则回滚; 默认不启用
 }
 
 // Out http 输出
@@ -35,6 +41,8 @@ type Out struct {
 	Status  int               `json:"status"`
 	Type    string            `json:"type,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
+	Formats []string          `json:"formats,omitempty"` // 可协商的响应数据类型 (如 csv, xml, text/csv), 按 ?format= 参数或 Accept 请求头选择, 默认 JSON
+	Stream  bool              `json:"stream,omitempty"`  // 模型 Get 接口 (models.<模型>.Get) 分批读取并逐批写入 JSON 数组, 内存占用不随数据量增长
 }
 
 // Server API 服务配置
@@ -44,6 +52,9 @@ type Server struct {
 	Host   string   `json:"host,omitempty"`
 	Root   string   `json:"root,omitempty"`   // API 根目录
 	Allows []string `json:"allows,omitempty"` // 许可跨域访问域名
+	Gin    bool     `json:"gin,omitempty"`    // 使用 gin 默认中间件 (Logger, Recovery), 默认不使用
+
+	MaxBodySize int64 `json:"max_body_size,omitempty"` // 请求数据大小上限 (字节), 默认 DefaultMaxBodySize
 }
 
 // SocketServer Socket Server 描述数据结构
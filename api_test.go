@@ -1,11 +1,23 @@
 package gou
 
 import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"path"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,7 +26,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/session"
 	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/capsule"
 )
 
 func init() {
@@ -188,9 +202,485 @@ func GetTestRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
 	return router
 }
 
+func TestCheckRoutes(t *testing.T) {
+	assert.Nil(t, checkRoutes("/api"))
+
+	LoadAPI(`{"name": "冲突", "group": "user", "paths": [{"path": "/conflict/:uid", "method": "GET", "process": "models.user.Find"}]}`, "conflict")
+	LoadAPI(`{"name": "冲突", "group": "user", "paths": [{"path": "/conflict/:id", "method": "GET", "process": "models.user.Find"}]}`, "conflict_test")
+	defer delete(APIs, "conflict")
+	defer delete(APIs, "conflict_test")
+
+	err := checkRoutes("/api")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "GET /api/user/conflict/:id")
+	assert.Contains(t, err.Error(), "API conflict")
+	assert.Contains(t, err.Error(), "API conflict_test")
+	assert.Panics(t, func() { GetTestRouter() })
+}
+
+func TestAPIVersion(t *testing.T) {
+	v1 := LoadAPI(`{"name": "版本", "api_version": "v1", "group": "version_test", "guard": "-", "paths": [{"path": "/users", "method": "GET", "process": "models.user.Get", "in": [":params"], "out": {"status": 200}}]}`, "version_test")
+	v2 := LoadAPI(`{"name": "版本", "api_version": "/v2/", "group": "version_test", "guard": "-", "paths": [{"path": "/users", "method": "GET", "process": "models.user.Get", "in": [":params"], "out": {"status": 200}}]}`, "version_test")
+	defer delete(APIs, "version_test@v1")
+	defer delete(APIs, "version_test@v2")
+
+	assert.Equal(t, v1, SelectAPI("version_test", "v1"))
+	assert.Equal(t, v2, SelectAPI("version_test@v2"))
+	assert.Equal(t, "v2", v2.HTTP.APIVersion)
+	assert.Panics(t, func() { SelectAPI("version_test") })
+	assert.Nil(t, checkRoutes("/api"))
+
+	router := GetTestRouter()
+	for _, version := range []string{"v1", "v2"} {
+		response := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/"+version+"/version_test/users?select=id,name", nil)
+		router.ServeHTTP(response, req)
+		assert.Equal(t, 200, response.Code)
+	}
+
+	LoadAPI(`{"name": "版本", "api_version": "v1", "group": "version_test", "paths": [{"path": "/users", "method": "GET", "process": "models.user.Get"}]}`, "version_test_dup")
+	defer delete(APIs, "version_test_dup@v1")
+	err := checkRoutes("/api")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "GET /api/v1/version_test/users")
+}
+
+func TestAPIErrorStatus(t *testing.T) {
+	LoadAPI(`{
+		"name": "错误状态码", "group": "error_test", "guard": "-",
+		"paths": [
+			{"path": "/info/:id", "method": "GET", "process": "models.user.Find", "in": ["$param.id", ":params"], "out": {"status": 200}},
+			{"path": "/users", "method": "POST", "process": "models.user.Create", "in": [":payload"]}
+		]
+	}`, "error_test")
+	defer delete(APIs, "error_test")
+	router := GetTestRouter()
+
+	response := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/error_test/info/100", nil)
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 404, response.Code)
+	assert.Equal(t, "ID=100的数据不存在", GetResponseMap(response).Get("message"))
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/error_test/users", strings.NewReader(`{"name": "错误状态码"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 400, response.Code)
+}
+
+func TestAPITransaction(t *testing.T) {
+	RegisterProcessHandler("txtest.user.write", func(process *Process) interface{} {
+		process.model().MustUpdate(1, maps.MapStr{"name": process.Args[1]})
+		if process.Args[0] == "fail" {
+			exception.New("写入失败", 400).Throw()
+		}
+		return nil
+	})
+	defer delete(ThirdHandlers, "txtest.user.write")
+
+	LoadAPI(`{
+		"name": "请求事务", "group": "tx_test", "guard": "-",
+		"paths": [
+			{"path": "/write/:mode/:name", "method": "POST", "process": "txtest.user.Write", "in": ["$param.mode", "$param.name"], "out": {"status": 200}, "transaction": true},
+			{"path": "/plain/:mode/:name", "method": "POST", "process": "txtest.user.Write", "in": ["$param.mode", "$param.name"], "out": {"status": 200}}
+		]
+	}`, "tx_test")
+	defer delete(APIs, "tx_test")
+	router := GetTestRouter()
+
+	user := Select("user")
+	name := user.MustFind(1, QueryParam{}).Get("name")
+	defer user.MustUpdate(1, maps.MapStr{"name": name})
+
+	request := func(path string) int {
+		response := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, nil)
+		router.ServeHTTP(response, req)
+		return response.Code
+	}
+
+	// 2xx 提交
+	assert.Equal(t, 200, request("/tx_test/write/ok/committed"))
+	assert.Equal(t, "committed", user.MustFind(1, QueryParam{}).Get("name"))
+
+	// 异常回滚
+	assert.Equal(t, 400, request("/tx_test/write/fail/rollback"))
+	assert.Equal(t, "committed", user.MustFind(1, QueryParam{}).Get("name"))
+
+	// 未启用请求事务
+	assert.Equal(t, 400, request("/tx_test/plain/fail/plain"))
+	assert.Equal(t, "plain", user.MustFind(1, QueryParam{}).Get("name"))
+
+	// 响应数据在事务提交后发送
+	response := httptest.NewRecorder()
+	buffered := gin.New()
+	buffered.POST("/buffered", RequestTransaction, func(c *gin.Context) {
+		c.String(201, "created")
+		assert.Equal(t, 0, response.Body.Len())
+	})
+	req, _ := http.NewRequest("POST", "/buffered", nil)
+	buffered.ServeHTTP(response, req)
+	assert.Equal(t, 201, response.Code)
+	assert.Equal(t, "created", response.Body.String())
+}
+
+func TestAPIFormats(t *testing.T) {
+	LoadAPI(`{
+		"name": "内容协商", "group": "format_test", "guard": "-",
+		"paths": [{"path": "/users", "method": "GET", "process": "models.user.Get", "in": [":params"], "out": {"status": 200, "formats": ["csv", "xml"]}}]
+	}`, "format_test")
+	defer delete(APIs, "format_test")
+	router := GetTestRouter()
+
+	response := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/format_test/users?select=id,name&format=csv", nil)
+	router.ServeHTTP(response, req)
+	assert.Contains(t, response.Header().Get("Content-Type"), "text/csv")
+	assert.True(t, strings.HasPrefix(response.Body.String(), "id,name\n1,"))
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/format_test/users?select=id,name", nil)
+	req.Header.Set("Accept", "text/html, application/xml;q=0.9")
+	router.ServeHTTP(response, req)
+	assert.Contains(t, response.Header().Get("Content-Type"), "application/xml")
+	assert.Contains(t, response.Body.String(), "<response><item><id>1</id>")
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/format_test/users?select=id,name&format=yaml", nil)
+	router.ServeHTTP(response, req)
+	res := []map[string]interface{}{}
+	assert.Nil(t, jsoniter.Unmarshal(response.Body.Bytes(), &res))
+	assert.Equal(t, float64(1), res[0]["id"])
+}
+
+func TestAPIMaxBodySize(t *testing.T) {
+	LoadAPI(`{
+		"name": "请求数据大小", "group": "body_test", "guard": "-",
+		"paths": [
+			{"path": "/find", "method": "POST", "process": "models.user.Find", "in": ["$payload.id", ":params"], "out": {"status": 200}},
+			{"path": "/upload", "method": "POST", "process": "models.user.Find", "in": ["$payload.id", ":params"], "out": {"status": 200}, "max_body_size": 1024}
+		]
+	}`, "body_test")
+	defer delete(APIs, "body_test")
+	DefaultMaxBodySize = 64
+	defer func() { DefaultMaxBodySize = 10 << 20 }()
+	router := GetTestRouter()
+
+	post := func(url string, body string, chunked bool) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if chunked {
+			req.ContentLength = -1
+		}
+		router.ServeHTTP(response, req)
+		return response
+	}
+
+	large := `{"id": 1, "pad": "` + strings.Repeat("x", 100) + `"}`
+	assert.Equal(t, 200, post("/body_test/find", `{"id": 1}`, false).Code)
+	assert.Equal(t, 413, post("/body_test/find", large, false).Code)
+	assert.Equal(t, 413, post("/body_test/find", large, true).Code)
+	assert.Equal(t, 200, post("/body_test/upload", large, false).Code)
+}
+
+func TestAPIIdempotency(t *testing.T) {
+	LoadAPI(`{
+		"name": "幂等键", "group": "idempotency_test", "guard": "-",
+		"paths": [
+			{"path": "/find", "method": "POST", "process": "models.user.Find", "in": ["$payload.id", ":params"], "out": {"status": 200}, "idempotent": true}
+		]
+	}`, "idempotency_test")
+	defer delete(APIs, "idempotency_test")
+	IdempotencyStore = NewMemoryIdempotentStore()
+	router := GetTestRouter()
+
+	post := func(id int, key string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/idempotency_test/find", strings.NewReader(fmt.Sprintf(`{"id": %d}`, id)))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		router.ServeHTTP(response, req)
+		return response
+	}
+
+	first := post(1, "k1")
+	assert.Equal(t, 200, first.Code)
+	assert.Equal(t, "", first.Header().Get("Idempotent-Replayed"))
+
+	replayed := post(1, "k1") // 相同幂等键返回首次请求的响应结果
+	assert.Equal(t, 200, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), replayed.Body.String())
+
+	assert.Equal(t, 422, post(2, "k1").Code) // 请求数据不同
+
+	// 无会话的请求按客户端 IP 区分幂等键
+	response := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/idempotency_test/find", strings.NewReader(`{"id": 1}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "k1")
+	req.RemoteAddr = "198.51.100.1:1234"
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "", response.Header().Get("Idempotent-Replayed"))
+
+	assert.Equal(t, float64(2), GetResponseMap(post(2, "k2")).Get("id"))
+	assert.Equal(t, float64(2), GetResponseMap(post(2, "")).Get("id"))
+
+	assert.True(t, Path{Process: "models.user.Create"}.idempotent())
+	assert.False(t, Path{Process: "models.user.Find"}.idempotent())
+
+	store := NewMemoryIdempotentStore()
+	store.Set("expired", &IdempotentResponse{Status: 200}, -time.Second)
+	_, has := store.Get("expired")
+	assert.False(t, has)
+}
+
+func TestAPICreated(t *testing.T) {
+	LoadAPI(`{
+		"name": "创建", "group": "created_test", "guard": "-",
+		"paths": [{"path": "/users", "method": "POST", "process": "models.user.Create", "in": [":payload"]}]
+	}`, "created_test")
+	defer delete(APIs, "created_test")
+	router := GetTestRouter()
+
+	post := func() *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/created_test/users", strings.NewReader(`{
+			"name": "创建接口", "manu_id": 2, "type": "user", "idcard": "23082619820207006X", "mobile": "13900005555",
+			"password": "qV@uT1DI", "key": "XZ12MiPp", "secret": "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN", "status": "enabled"
+		}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "created")
+		router.ServeHTTP(response, req)
+		return response
+	}
+
+	response := post()
+	res := GetResponseMap(response)
+	id := any.Of(res.Get("id")).CInt()
+	defer capsule.Query().Table(Select("user").MetaData.Table.Name).Where("id", id).Delete()
+	assert.Equal(t, 201, response.Code)
+	assert.Equal(t, fmt.Sprintf("/created_test/users/%d", id), response.Header().Get("Location"))
+	assert.Equal(t, "创建接口", res.Get("name"))
+
+	replayed := post() // 相同幂等键不重复创建
+	assert.Equal(t, 201, replayed.Code)
+	assert.Equal(t, response.Body.String(), replayed.Body.String())
+	rows := capsule.Query().Table(Select("user").MetaData.Table.Name).Where("name", "创建接口").MustGet()
+	assert.Equal(t, 1, len(rows))
+}
+
+func TestAPIStream(t *testing.T) {
+	chunk := StreamChunkSize
+	StreamChunkSize = 1
+	defer func() { StreamChunkSize = chunk }()
+	LoadAPI(`{
+		"name": "流式响应", "group": "stream_test", "guard": "-",
+		"paths": [
+			{"path": "/users", "method": "GET", "process": "models.user.Get", "in": [":query-param"], "out": {"status": 200, "stream": true}},
+			{"path": "/all", "method": "GET", "process": "models.user.Get", "in": [":query-param"], "out": {"status": 200}}
+		]
+	}`, "stream_test")
+	defer delete(APIs, "stream_test")
+	router := GetTestRouter()
+
+	get := func(url string) []map[string]interface{} {
+		response := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(response, req)
+		assert.Equal(t, 200, response.Code)
+		assert.Contains(t, response.Header().Get("Content-Type"), "application/json")
+		rows := []map[string]interface{}{}
+		assert.Nil(t, jsoniter.Unmarshal(response.Body.Bytes(), &rows))
+		return rows
+	}
+
+	assert.Equal(t, get("/stream_test/all?select=id,name"), get("/stream_test/users?select=id,name"))
+	assert.Equal(t, 0, len(get("/stream_test/users?where.name.eq=none")))
+}
+
+func TestJWTGuard(t *testing.T) {
+	AddHTTPGuard("jwt_test", JWTGuard(JWTConfig{Secret: "secret", Issuer: "gou", Audience: "api", Leeway: time.Minute}))
+	AddHTTPGuard("jwt_test_admin", JWTScopes("scope", "admin"))
+	defer delete(HTTPGuards, "jwt_test")
+	defer delete(HTTPGuards, "jwt_test_admin")
+	LoadAPI(`{
+		"name": "JWT", "group": "jwt_test", "guard": "jwt_test",
+		"paths": [
+			{"path": "/info/:id", "method": "GET", "process": "models.user.Find", "in": ["$param.id", ":params"], "out": {"status": 200}},
+			{"path": "/admin/:id", "method": "GET", "guard": "jwt_test,jwt_test_admin", "process": "models.user.Find", "in": ["$param.id", ":params"], "out": {"status": 200}}
+		]
+	}`, "jwt_test")
+	defer delete(APIs, "jwt_test")
+	router := GetTestRouter()
+
+	sign := func(claims map[string]interface{}) string { return signTestJWT("secret", claims) }
+	get := func(url string, token string) int {
+		response := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(response, req)
+		return response.Code
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := sign(map[string]interface{}{"sub": "1", "iss": "gou", "aud": "api", "exp": exp, "scope": "read"})
+	assert.Equal(t, 200, get("/jwt_test/info/1", valid))
+	assert.Equal(t, 401, get("/jwt_test/info/1", ""))
+	assert.Equal(t, 401, get("/jwt_test/info/1", valid+"x"))
+	assert.Equal(t, 401, get("/jwt_test/info/1", sign(map[string]interface{}{"iss": "gou", "aud": "api", "exp": time.Now().Add(-time.Hour).Unix()})))
+	assert.Equal(t, 200, get("/jwt_test/info/1", sign(map[string]interface{}{"iss": "gou", "aud": "api", "exp": time.Now().Add(-30 * time.Second).Unix()}))) // 时钟偏差
+	assert.Equal(t, 401, get("/jwt_test/info/1", sign(map[string]interface{}{"iss": "other", "aud": "api", "exp": exp})))
+	assert.Equal(t, 401, get("/jwt_test/info/1", sign(map[string]interface{}{"iss": "gou", "aud": []string{"web"}, "exp": exp})))
+	assert.Equal(t, 403, get("/jwt_test/admin/1", valid))
+	assert.Equal(t, 200, get("/jwt_test/admin/1", sign(map[string]interface{}{"iss": "gou", "aud": []string{"web", "api"}, "exp": exp, "scope": []string{"read", "admin"}})))
+
+	// RS256 公钥校验
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	verifier := &jwtVerifier{config: JWTConfig{}, keys: map[string]crypto.PublicKey{}}
+	verifier.publicKey, _ = parsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	header, _ := jsoniter.Marshal(map[string]interface{}{"alg": "RS256"})
+	payload, _ := jsoniter.Marshal(map[string]interface{}{"sub": "1", "exp": exp})
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	claims, err := verifier.verify(signed + "." + base64.RawURLEncoding.EncodeToString(signature))
+	assert.Nil(t, err)
+	assert.Equal(t, "1", claims["sub"])
+	_, err = verifier.verify(valid) // HS256 令牌不能通过公钥校验
+	assert.NotNil(t, err)
+
+	// 不含过期时间的令牌默认拒绝
+	noexp := sign(map[string]interface{}{"iss": "gou", "aud": "api"})
+	assert.Equal(t, 401, get("/jwt_test/info/1", noexp))
+	verifier = &jwtVerifier{config: JWTConfig{Secret: "secret", AllowNoExp: true}, keys: map[string]crypto.PublicKey{}}
+	_, err = verifier.verify(noexp)
+	assert.Nil(t, err)
+}
+
+func TestJWTJWKS(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var fail, requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		jsoniter.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]interface{}{{
+			"kty": "RSA", "kid": "k1",
+			"n": base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	verifier := &jwtVerifier{config: JWTConfig{JWKS: server.URL}, keys: map[string]crypto.PublicKey{}}
+	pub, err := verifier.key("k1")
+	assert.Nil(t, err)
+	assert.Equal(t, key.PublicKey.N, pub.(*rsa.PublicKey).N)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// 未知 kid 1 分钟内不重复读取
+	_, err = verifier.key("k2")
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// 缓存过期且读取失败时继续使用已缓存的公钥
+	atomic.StoreInt32(&fail, 1)
+	verifier.lock.Lock()
+	verifier.fetched = time.Now().Add(-2 * JWTRefresh)
+	verifier.checked = verifier.fetched
+	verifier.lock.Unlock()
+	pub, err = verifier.key("k1")
+	assert.Nil(t, err)
+	assert.NotNil(t, pub)
+	for i := 0; i < 100 && atomic.LoadInt32(&requests) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	pub, err = verifier.key("k1")
+	assert.Nil(t, err)
+	assert.NotNil(t, pub)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestAPIAuthorize(t *testing.T) {
+	AddHTTPGuard("jwt_test", JWTGuard(JWTConfig{Secret: "secret"}))
+	defer delete(HTTPGuards, "jwt_test")
+	LoadAPI(`{
+		"name": "授权", "group": "authorize_test", "guard": "jwt_test",
+		"paths": [
+			{"path": "/write/:id", "method": "GET", "scopes": ["user:write"], "process": "models.user.Find", "in": ["$param.id", ":params"], "out": {"status": 200}},
+			{"path": "/admin/:id", "method": "GET", "roles": ["admin", "root"], "process": "models.user.Find", "in": ["$param.id", ":params"], "out": {"status": 200}},
+			{"path": "/open/:id", "method": "GET", "guard": "-", "scopes": ["user:write"], "process": "models.user.Find", "in": ["$param.id", ":params"], "out": {"status": 200}}
+		]
+	}`, "authorize_test")
+	defer delete(APIs, "authorize_test")
+	router := GetTestRouter()
+
+	get := func(url string, claims map[string]interface{}) int {
+		response := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		if claims != nil {
+			claims["exp"] = time.Now().Add(time.Hour).Unix()
+			req.Header.Set("Authorization", "Bearer "+signTestJWT("secret", claims))
+		}
+		router.ServeHTTP(response, req)
+		return response.Code
+	}
+
+	assert.Equal(t, 200, get("/authorize_test/write/1", map[string]interface{}{"scope": "user:read user:write"}))
+	assert.Equal(t, 403, get("/authorize_test/write/1", map[string]interface{}{"scope": "user:read"}))
+	assert.Equal(t, 200, get("/authorize_test/admin/1", map[string]interface{}{"roles": []string{"staff", "admin"}}))
+	assert.Equal(t, 403, get("/authorize_test/admin/1", map[string]interface{}{"roles": "staff"}))
+	assert.Equal(t, 401, get("/authorize_test/open/1", nil)) // 未鉴权
+}
+
+func signTestJWT(secret string, claims map[string]interface{}) string {
+	header, _ := jsoniter.Marshal(map[string]interface{}{"alg": "HS256", "typ": "JWT"})
+	payload, _ := jsoniter.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestNewRouter(t *testing.T) {
+	assert.Equal(t, 0, len(NewRouter(Server{}).Handlers))
+	assert.Equal(t, 2, len(NewRouter(Server{Gin: true}).Handlers))
+}
+
 func GetResponseMap(resp *httptest.ResponseRecorder) maps.MapStrAny {
 	body := resp.Body.Bytes()
 	res := map[string]interface{}{}
 	jsoniter.Unmarshal(body, &res)
 	return maps.Of(res)
 }
+
+func TestGenerateOpenAPI(t *testing.T) {
+	bytes, err := GenerateOpenAPI()
+	assert.Nil(t, err)
+
+	doc := maps.MapStr{}
+	err = jsoniter.Unmarshal(bytes, &doc)
+	assert.Nil(t, err)
+	assert.Equal(t, "3.0.3", doc.Get("openapi"))
+
+	res := doc.Dot()
+	assert.Equal(t, "bearer-jwt", res.Get("paths./user/info/{id}.get.x-guard"))
+	assert.Equal(t, "id", res.Get("paths./user/info/{id}.get.parameters.0.name"))
+	assert.Equal(t, "path", res.Get("paths./user/info/{id}.get.parameters.0.in"))
+	assert.Equal(t, "#/components/schemas/user", res.Get("paths./user/info/{id}.get.responses.200.content.application/json.schema.$ref"))
+	assert.Nil(t, res.Get("paths./user/login.post.x-guard"))
+	assert.Equal(t, "object", res.Get("paths./user/login.post.requestBody.content.application/json.schema.type"))
+	assert.Equal(t, "object", res.Get("components.schemas.user.type"))
+	assert.Equal(t, "integer", res.Get("components.schemas.user.properties.id.type"))
+}
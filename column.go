@@ -1,7 +1,11 @@
 package gou
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/kun/day"
@@ -19,6 +23,24 @@ func (column *Column) FliterIn(value interface{}, row maps.MapStrAny) {
 	column.fliterInDateTime(value, row)
 }
 
+// TimeFormat 日期时间字段 (datetime, timestamp) 的输出格式和输入解析格式, 为空时不转换 (模型可通过 option.time_format 单独设定)
+var TimeFormat = ""
+
+// TimeZone 日期时间字段的输出时区和输入解析时区, 设置时区未设置格式时使用 RFC3339 (模型可通过 option.timezone 单独设定)
+// 数据库中的日期时间按本地时区 (time.Local) 读写
+var TimeZone *time.Location
+
+var timeLocations = sync.Map{}
+
+// outputTransformers 自定义输出转换函数 (按字段类型)
+var outputTransformers = map[string]func(value interface{}, col Column) interface{}{}
+
+// RegisterOutputTransformer 注册字段输出转换函数, 按字段类型 (Column.Type) 匹配, 在内置输出过滤 (JSON 解码等) 之后执行
+// 同一类型重复注册时覆盖
+func RegisterOutputTransformer(typeName string, fn func(value interface{}, col Column) interface{}) {
+	outputTransformers[typeName] = fn
+}
+
 // FliterOut 输出过滤器
 func (column *Column) FliterOut(value interface{}, row maps.MapStrAny, export ...string) {
 	exportName := ""
@@ -26,6 +48,143 @@ func (column *Column) FliterOut(value interface{}, row maps.MapStrAny, export ..
 		exportName = export[0]
 	}
 	column.fliterOutJSON(value, row, exportName)
+	column.fliterOutTime(value, row, exportName)
+	column.fliterOutTransform(row, exportName)
+}
+
+// fliterOutTime 日期时间字段按 TimeFormat, TimeZone 输出, 空值和无法解析的数值不转换
+func (column *Column) fliterOutTime(value interface{}, row maps.MapStrAny, export string) {
+	layout, loc := column.timeFormat()
+	if layout == "" {
+		return
+	}
+
+	t, ok := parseTime(value)
+	if !ok {
+		return
+	}
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	name := column.Name
+	if export != "" {
+		name = export
+	}
+	row.Set(name, t.Format(layout))
+}
+
+// timeFormat 日期时间字段的格式和时区 (模型配置优先), 非日期时间字段返回空
+func (column *Column) timeFormat() (string, *time.Location) {
+	switch strings.ToLower(column.Type) {
+	case "datetime", "datetimetz", "timestamp", "timestamptz":
+	default:
+		return "", nil
+	}
+
+	layout, loc := TimeFormat, TimeZone
+	if column.model != nil {
+		option := column.model.MetaData.Option
+		if option.TimeFormat != "" {
+			layout = option.TimeFormat
+		}
+		if option.TimeZone != "" {
+			loc = timeLocation(option.TimeZone)
+		}
+	}
+	if layout == "" && loc != nil {
+		layout = time.RFC3339
+	}
+	return layout, loc
+}
+
+// timeLocation 读取时区 (缓存), 时区无效时抛出异常
+func timeLocation(name string) *time.Location {
+	if loc, has := timeLocations.Load(name); has {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+	timeLocations.Store(name, loc)
+	return loc
+}
+
+// parseTime 数据库返回的日期时间 (time.Time 或按本地时区解析的字符串) 转换为 time.Time
+func parseTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return time.Time{}, false
+		}
+		return *v, true
+	case []byte:
+		return parseTime(string(v))
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05"} {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// fliterOutTransform 自定义输出转换
+func (column *Column) fliterOutTransform(row maps.MapStrAny, export string) {
+	fn, has := outputTransformers[column.Type]
+	if !has {
+		return
+	}
+	name := column.Name
+	if export != "" {
+		name = export
+	}
+	row.Set(name, fn(row[name], *column))
+}
+
+// coerce 字符串数值按字段类型转换, 无法转换时返回原值
+func (column *Column) coerce(value interface{}) interface{} {
+	input, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	input = strings.TrimSpace(input)
+	schema := column.schemaType()
+	switch schema["type"] {
+	case "integer":
+		if v, err := strconv.ParseInt(input, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(input, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(input); err == nil {
+			return v
+		}
+	case "string":
+		layout := ""
+		switch schema["format"] {
+		case "date":
+			layout = "2006-01-02"
+		case "date-time":
+			layout = "2006-01-02 15:04:05"
+		default:
+			return value
+		}
+		for _, iso := range []string{time.RFC3339Nano, "2006-01-02T15:04:05"} {
+			if v, err := time.ParseInLocation(iso, input, time.Local); err == nil {
+				return v.Local().Format(layout)
+			}
+		}
+	}
+	return value
 }
 
 // fliterInJSON JSON字段处理
@@ -103,13 +262,35 @@ func (column *Column) fliterInDateTime(value interface{}, row maps.MapStrAny) {
 	switch typ {
 	case "datetime", "date", "datetimeTz", "timestamp", "timestampTz", "time", "timeTz":
 		if _, ok := value.(dbal.Expression); !ok {
-			if value != nil {
+			if t, ok := column.parseTimeIn(value); ok {
+				row.Set(column.Name, t.Format("2006-01-02 15:04:05"))
+			} else if value != nil {
 				row.Set(column.Name, day.Of(value).Format("2006-01-02 15:04:05"))
 			}
 		}
 	}
 }
 
+// parseTimeIn 按 TimeFormat, TimeZone 解析输入的日期时间字符串并转换为本地时区, 无法解析时返回 false
+func (column *Column) parseTimeIn(value interface{}) (time.Time, bool) {
+	input, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	layout, loc := column.timeFormat()
+	if layout == "" {
+		return time.Time{}, false
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	t, err := time.ParseInLocation(layout, input, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.In(time.Local), true
+}
+
 // fliterInJSON JSON字段处理
 func (column *Column) fliterInJSON(value interface{}, row maps.MapStrAny) {
 	if strings.ToLower(column.Type) != "json" {
@@ -123,7 +304,12 @@ func (column *Column) fliterInJSON(value interface{}, row maps.MapStrAny) {
 }
 
 // Validate 数值有效性验证
-func (column *Column) Validate(value interface{}, row maps.MapStrAny) (bool, []string) {
+func (column *Column) Validate(value interface{}, row maps.MapStrAny, locale ...string) (bool, []string) {
+	lang := ""
+	if len(locale) > 0 {
+		lang = locale[0]
+	}
+
 	messages := []string{}
 	success := true
 	for _, v := range column.Validations {
@@ -134,7 +320,10 @@ func (column *Column) Validate(value interface{}, row maps.MapStrAny) (bool, []s
 		if !method(value, row, v.Args...) {
 			data := column.Map()
 			data["input"] = value
-			message := str.Bind(v.Message, data)
+			if len(v.Args) > 0 {
+				data["arg"] = v.Args[0]
+			}
+			message := str.Bind(v.message(lang), data)
 			messages = append(messages, message)
 			success = false
 		}
@@ -142,6 +331,18 @@ func (column *Column) Validate(value interface{}, row maps.MapStrAny) (bool, []s
 	return success, messages
 }
 
+// required 创建数据时是否必须提供数值 (不允许为空, 没有默认值, 非自增或自动生成字段)
+func (column *Column) required() bool {
+	if column.Nullable || column.Default != nil || column.DefaultRaw != "" || column.Generate != "" {
+		return false
+	}
+	switch strings.ToLower(column.Type) {
+	case "id", "increments", "tinyincrements", "smallincrements", "mediumincrements", "bigincrements":
+		return false
+	}
+	return true
+}
+
 // Map 转换为Map
 func (column *Column) Map() map[string]interface{} {
 	res := map[string]interface{}{}
@@ -156,6 +357,48 @@ func (column *Column) Map() map[string]interface{} {
 	return res
 }
 
+// UnmarshalJSON 解析字段描述, unique 可以为布尔值或范围唯一约束 {"scope": [...], "ignore_deleted": true}
+func (column *Column) UnmarshalJSON(data []byte) error {
+	raw := map[string]jsoniter.RawMessage{}
+	err := jsoniter.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	unique, has := raw["unique"]
+	delete(raw, "unique")
+	data, err = jsoniter.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	type alias Column
+	err = jsoniter.Unmarshal(data, (*alias)(column))
+	if err != nil || !has {
+		return err
+	}
+
+	trimed := strings.TrimSpace(string(unique))
+	if strings.HasPrefix(trimed, "{") {
+		scope := UniqueScope{}
+		err = jsoniter.Unmarshal(unique, &scope)
+		if err != nil {
+			return fmt.Errorf("字段 %s 范围唯一约束格式错误: %s", column.Name, err)
+		}
+		column.Unique = false
+		column.UniqueScope = &scope
+		return nil
+	}
+	if trimed == "null" {
+		return nil
+	}
+	err = jsoniter.Unmarshal(unique, &column.Unique)
+	if err != nil {
+		return fmt.Errorf("字段 %s unique 应为布尔值或范围唯一约束: %s", column.Name, err)
+	}
+	return nil
+}
+
 // SetOption 设置字段选项
 func (column Column) SetOption(col *schema.Column) {
 	if column.Comment != "" { // 注释
@@ -183,158 +426,20 @@ func (column Column) SetOption(col *schema.Column) {
 	}
 }
 
-// SetType 设置字段类型
+// SetType 设置字段类型 (内置类型)
 func (column Column) SetType(table schema.Blueprint) *schema.Column {
-
-	switch column.Type {
-	// String
-	case "string":
-		return table.String(column.Name, column.Length)
-	case "char":
-		return table.Char(column.Name, column.Length)
-
-	case "text":
-		return table.Text(column.Name)
-	case "mediumText":
-		return table.MediumText(column.Name)
-	case "longText":
-		return table.LongText(column.Name)
-
-	// Binary
-	case "binary":
-		return table.Binary(column.Name, column.Length)
-
-	// Datetime
-	case "date":
-		return table.Date(column.Name)
-	case "datetime":
-		if column.Length > 0 {
-			return table.DateTime(column.Name, column.Length)
-		}
-		return table.DateTime(column.Name)
-	case "datetimeTz":
-		if column.Length > 0 {
-			return table.DateTime(column.Name, column.Length)
-
-		}
-		return table.DateTime(column.Name)
-	case "time":
-		if column.Length > 0 {
-			return table.Time(column.Name, column.Length)
-
-		}
-		return table.Time(column.Name)
-
-	case "timeTz":
-		if column.Length > 0 {
-			return table.TimeTz(column.Name, column.Length)
-
-		}
-		return table.TimeTz(column.Name)
-
-	case "timestamp":
-		if column.Length > 0 {
-			return table.Timestamp(column.Name, column.Length)
-
-		}
-		return table.Timestamp(column.Name)
-
-	case "timestampTz":
-		if column.Length > 0 {
-			return table.TimestampTz(column.Name, column.Length)
-
-		}
-		return table.TimestampTz(column.Name)
-
-	// Numberic: Integer
-	case "tinyInteger":
-		return table.TinyInteger(column.Name)
-
-	case "unsignedTinyInteger":
-		return table.UnsignedTinyInteger(column.Name)
-
-	case "tinyIncrements":
-		return table.TinyIncrements(column.Name)
-
-	case "smallInteger":
-		return table.SmallInteger(column.Name)
-
-	case "unsignedSmallInteger":
-		return table.UnsignedSmallInteger(column.Name)
-
-	case "smallIncrements":
-		return table.SmallIncrements(column.Name)
-
-	case "integer":
-		return table.Integer(column.Name)
-
-	case "unsignedInteger":
-		return table.UnsignedInteger(column.Name)
-
-	case "increments":
-		return table.Increments(column.Name)
-
-	case "bigInteger":
-		return table.BigInteger(column.Name)
-
-	case "unsignedBigInteger":
-		return table.UnsignedBigInteger(column.Name)
-
-	case "bigIncrements":
-		return table.BigIncrements(column.Name)
-
-	case "id", "ID":
-		return table.ID(column.Name)
-
-	// Numberic: Decimal
-	case "decimal":
-		return table.Decimal(column.Name, column.Precision, column.Scale)
-
-	case "unsignedDecimal":
-		return table.UnsignedDecimal(column.Name, column.Precision, column.Scale)
-
-	case "float":
-		return table.Float(column.Name, column.Precision, column.Scale)
-
-	case "unsignedFloat":
-		return table.UnsignedFloat(column.Name, column.Precision, column.Scale)
-
-	case "double":
-		return table.Double(column.Name, column.Precision, column.Scale)
-
-	case "unsignedDouble":
-		return table.UnsignedDouble(column.Name, column.Precision, column.Scale)
-
-	// Boolen,enum
-	case "Boolean", "boolean":
-		return table.Boolean(column.Name)
-
-	case "enum":
-		return table.Enum(column.Name, column.Option)
-
-	// JSON
-	case "json", "JSON":
-		return table.JSON(column.Name)
-
-	case "jsonb", "JSONB":
-		return table.JSONB(column.Name)
-
-	// uuid, ipAddress, macAddress, year etc.
-	case "uuid":
-		return table.UUID(column.Name)
-
-	case "ipAddress":
-		return table.IPAddress(column.Name)
-
-	case "macAddress":
-		return table.MACAddress(column.Name)
-
-	case "year":
-		return table.Year(column.Name)
-
+	typ, has := columnTypes[column.Type]
+	if !has || typ.Blueprint == nil {
+		exception.New("类型错误 %s %s", 400, column.Type, column.Name).Throw()
 	}
+	return typ.Blueprint(column, table)
+}
 
-	exception.New("类型错误 %s %s", 400, column.Type, column.Name).Throw()
-
-	return nil
+// DDL 自定义类型字段定义, 非自定义类型返回空字符串
+func (column Column) DDL(driver string) string {
+	typ, has := columnTypes[column.Type]
+	if !has || typ.DDL == nil {
+		return ""
+	}
+	return typ.DDL(column, driver)
 }
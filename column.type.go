@@ -0,0 +1,178 @@
+package gou
+
+import (
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// ColumnType 字段类型, 内置类型使用 Blueprint 方法创建字段, 自定义类型使用 DDL 语句创建字段
+type ColumnType struct {
+	Blueprint func(column Column, table schema.Blueprint) *schema.Column // 内置类型
+	DDL       func(column Column, driver string) string                  // 自定义类型, 返回字段名称之后的字段定义
+}
+
+// columnTypes 已注册字段类型
+var columnTypes = map[string]ColumnType{}
+
+// RegisterColumnType 注册自定义字段类型, ddl 按数据库驱动返回字段名称之后的完整字段定义 (如 "inet NOT NULL")
+// 自定义字段在数据表创建后以 ALTER TABLE ... ADD COLUMN 添加, 同名内置类型将被覆盖
+func RegisterColumnType(name string, ddl func(col Column, driver string) string) {
+	columnTypes[name] = ColumnType{DDL: ddl}
+}
+
+// registerBlueprintType 注册内置字段类型
+func registerBlueprintType(fn func(column Column, table schema.Blueprint) *schema.Column, names ...string) {
+	for _, name := range names {
+		columnTypes[name] = ColumnType{Blueprint: fn}
+	}
+}
+
+func init() {
+
+	// String
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.String(column.Name, column.Length)
+	}, "string")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Char(column.Name, column.Length)
+	}, "char")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Text(column.Name)
+	}, "text")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.MediumText(column.Name)
+	}, "mediumText")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.LongText(column.Name)
+	}, "longText")
+
+	// Binary
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Binary(column.Name, column.Length)
+	}, "binary")
+
+	// Datetime
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Date(column.Name)
+	}, "date")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		if column.Length > 0 {
+			return table.DateTime(column.Name, column.Length)
+		}
+		return table.DateTime(column.Name)
+	}, "datetime", "datetimeTz")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		if column.Length > 0 {
+			return table.Time(column.Name, column.Length)
+		}
+		return table.Time(column.Name)
+	}, "time")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		if column.Length > 0 {
+			return table.TimeTz(column.Name, column.Length)
+		}
+		return table.TimeTz(column.Name)
+	}, "timeTz")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		if column.Length > 0 {
+			return table.Timestamp(column.Name, column.Length)
+		}
+		return table.Timestamp(column.Name)
+	}, "timestamp")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		if column.Length > 0 {
+			return table.TimestampTz(column.Name, column.Length)
+		}
+		return table.TimestampTz(column.Name)
+	}, "timestampTz")
+
+	// Numberic: Integer
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.TinyInteger(column.Name)
+	}, "tinyInteger")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.UnsignedTinyInteger(column.Name)
+	}, "unsignedTinyInteger")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.TinyIncrements(column.Name)
+	}, "tinyIncrements")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.SmallInteger(column.Name)
+	}, "smallInteger")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.UnsignedSmallInteger(column.Name)
+	}, "unsignedSmallInteger")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.SmallIncrements(column.Name)
+	}, "smallIncrements")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Integer(column.Name)
+	}, "integer")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.UnsignedInteger(column.Name)
+	}, "unsignedInteger")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Increments(column.Name)
+	}, "increments")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.BigInteger(column.Name)
+	}, "bigInteger")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.UnsignedBigInteger(column.Name)
+	}, "unsignedBigInteger")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.BigIncrements(column.Name)
+	}, "bigIncrements")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.ID(column.Name)
+	}, "id", "ID")
+
+	// Numberic: Decimal
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Decimal(column.Name, column.Precision, column.Scale)
+	}, "decimal")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.UnsignedDecimal(column.Name, column.Precision, column.Scale)
+	}, "unsignedDecimal")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Float(column.Name, column.Precision, column.Scale)
+	}, "float")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.UnsignedFloat(column.Name, column.Precision, column.Scale)
+	}, "unsignedFloat")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Double(column.Name, column.Precision, column.Scale)
+	}, "double")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.UnsignedDouble(column.Name, column.Precision, column.Scale)
+	}, "unsignedDouble")
+
+	// Boolen,enum
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Boolean(column.Name)
+	}, "Boolean", "boolean")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Enum(column.Name, column.Option)
+	}, "enum")
+
+	// JSON
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.JSON(column.Name)
+	}, "json", "JSON")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.JSONB(column.Name)
+	}, "jsonb", "JSONB")
+
+	// uuid, ipAddress, macAddress, year etc.
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.UUID(column.Name)
+	}, "uuid")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.IPAddress(column.Name)
+	}, "ipAddress")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.MACAddress(column.Name)
+	}, "macAddress")
+	registerBlueprintType(func(column Column, table schema.Blueprint) *schema.Column {
+		return table.Year(column.Name)
+	}, "year")
+}
@@ -0,0 +1,127 @@
+package gou
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun"
+	"github.com/yaoapp/xun/capsule"
+)
+
+// ConnectMaxBackoff 连接重试的最长等待间隔
+var ConnectMaxBackoff = 30 * time.Second
+
+// ConnectTimeout 每次连接检查数据库是否可以访问的超时时间
+var ConnectTimeout = 5 * time.Second
+
+// DBConfig 数据库连接配置
+type DBConfig struct {
+	Name   string `json:"name,omitempty"` // 连接名称, 默认 primary
+	Driver string `json:"driver"`         // 数据库驱动 mysql, sqlite3, postgres
+	DSN    string `json:"dsn"`            // 数据库连接字符串
+}
+
+// 数据库连接状态 (ConnectWithRetry 设定)
+const (
+	dbIdle int32 = iota
+	dbConnecting
+	dbConnected
+	dbFailed
+)
+
+var dbState int32 = dbIdle
+
+// ConnectWithRetry 创建数据库连接并设为全局连接, 数据库无法访问时等待 backoff 后重试 (每次等待时间加倍, 最长 ConnectMaxBackoff)
+// 直到数据库可以访问或尝试 attempts 次 (attempts 小于 1 时尝试 1 次); 与 ServeHTTP 并行运行时, 连接成功前 HTTP 服务返回 503
+func ConnectWithRetry(config DBConfig, attempts int, backoff time.Duration) error {
+	if config.Name == "" {
+		config.Name = "primary"
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	atomic.StoreInt32(&dbState, dbConnecting)
+	var err error
+	for i := 1; i <= attempts; i++ {
+		var manager *capsule.Manager
+		manager, err = connect(config)
+		if err == nil {
+			manager.SetAsGlobal()
+			atomic.StoreInt32(&dbState, dbConnected)
+			return nil
+		}
+		if i == attempts {
+			break
+		}
+
+		log.Warn("数据库连接失败 (%d/%d), %s 后重试: %s", i, attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff = backoff * 2
+		if backoff > ConnectMaxBackoff {
+			backoff = ConnectMaxBackoff
+		}
+	}
+
+	atomic.StoreInt32(&dbState, dbFailed)
+	return fmt.Errorf("数据库连接失败 (已尝试 %d 次): %s", attempts, err)
+}
+
+// MustConnectWithRetry 创建数据库连接并设为全局连接, 失败抛出异常
+func MustConnectWithRetry(config DBConfig, attempts int, backoff time.Duration) {
+	err := ConnectWithRetry(config, attempts, backoff)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+}
+
+// connect 创建数据库连接, 并检查全部连接可以访问
+func connect(config DBConfig) (manager *capsule.Manager, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Catch(r)
+		}
+	}()
+
+	// 先检查数据库可以访问 (capsule.AddConn 在协程中检查连接, 无法访问时抛出的异常不能捕获)
+	db, err := sql.Open(config.Driver, config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ConnectTimeout)
+	defer cancel()
+	err = db.PingContext(ctx)
+	db.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	manager = capsule.AddConn(config.Name, config.Driver, config.DSN)
+	for _, conn := range managerConns(manager) {
+		if err := conn.db.Ping(); err != nil {
+			return nil, err
+		}
+	}
+	return manager, nil
+}
+
+// dbReadiness 数据库连接就绪检查, ConnectWithRetry 连接成功前返回 503
+func dbReadiness(c *gin.Context) {
+	state := atomic.LoadInt32(&dbState)
+	if state != dbConnecting && state != dbFailed {
+		c.Next()
+		return
+	}
+	c.Header("Retry-After", "5")
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, xun.R{
+		"code":    http.StatusServiceUnavailable,
+		"message": "数据库尚未连接",
+	})
+}
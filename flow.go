@@ -1,6 +1,7 @@
 package gou
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -165,6 +166,12 @@ func (flow *Flow) WithSID(sid string) *Flow {
 	return flow
 }
 
+// WithContext 设定请求上下文 (节点处理器继承该上下文)
+func (flow *Flow) WithContext(ctx context.Context) *Flow {
+	flow.Context = ctx
+	return flow
+}
+
 // WithGlobal 设定全局变量
 func (flow *Flow) WithGlobal(global map[string]interface{}) *Flow {
 	flow.Global = global
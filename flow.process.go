@@ -119,7 +119,7 @@ func (flow *Flow) RunProcess(node *FlowNode, ctx *FlowContext, data maps.Map) (i
 	}
 
 	if node.Process != "" {
-		process := NewProcess(node.Process, args...).WithGlobal(flow.Global).WithSID(flow.Sid)
+		process := NewProcess(node.Process, args...).WithGlobal(flow.Global).WithSID(flow.Sid).WithContext(flow.Context)
 		resp = process.Run()
 
 		// 当使用 Session start 设置SID时
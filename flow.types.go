@@ -19,6 +19,7 @@ type Flow struct {
 	Output      interface{}            `json:"output,omitempty"`
 	Global      map[string]interface{} // 全局变量
 	Sid         string                 // 会话ID
+	Context     context.Context        `json:"-"` // 请求上下文
 }
 
 // FlowNode 工作流节点
@@ -3,7 +3,9 @@ module github.com/yaoapp/gou
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/buraksezer/olric v0.4.2
+	github.com/fsnotify/fsnotify v1.5.1
 	github.com/gin-gonic/gin v1.7.7
 	github.com/go-errors/errors v1.4.2
 	github.com/go-playground/validator/v10 v10.10.0 // indirect
@@ -13,11 +15,12 @@ require (
 	github.com/robertkrimen/otto v0.0.0-20211024170158-b87d35c0b86f
 	github.com/stretchr/testify v1.7.0
 	github.com/ugorji/go v1.2.6 // indirect
+	github.com/xuri/excelize/v2 v2.5.0
 	github.com/yaoapp/kun v0.9.0
 	github.com/yaoapp/xun v0.0.0-00010101000000-000000000000
 	golang.org/x/crypto v0.0.0-20220208050332-20e1d8d225ab
 	golang.org/x/sys v0.0.0-20220207234003-57398862261d // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 	rogchap.com/v8go v0.7.0
 )
 
@@ -2,21 +2,114 @@ package helper
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v2"
 )
 
 // 常用函数
 
-// UnmarshalFile JSON Unmarshal
+// UnmarshalFile JSON Unmarshal (非 JSON 内容按 YAML 解析)
 func UnmarshalFile(file io.Reader, v interface{}) error {
+	return UnmarshalFileFormat(file, "", v)
+}
+
+// UnmarshalFileFormat 按格式 (json, yaml, yml, toml) 解析文件内容, 格式为空时根据内容判断 JSON 或 YAML
+// YAML/TOML 内容先转换为 JSON 再解析, 与 JSON 文件使用相同的 json 标签
+func UnmarshalFileFormat(file io.Reader, format string, v interface{}) error {
 	content, err := ReadFile(file)
 	if err != nil {
 		return err
 	}
 
-	return jsoniter.Unmarshal(content, v)
+	if format == "" {
+		format = "json"
+		trimed := bytes.TrimSpace(content)
+		if len(trimed) > 0 && trimed[0] != '{' && trimed[0] != '[' {
+			format = "yaml"
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return jsoniter.Unmarshal(content, v)
+	case "yaml", "yml":
+		var data interface{}
+		err = yaml.Unmarshal(content, &data)
+		if err != nil {
+			return err
+		}
+		content, err = jsoniter.Marshal(yamlValue(data))
+		if err != nil {
+			return err
+		}
+		return jsoniter.Unmarshal(content, v)
+	case "toml":
+		data := map[string]interface{}{}
+		_, err = toml.Decode(string(content), &data)
+		if err != nil {
+			return err
+		}
+		content, err = jsoniter.Marshal(data)
+		if err != nil {
+			return err
+		}
+		return jsoniter.Unmarshal(content, v)
+	}
+	return fmt.Errorf("不支持的文件格式 %s", format)
+}
+
+// FileFormat 根据文件扩展名读取文件格式 (json, yaml, yml, toml), 其他扩展名返回空 (根据内容判断)
+func FileFormat(filename string) string {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	switch format {
+	case "json", "yaml", "yml", "toml":
+		return format
+	}
+	return ""
+}
+
+var reEnv = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv 替换内容中的环境变量 ${NAME} 或 ${NAME:-默认值} (变量未设置或为空时使用默认值, 无默认值替换为空)
+// 环境变量的值原样替换, 不做转义; 字面量 ${ 写作 $${
+func ExpandEnv(content []byte) []byte {
+	return reEnv.ReplaceAllFunc(content, func(match []byte) []byte {
+		if bytes.Equal(match, []byte("$${")) {
+			return []byte("${")
+		}
+		sub := reEnv.FindSubmatch(match)
+		if value := os.Getenv(string(sub[1])); value != "" {
+			return []byte(value)
+		}
+		return sub[3]
+	})
+}
+
+// yamlValue YAML 解析结果转换为 JSON 兼容的数据 (map[interface{}]interface{} => map[string]interface{})
+func yamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		res := map[string]interface{}{}
+		for key, val := range v {
+			res[fmt.Sprintf("%v", key)] = yamlValue(val)
+		}
+		return res
+	case []interface{}:
+		res := []interface{}{}
+		for _, val := range v {
+			res = append(res, yamlValue(val))
+		}
+		return res
+	}
+	return value
 }
 
 // ReadFile 读取文件内容
@@ -142,7 +142,7 @@ func (resp *ResponseImport) InsertChunk(line int, data []map[string]string, mode
 	err := model.Insert(columns, values)
 	if err != nil {
 		resp.Failure = resp.Failure + total
-		resp.Error(err.Error(), 500).AtLine(line).ValueIs(data)
+		resp.Error(err.Error(), ErrorCode(err)).AtLine(line).ValueIs(data)
 		return
 	}
 	resp.Success = resp.Success + total
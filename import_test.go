@@ -2,9 +2,11 @@ package gou
 
 import (
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/xun/capsule"
 )
 
 func TestImportInsertCSV(t *testing.T) {
@@ -24,3 +26,66 @@ func TestImportInsertCSV(t *testing.T) {
 	assert.Equal(t, 100, res.Failure)
 	manu.Migrate(true)
 }
+
+func TestModelImportCSV(t *testing.T) {
+	address := Select("address")
+	data := "用户,省份,城市,地址\n" +
+		"9,北京市,丰台区,\"银海星月9号楼9单元9层1024室\"\n" +
+		"9,天津市,塘沽区,\"益海星云7号楼3单元1003室, 东门\"\n"
+
+	imported, errs := address.ImportCSV(strings.NewReader(data), map[string]string{
+		"user_id":  "用户",
+		"province": "省份",
+		"city":     "城市",
+		"location": "地址",
+	})
+
+	rows := address.MustGet(QueryParam{Wheres: []QueryWhere{{Column: "user_id", Value: 9}}})
+	capsule.Query().Table(address.MetaData.Table.Name).Where("user_id", 9).Delete()
+
+	assert.Equal(t, 2, imported)
+	assert.Equal(t, 0, len(errs))
+	assert.Equal(t, 2, len(rows))
+}
+
+func TestModelImportCSVRowErrors(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "导入测试",
+		"table": { "name": "import_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "label": "名称", "name": "name", "type": "string", "length": 80 },
+			{
+				"label": "数量", "name": "amount", "type": "integer",
+				"validations": [
+					{ "method": "typeof", "args": ["integer"], "message": "{{input}}类型错误, {{label}}应为数字" },
+					{ "method": "min", "args": [0], "message": "{{label}}应大于0" }
+				]
+			}
+		]
+	}`, "import_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("import_test")
+		delete(Models, "import_test")
+	}()
+
+	data := "name,amount\n" +
+		"foo,1\n" +
+		"bar,abc\n" +
+		"baz,-1\n" +
+		"qux,2\n"
+
+	imported, errs := mod.ImportCSV(strings.NewReader(data), nil)
+	assert.Equal(t, 2, imported)
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, 3, errs[0].Line)
+	assert.Equal(t, "amount", errs[0].Column)
+	assert.Equal(t, 4, errs[1].Line)
+	assert.Equal(t, "amount", errs[1].Column)
+
+	rows := mod.MustGet(QueryParam{Orders: []QueryOrder{{Column: "id"}}})
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "foo", rows[0].Get("name"))
+	assert.Equal(t, "qux", rows[1].Get("name"))
+}
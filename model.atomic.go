@@ -1,10 +1,15 @@
 package gou
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/kun/any"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/maps"
@@ -15,6 +20,7 @@ import (
 // Find 查询单条记录
 func (mod *Model) Find(id interface{}, param QueryParam) (maps.MapStr, error) {
 	param.Model = mod.Name
+	mod.bind(&param)
 	param.Wheres = []QueryWhere{
 		{
 			Column: mod.PrimaryKey,
@@ -22,12 +28,16 @@ func (mod *Model) Find(id interface{}, param QueryParam) (maps.MapStr, error) {
 		},
 	}
 	param.Limit = 1
+	fields, err := mod.applyFields(&param)
+	if err != nil {
+		return nil, err
+	}
 	stack := NewQueryStack(param)
 	res := stack.Run()
 	if len(res) <= 0 {
-		return nil, fmt.Errorf("ID=%v的数据不存在", id)
+		return nil, &ErrNotFound{Model: mod.Name, ID: id}
 	}
-	return res[0], nil
+	return fields.trim(res)[0], nil
 
 }
 
@@ -35,7 +45,88 @@ func (mod *Model) Find(id interface{}, param QueryParam) (maps.MapStr, error) {
 func (mod *Model) MustFind(id interface{}, param QueryParam) maps.MapStr {
 	res, err := mod.Find(id, param)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
+	}
+	return res
+}
+
+// FindMany 按ID集合查询多条记录 (单次 WHERE IN 查询), 返回结果按输入ID顺序排列
+func (mod *Model) FindMany(ids []interface{}, param QueryParam) ([]maps.MapStr, error) {
+	res := []maps.MapStr{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	param.Model = mod.Name
+	mod.bind(&param)
+	param.Wheres = append(param.Wheres, QueryWhere{
+		Column: mod.PrimaryKey,
+		OP:     "in",
+		Value:  ids,
+	})
+	param.Limit = len(ids)
+	selected := len(param.Select) == 0 || param.hasSelectColumn(mod.PrimaryKey)
+	param.selectPrimaryKey(mod) // 按主键排列结果
+	stack := NewQueryStack(param)
+	rows := stack.Run()
+
+	index := map[string]maps.MapStr{}
+	for _, row := range rows {
+		index[fmt.Sprintf("%v", row.Get(mod.PrimaryKey))] = row
+		if !selected {
+			row.Del(mod.PrimaryKey)
+		}
+	}
+
+	for _, id := range ids {
+		if row, has := index[fmt.Sprintf("%v", id)]; has {
+			res = append(res, row)
+		}
+	}
+	return res, nil
+}
+
+// MustFindMany 按ID集合查询多条记录, 失败抛出异常
+func (mod *Model) MustFindMany(ids []interface{}, param QueryParam) []maps.MapStr {
+	res, err := mod.FindMany(ids, param)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// FindForUpdate 查询单条记录并加行锁 (SELECT ... FOR UPDATE), 需在事务中调用
+func (mod *Model) FindForUpdate(id interface{}, param QueryParam) (maps.MapStr, error) {
+	if mod.tx == nil {
+		return nil, fmt.Errorf("FindForUpdate 需要在事务中调用")
+	}
+	param.Lock = "update"
+	return mod.Find(id, param)
+}
+
+// MustFindForUpdate 查询单条记录并加行锁, 失败抛出异常
+func (mod *Model) MustFindForUpdate(id interface{}, param QueryParam) maps.MapStr {
+	res, err := mod.FindForUpdate(id, param)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// Fresh 按数据中的主键重新读取记录 (数据库中的最新数据), 字段格式化及关联数据与 Find 一致
+func (mod *Model) Fresh(row maps.MapStr, param QueryParam) (maps.MapStr, error) {
+	id := row.Get(mod.PrimaryKey)
+	if id == nil {
+		return nil, fmt.Errorf("数据缺少主键 %s", mod.PrimaryKey)
+	}
+	return mod.Find(id, param)
+}
+
+// MustFresh 按数据中的主键重新读取记录, 失败抛出异常
+func (mod *Model) MustFresh(row maps.MapStr, param QueryParam) maps.MapStr {
+	res, err := mod.Fresh(row, param)
+	if err != nil {
+		throwError(err)
 	}
 	return res
 }
@@ -43,168 +134,959 @@ func (mod *Model) MustFind(id interface{}, param QueryParam) maps.MapStr {
 // Get 按条件查询, 不分页
 func (mod *Model) Get(param QueryParam) ([]maps.MapStr, error) {
 	param.Model = mod.Name
+	mod.bind(&param)
+	mod.defaultOrder(&param)
+	fields, err := mod.applyFields(&param)
+	if err != nil {
+		return nil, err
+	}
 	stack := NewQueryStack(param)
 	res := stack.Run()
-	return res, nil
+	return fields.trim(res), nil
 }
 
 // MustGet 按条件查询, 不分页, 失败抛出异常
 func (mod *Model) MustGet(param QueryParam) []maps.MapStr {
 	res, err := mod.Get(param)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
+	}
+	return res
+}
+
+// Pluck 按条件查询单个字段, 返回字段数值清单 (字段按模型定义解码, 未指定 Limit 时与 Get 一致最多返回 100 条)
+func (mod *Model) Pluck(column string, param QueryParam) ([]interface{}, error) {
+	if _, has := mod.Columns[column]; !has {
+		return nil, fmt.Errorf("字段 %s 不存在", column)
+	}
+
+	param.Select = []interface{}{column}
+	param.Withs = nil
+	param.WithCount = nil
+	rows, err := mod.Get(param)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []interface{}{}
+	for _, row := range rows {
+		res = append(res, row.Get(column))
+	}
+	return res, nil
+}
+
+// MustPluck 按条件查询单个字段, 失败抛出异常
+func (mod *Model) MustPluck(column string, param QueryParam) []interface{} {
+	res, err := mod.Pluck(column, param)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// PluckString 按条件查询单个字段, 返回字符串清单 (空值为空字符串)
+func (mod *Model) PluckString(column string, param QueryParam) ([]string, error) {
+	values, err := mod.Pluck(column, param)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			res = append(res, "")
+		case []byte:
+			res = append(res, string(v))
+		default:
+			res = append(res, fmt.Sprintf("%v", v))
+		}
+	}
+	return res, nil
+}
+
+// MustPluckString 按条件查询单个字段, 返回字符串清单, 失败抛出异常
+func (mod *Model) MustPluckString(column string, param QueryParam) []string {
+	res, err := mod.PluckString(column, param)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// PluckInt 按条件查询单个字段, 返回整数清单 (空值为 0)
+func (mod *Model) PluckInt(column string, param QueryParam) ([]int, error) {
+	values, err := mod.Pluck(column, param)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []int{}
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			res = append(res, 0)
+		case string, []byte:
+			n, err := strconv.Atoi(fmt.Sprintf("%s", v))
+			if err != nil {
+				return nil, fmt.Errorf("字段 %s 数值 %s 不是整数", column, v)
+			}
+			res = append(res, n)
+		default:
+			if !any.Of(v).IsNumber() {
+				return nil, fmt.Errorf("字段 %s 数值 %v 不是整数", column, v)
+			}
+			res = append(res, any.Of(v).CInt())
+		}
+	}
+	return res, nil
+}
+
+// MustPluckInt 按条件查询单个字段, 返回整数清单, 失败抛出异常
+func (mod *Model) MustPluckInt(column string, param QueryParam) []int {
+	res, err := mod.PluckInt(column, param)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// PluckMap 按条件查询两个字段, 返回以 keyColumn 数值为键, valueColumn 数值为值的映射表 (键重复时保留最后一条)
+func (mod *Model) PluckMap(keyColumn string, valueColumn string, param QueryParam) (maps.MapStr, error) {
+	for _, column := range []string{keyColumn, valueColumn} {
+		if _, has := mod.Columns[column]; !has {
+			return nil, fmt.Errorf("字段 %s 不存在", column)
+		}
+	}
+
+	param.Select = []interface{}{keyColumn, valueColumn}
+	param.Withs = nil
+	param.WithCount = nil
+	rows, err := mod.Get(param)
+	if err != nil {
+		return nil, err
+	}
+
+	res := maps.MapStr{}
+	for _, row := range rows {
+		key := row.Get(keyColumn)
+		if bytes, ok := key.([]byte); ok {
+			key = string(bytes)
+		}
+		res[fmt.Sprintf("%v", key)] = row.Get(valueColumn)
+	}
+	return res, nil
+}
+
+// MustPluckMap 按条件查询两个字段, 返回映射表, 失败抛出异常
+func (mod *Model) MustPluckMap(keyColumn string, valueColumn string, param QueryParam) maps.MapStr {
+	res, err := mod.PluckMap(keyColumn, valueColumn, param)
+	if err != nil {
+		throwError(err)
 	}
 	return res
 }
 
-// Paginate 按条件查询, 分页
+// Exists 检查是否存在符合条件的记录 (LIMIT 1, 不统计总数), 默认排除已软删除的记录
+func (mod *Model) Exists(param QueryParam) (bool, error) {
+	param.Model = mod.Name
+	mod.bind(&param)
+	param.Select = []interface{}{mod.PrimaryKey}
+	param.Withs = nil
+	param.WithCount = nil
+	param.Orders = nil
+	param.Limit = 1
+	start := time.Now()
+	row, err := NewQueryStack(param).FirstQuery().First()
+	if err != nil {
+		return false, err
+	}
+	if row.IsEmpty() {
+		mod.stat(start, 1, 0, 0)
+		return false, nil
+	}
+	mod.stat(start, 1, 1, 0)
+	return true, nil
+}
+
+// MustExists 检查是否存在符合条件的记录, 失败抛出异常
+func (mod *Model) MustExists(param QueryParam) bool {
+	has, err := mod.Exists(param)
+	if err != nil {
+		throwError(err)
+	}
+	return has
+}
+
+// Raw 执行原生SQL查询, 结果按模型字段定义解码 (如 JSON 字段), 不加载关联数据, 不应用查询范围和软删除过滤
+func (mod *Model) Raw(sql string, bindings ...interface{}) ([]maps.MapStr, error) {
+	start := time.Now()
+	qb := mod.newQuery().SQL(sql, bindings...)
+	rows, err := qb.Get()
+	slowQuery("Model Raw()", start, qb)
+	mod.stat(start, 1, len(rows), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []maps.MapStr{}
+	for _, row := range rows {
+		fmtRow := maps.MapStr{}
+		for key, value := range row {
+			fmtRow[key] = value
+		}
+		mod.FliterOut(fmtRow)
+		res = append(res, fmtRow)
+	}
+	return res, nil
+}
+
+// MustRaw 执行原生SQL查询, 失败抛出异常
+func (mod *Model) MustRaw(sql string, bindings ...interface{}) []maps.MapStr {
+	res, err := mod.Raw(sql, bindings...)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// Paginate 按条件查询, 分页 (每页记录数按 MaxPageSize 限制, 返回结果中的 pagesize 为实际每页记录数)
 func (mod *Model) Paginate(param QueryParam, page int, pagesize int) (maps.MapStr, error) {
 	param.Model = mod.Name
+	mod.bind(&param)
+	mod.defaultOrder(&param)
+	fields, err := mod.applyFields(&param)
+	if err != nil {
+		return nil, err
+	}
 	stack := NewQueryStack(param)
-	res := stack.Paginate(page, pagesize)
-	return res, nil
+	res := stack.Paginate(page, mod.pageSize(pagesize))
+	return fields.trimPaginate(res), nil
+}
+
+// PaginateCtx 按条件查询, 分页; 统计查询与数据查询之间检查 ctx, 已取消时返回 ctx.Err(), 不再执行后续查询
+// ctx 仅用于取消查询, 全局查询范围仍使用 WithContext 绑定的上下文
+// 设置 PaginateSnapshot 时, 未绑定事务的查询在同一事务中执行, 数据库支持时 (如 MySQL InnoDB 可重复读) 统计与数据读取同一快照
+func (mod *Model) PaginateCtx(ctx context.Context, param QueryParam, page int, pagesize int) (res maps.MapStr, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if PaginateSnapshot && mod.tx == nil {
+		err = transaction(func(tx *Tx) error {
+			res, err = mod.inTx(tx).PaginateCtx(ctx, param, page, pagesize)
+			return err
+		})
+		return res, err
+	}
+
+	param.Model = mod.Name
+	mod.bind(&param)
+	mod.defaultOrder(&param)
+	fields, err := mod.applyFields(&param)
+	if err != nil {
+		return nil, err
+	}
+	stack := NewQueryStack(param)
+	res, err = stack.PaginateCtx(ctx, page, mod.pageSize(pagesize))
+	if err != nil {
+		return nil, err
+	}
+	return fields.trimPaginate(res), nil
+}
+
+// pageSize 实际每页记录数
+func (mod *Model) pageSize(pagesize int) int {
+	if pagesize <= 0 {
+		pagesize = DefaultPageSize
+	}
+
+	max := MaxPageSize
+	if mod.MetaData.Option.MaxPageSize > 0 {
+		max = mod.MetaData.Option.MaxPageSize
+	}
+	if max > 0 && pagesize > max {
+		return max
+	}
+	return pagesize
+}
+
+// defaultOrder 未指定排序时使用模型默认排序 (default_order)
+func (mod *Model) defaultOrder(param *QueryParam) {
+	if len(param.Orders) > 0 {
+		return
+	}
+	orders, err := mod.defaultOrders()
+	if err == nil {
+		param.Orders = orders
+	}
+}
+
+// defaultOrders 解析模型默认排序, 格式为 "字段 [asc|desc]", 多个排序以 "," 分隔
+func (mod *Model) defaultOrders() ([]QueryOrder, error) {
+	orders := []QueryOrder{}
+	if strings.TrimSpace(mod.MetaData.DefaultOrder) == "" {
+		return orders, nil
+	}
+	for _, item := range strings.Split(mod.MetaData.DefaultOrder, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("模型 %s 默认排序 %s 格式错误", mod.Name, mod.MetaData.DefaultOrder)
+		}
+		if _, has := mod.Columns[fields[0]]; !has {
+			return nil, fmt.Errorf("模型 %s 默认排序字段 %s 不存在", mod.Name, fields[0])
+		}
+		order := QueryOrder{Column: fields[0]}
+		if len(fields) == 2 {
+			order.Option = strings.ToLower(fields[1])
+			if order.Option != "asc" && order.Option != "desc" {
+				return nil, fmt.Errorf("模型 %s 默认排序方式 %s 错误, 应为 asc 或 desc", mod.Name, fields[1])
+			}
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
 }
 
 // MustPaginate 按条件查询, 分页, 失败抛出异常
 func (mod *Model) MustPaginate(param QueryParam, page int, pagesize int) maps.MapStr {
 	res, err := mod.Paginate(param, page, pagesize)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
 	}
 	return res
 }
 
-// Create 创建单条数据, 返回新创建数据ID
+// Each 按条件分批读取数据, 每批数据调用一次 handler, handler 返回错误时终止遍历
+// 未指定排序或仅按主键排序时按主键游标分批读取 (见 Chunk), 指定其他排序时追加主键排序并按偏移量读取
+func (mod *Model) Each(param QueryParam, size int, handler func(rows []maps.MapStr) error) error {
+	if len(param.Orders) == 0 || (len(param.Orders) == 1 && param.Orders[0].Rel == "" && param.Orders[0].Column == mod.PrimaryKey) {
+		return mod.Chunk(param, size, handler)
+	}
+
+	if size <= 0 {
+		size = 100
+	}
+
+	param.Orders = append(append([]QueryOrder{}, param.Orders...), QueryOrder{Column: mod.PrimaryKey})
+	param.selectPrimaryKey(mod)
+	param.Limit = size
+	for {
+		rows, err := mod.Get(param)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		err = handler(rows)
+		if err != nil {
+			return err
+		}
+
+		if len(rows) < size {
+			return nil
+		}
+		param.offset = param.offset + size
+	}
+}
+
+// Chunk 按主键游标分批读取数据 (WHERE pk > 上批最后主键 LIMIT size), 不使用偏移量, 读取性能与遍历深度无关
+// 按主键升序读取; 排序仅支持主键 (desc 时为 WHERE pk < 上批最后主键), 指定其他排序时返回错误
+func (mod *Model) Chunk(param QueryParam, size int, handler func(rows []maps.MapStr) error) error {
+	if size <= 0 {
+		size = 100
+	}
+
+	op := "gt"
+	orders := []QueryOrder{{Column: mod.PrimaryKey}}
+	for _, order := range param.Orders {
+		if len(param.Orders) > 1 || order.Rel != "" || order.Column != mod.PrimaryKey {
+			return fmt.Errorf("Chunk 按主键 %s 顺序读取, 不支持按 %s 排序", mod.PrimaryKey, order.Column)
+		}
+		if strings.ToLower(order.Option) == "desc" {
+			op = "lt"
+			orders = []QueryOrder{order}
+		}
+	}
+	param.Orders = orders
+	param.selectPrimaryKey(mod)
+	param.Limit = size
+
+	wheres := param.Wheres
+	var last interface{}
+	for {
+		chunk := param
+		if last != nil {
+			chunk.Wheres = append(append([]QueryWhere{}, wheres...), QueryWhere{Column: mod.PrimaryKey, OP: op, Value: last})
+		}
+
+		rows, err := mod.Get(chunk)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		err = handler(rows)
+		if err != nil {
+			return err
+		}
+
+		if len(rows) < size {
+			return nil
+		}
+		last = rows[len(rows)-1].Get(mod.PrimaryKey)
+	}
+}
+
+// MustChunk 按主键游标分批读取数据, 失败抛出异常
+func (mod *Model) MustChunk(param QueryParam, size int, handler func(rows []maps.MapStr) error) {
+	err := mod.Chunk(param, size, handler)
+	if err != nil {
+		throwError(err)
+	}
+}
+
+// selectPrimaryKey 指定读取字段时追加主键 (分批读取需要主键)
+func (param *QueryParam) selectPrimaryKey(mod *Model) {
+	if len(param.Select) > 0 && !param.hasSelectColumn(mod.PrimaryKey) {
+		param.Select = append(append([]interface{}{}, param.Select...), mod.PrimaryKey)
+	}
+}
+
+// Create 创建单条数据, 返回新创建数据ID (非数字主键返回 0, 使用 CreateGetID 读取)
 func (mod *Model) Create(row maps.MapStrAny) (int, error) {
+	id, err := mod.CreateGetID(row)
+	if err != nil {
+		return 0, err
+	}
+	return intID(id), nil
+}
 
-	errs := mod.Validate(row) // 输入数据校验
+// CreateGetID 创建单条数据, 返回新创建数据主键 (自增主键为 int, 使用主键生成器时为生成的主键)
+func (mod *Model) CreateGetID(row maps.MapStrAny) (interface{}, error) {
+
+	if err := mod.readonly(); err != nil {
+		return nil, err
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		var id interface{}
+		err := transaction(func(tx *Tx) (err error) {
+			id, err = mod.inTx(tx).CreateGetID(row)
+			return err
+		})
+		return id, err
+	}
+
+	errs := append(mod.Validate(row), mod.ValidateRequired(row)...) // 输入数据校验
+	errs = append(errs, mod.ValidateUnique(row, nil)...)
 	if len(errs) > 0 {
 		exception.New("输入参数错误", 400).Ctx(errs).Throw()
 	}
 
 	mod.FliterIn(row) // 入库前输入数据预处理
+	if mod.MetaData.Option.SoftDeletes {
+		mod.softDelete().fliterIn(row) // 忽略删除字段
+	}
 
 	if mod.MetaData.Option.Timestamps {
 		row.Set("created_at", dbal.Raw("CURRENT_TIMESTAMP"))
 	}
 
-	id, err := capsule.Query().
-		Table(mod.MetaData.Table.Name).
-		InsertGetID(row)
-
+	start := time.Now()
+	id, err := mod.insertGetID(row)
 	if err != nil {
-		return 0, err
+		return nil, mod.conflict(err)
 	}
+	mod.stat(start, 1, 0, 1)
+
+	if err := mod.pathCreated(id, row); err != nil {
+		return nil, err
+	}
+	err = mod.changed(EventCreate, id, nil, row)
+	return id, err
+}
 
-	return int(id), err
+// MustCreateGetID 创建单条数据, 返回新创建数据主键, 失败抛出异常
+func (mod *Model) MustCreateGetID(row maps.MapStrAny) interface{} {
+	id, err := mod.CreateGetID(row)
+	if err != nil {
+		throwError(err)
+	}
+	return id
 }
 
 // MustCreate 创建单条数据, 返回新创建数据ID, 失败抛出异常
 func (mod *Model) MustCreate(row maps.MapStrAny) int {
 	id, err := mod.Create(row)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
 	}
 	return id
 }
 
+// CreateReturning 创建单条数据, 返回新创建的完整数据 (含数据库默认值和时间戳)
+// 主键由 InsertGetID 读取 (PostgreSQL 使用 RETURNING) 或由主键生成器生成, 完整数据按主键读取, 字段格式化与 Find 一致
+func (mod *Model) CreateReturning(row maps.MapStrAny) (maps.MapStr, error) {
+
+	if err := mod.readonly(); err != nil {
+		return nil, err
+	}
+
+	if mod.tx == nil { // 写入与读取共用事务
+		var res maps.MapStr
+		err := transaction(func(tx *Tx) (err error) {
+			res, err = mod.inTx(tx).CreateReturning(row)
+			return err
+		})
+		return res, err
+	}
+
+	id, err := mod.CreateGetID(row)
+	if err != nil {
+		return nil, err
+	}
+	return mod.Find(id, QueryParam{})
+}
+
+// MustCreateReturning 创建单条数据, 返回新创建的完整数据, 失败抛出异常
+func (mod *Model) MustCreateReturning(row maps.MapStrAny) maps.MapStr {
+	res, err := mod.CreateReturning(row)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
 // Update 更新单条数据
 func (mod *Model) Update(id interface{}, row maps.MapStrAny) error {
+	if errs := mod.ValidateUnique(row, id); len(errs) > 0 { // 范围唯一约束
+		exception.New("输入参数错误", 400).Ctx(errs).Throw()
+	}
+
+	if mod.pathMaintained(row) { // 修改上级时更新树形路径
+		return mod.pathUpdate(id, row)
+	}
+
+	effect, err := mod.UpdateWhere(QueryParam{
+		Wheres: []QueryWhere{
+			{
+				Column: mod.PrimaryKey,
+				Value:  id,
+			},
+		},
+		Limit: 1,
+	}, row)
 
+	if err != nil {
+		return err
+	}
+
+	if effect == 0 {
+		return &ErrNotFound{Model: mod.Name, ID: id}
+	}
+	return nil
+}
+
+// Touch 更新单条数据的更新时间 (updated_at), 模型未开启时间戳时返回错误
+func (mod *Model) Touch(id interface{}) error {
+	where := QueryWhere{Column: mod.PrimaryKey, Value: id}
+	effect, err := mod.TouchWhere(QueryParam{Wheres: []QueryWhere{where}, Limit: 1})
+	if err != nil {
+		return err
+	}
+
+	if effect == 0 { // 更新时间未变化时 MySQL 返回 0 行, 检查数据是否存在
+		exists, err := mod.Exists(QueryParam{Wheres: []QueryWhere{where}})
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return &ErrNotFound{Model: mod.Name, ID: id}
+		}
+	}
+	return nil
+}
+
+// MustTouch 更新单条数据的更新时间, 失败抛出异常
+func (mod *Model) MustTouch(id interface{}) {
+	err := mod.Touch(id)
+	if err != nil {
+		throwError(err)
+	}
+}
+
+// TouchWhere 按条件更新数据的更新时间 (updated_at), 返回更新行数, 模型未开启时间戳时返回错误
+func (mod *Model) TouchWhere(param QueryParam) (int, error) {
+	if !mod.MetaData.Option.Timestamps {
+		return 0, fmt.Errorf("模型 %s 未开启时间戳 (timestamps), 不支持更新时间", mod.Name)
+	}
+	return mod.UpdateWhere(param, maps.MapStrAny{})
+}
+
+// MustTouchWhere 按条件更新数据的更新时间, 返回更新行数, 失败抛出异常
+func (mod *Model) MustTouchWhere(param QueryParam) int {
+	effect, err := mod.TouchWhere(param)
+	if err != nil {
+		throwError(err)
+	}
+	return effect
+}
+
+// MustUpdate 更新单条数据, 失败抛出异常
+func (mod *Model) MustUpdate(id interface{}, row maps.MapStrAny) {
+	err := mod.Update(id, row)
+	if err != nil {
+		throwError(err)
+	}
+}
+
+// Increment 单条数据字段自增 (SET column = column + amount), extra 为同时更新的其他字段
+func (mod *Model) Increment(id interface{}, column string, amount interface{}, extra ...maps.MapStrAny) error {
+	return mod.increment(id, column, "+", amount, extra...)
+}
+
+// MustIncrement 单条数据字段自增, 失败抛出异常
+func (mod *Model) MustIncrement(id interface{}, column string, amount interface{}, extra ...maps.MapStrAny) {
+	err := mod.Increment(id, column, amount, extra...)
+	if err != nil {
+		throwError(err)
+	}
+}
+
+// Decrement 单条数据字段自减 (SET column = column - amount), extra 为同时更新的其他字段
+func (mod *Model) Decrement(id interface{}, column string, amount interface{}, extra ...maps.MapStrAny) error {
+	return mod.increment(id, column, "-", amount, extra...)
+}
+
+// MustDecrement 单条数据字段自减, 失败抛出异常
+func (mod *Model) MustDecrement(id interface{}, column string, amount interface{}, extra ...maps.MapStrAny) {
+	err := mod.Decrement(id, column, amount, extra...)
+	if err != nil {
+		throwError(err)
+	}
+}
+
+// increment 在单条 UPDATE 语句中原子更新字段数值
+func (mod *Model) increment(id interface{}, column string, op string, amount interface{}, extra ...maps.MapStrAny) error {
+
+	if err := mod.readonly(); err != nil {
+		return err
+	}
+
+	if _, has := mod.Columns[column]; !has {
+		return fmt.Errorf("字段 %s 不存在", column)
+	}
+
+	if !any.Of(amount).IsNumber() {
+		return fmt.Errorf("%v 不是有效的数值", amount)
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		return transaction(func(tx *Tx) error {
+			return mod.inTx(tx).increment(id, column, op, amount, extra...)
+		})
+	}
+
+	row := maps.MapStrAny{}
+	if len(extra) > 0 {
+		for name, value := range extra[0] {
+			row[name] = value
+		}
+		errs := mod.Validate(row) // 输入数据校验
+		if len(errs) > 0 {
+			exception.New("输入参数错误", 400).Ctx(errs).Throw()
+		}
+		mod.FliterIn(row) // 入库前输入数据预处理
+	}
+
+	value := strconv.FormatFloat(any.Of(amount).CFloat(), 'f', -1, 64)
+	row[column] = dbal.Raw(fmt.Sprintf("%s %s %s", mod.quote(column), op, value))
+	if mod.MetaData.Option.Timestamps {
+		row.Set("updated_at", dbal.Raw("CURRENT_TIMESTAMP"))
+	}
+
+	effect, err := mod.updateWhere(QueryParam{
+		Wheres: []QueryWhere{
+			{
+				Column: mod.PrimaryKey,
+				Value:  id,
+			},
+		},
+		Limit: 1,
+	}, row)
+
+	if err != nil {
+		return err
+	}
+
+	if effect == 0 {
+		return &ErrNotFound{Model: mod.Name, ID: id}
+	}
+	return nil
+}
+
+// Save 保存单条数据, 不存在创建记录, 存在更新记录,  返回数据ID
+func (mod *Model) Save(row maps.MapStrAny) (int, error) {
+	id, err := mod.save(row)
+	return id, throwInvalid(err)
+}
+
+// save 保存单条数据, 校验失败返回 ErrValidation, 违反范围唯一约束返回 ErrConflict
+func (mod *Model) save(row maps.MapStrAny) (int, error) {
+
+	if err := mod.readonly(); err != nil {
+		return 0, err
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		var id int
+		err := transaction(func(tx *Tx) (err error) {
+			id, err = mod.inTx(tx).save(row)
+			return err
+		})
+		return id, err
+	}
+
+	row = copyRow(row)        // 不修改调用方数据
 	errs := mod.Validate(row) // 输入数据校验
+	if !row.Has(mod.PrimaryKey) {
+		errs = append(errs, mod.ValidateRequired(row)...) // 创建数据必填字段
+	}
 	if len(errs) > 0 {
-		exception.New("输入参数错误", 400).Ctx(errs).Throw()
+		return 0, &ErrValidation{Model: mod.Name, Responses: errs}
+	}
+	if errs := mod.ValidateUnique(row, row.Get(mod.PrimaryKey)); len(errs) > 0 {
+		return 0, &ErrConflict{Model: mod.Name, Responses: errs}
+	}
+
+	if row.Has(mod.PrimaryKey) && mod.pathMaintained(row) { // 修改上级时更新树形路径
+		id := row.Get(mod.PrimaryKey)
+		return intID(id), mod.pathUpdate(id, row)
 	}
 
 	mod.FliterIn(row) // 入库前输入数据预处理
+	if mod.MetaData.Option.SoftDeletes {
+		mod.softDelete().fliterIn(row) // 忽略删除字段
+	}
 
+	// 更新
+	if row.Has(mod.PrimaryKey) {
+
+		if mod.MetaData.Option.Timestamps {
+			row.Set("updated_at", dbal.Raw("CURRENT_TIMESTAMP"))
+			row.Del("created_at") // 忽略创建字段
+		}
+
+		id := row.Get(mod.PrimaryKey)
+		row.Del(mod.PrimaryKey) // 主键仅作为更新条件
+		_, err := mod.updateWhere(QueryParam{
+			Wheres: []QueryWhere{
+				{
+					Column: mod.PrimaryKey,
+					Value:  id,
+				},
+			},
+			Limit: 1,
+		}, row)
+
+		if err != nil {
+			return 0, err
+		}
+		return intID(id), nil
+	}
+
+	// 创建
 	if mod.MetaData.Option.Timestamps {
-		row.Set("updated_at", dbal.Raw("CURRENT_TIMESTAMP"))
+		row.Set("created_at", dbal.Raw("CURRENT_TIMESTAMP"))
+		row.Del("updated_at") // 忽略更新字段
 	}
 
-	effect, err := capsule.Query().
-		Table(mod.MetaData.Table.Name).
-		Where(mod.PrimaryKey, id).
-		Limit(1).
-		Update(row)
+	start := time.Now()
+	id, err := mod.insertGetID(row)
+	if err != nil {
+		return 0, mod.conflict(err)
+	}
+	mod.stat(start, 1, 0, 1)
 
-	if effect == 0 {
-		return fmt.Errorf("没有数据被更新")
+	if err := mod.pathCreated(id, row); err != nil {
+		return 0, err
 	}
+	err = mod.changed(EventCreate, id, nil, row)
+	return intID(id), err
+}
 
-	return err
+// MustSave 保存单条数据, 返回数据ID, 失败抛出异常
+func (mod *Model) MustSave(row maps.MapStrAny) int {
+	id, err := mod.Save(row)
+	if err != nil {
+		throwError(err)
+	}
+	return id
+}
+
+// FirstOrCreate 读取与 match 匹配的第一条数据, 不存在则合并 match 和 defaults 创建数据, 返回数据及是否新创建
+// 读取与创建在同一事务中执行, match 字段为唯一索引时, 并发创建产生唯一键冲突后重新读取已创建的数据
+func (mod *Model) FirstOrCreate(match maps.MapStr, defaults maps.MapStr) (maps.MapStr, bool, error) {
+	return mod.matchOrCreate(match, defaults, func(mod *Model, row maps.MapStr) (maps.MapStr, error) {
+		return row, nil
+	})
+}
+
+// MustFirstOrCreate 读取与 match 匹配的第一条数据, 不存在则创建, 失败抛出异常
+func (mod *Model) MustFirstOrCreate(match maps.MapStr, defaults maps.MapStr) (maps.MapStr, bool) {
+	res, created, err := mod.FirstOrCreate(match, defaults)
+	if err != nil {
+		throwError(err)
+	}
+	return res, created
+}
+
+// UpdateOrCreate 使用 values 更新与 match 匹配的第一条数据, 不存在则合并 match 和 values 创建数据, 返回数据及是否新创建
+// 读取与写入在同一事务中执行, match 字段为唯一索引时, 并发创建产生唯一键冲突后重试更新
+func (mod *Model) UpdateOrCreate(match maps.MapStr, values maps.MapStr) (maps.MapStr, bool, error) {
+	return mod.matchOrCreate(match, values, func(mod *Model, row maps.MapStr) (maps.MapStr, error) {
+		id := row.Get(mod.PrimaryKey)
+		data := maps.MapStrAny{}
+		for name, value := range values {
+			data[name] = value
+		}
+		if len(data) > 0 {
+			err := mod.Update(id, data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return mod.Find(id, QueryParam{})
+	})
 }
 
-// MustUpdate 更新单条数据, 失败抛出异常
-func (mod *Model) MustUpdate(id interface{}, row maps.MapStrAny) {
-	err := mod.Update(id, row)
+// MustUpdateOrCreate 更新与 match 匹配的第一条数据, 不存在则创建, 失败抛出异常
+func (mod *Model) MustUpdateOrCreate(match maps.MapStr, values maps.MapStr) (maps.MapStr, bool) {
+	res, created, err := mod.UpdateOrCreate(match, values)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
 	}
+	return res, created
 }
 
-// Save 保存单条数据, 不存在创建记录, 存在更新记录,  返回数据ID
-func (mod *Model) Save(row maps.MapStrAny) (int, error) {
+// matchOrCreate 在事务中读取与 match 匹配的第一条数据 (加行锁), 存在时调用 found, 不存在时合并 match 和 values 创建数据
+func (mod *Model) matchOrCreate(match maps.MapStr, values maps.MapStr, found func(mod *Model, row maps.MapStr) (maps.MapStr, error)) (maps.MapStr, bool, error) {
 
-	errs := mod.Validate(row) // 输入数据校验
-	if len(errs) > 0 {
-		exception.New("输入参数错误", 400).Ctx(errs).Throw()
+	if err := mod.readonly(); err != nil {
+		return nil, false, err
 	}
 
-	mod.FliterIn(row) // 入库前输入数据预处理
-
-	// 更新
-	if row.Has(mod.PrimaryKey) {
+	if len(match) == 0 {
+		return nil, false, fmt.Errorf("匹配条件不能为空")
+	}
 
-		if mod.MetaData.Option.Timestamps {
-			row.Set("updated_at", dbal.Raw("CURRENT_TIMESTAMP"))
-			row.Del("deleted_at") // 忽略删除字段
-			row.Del("created_at") // 忽略创建字段
+	names := []string{}
+	for name := range match {
+		if _, has := mod.Columns[name]; !has {
+			return nil, false, fmt.Errorf("字段 %s 不存在", name)
 		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-		id := row.Get(mod.PrimaryKey)
-		_, err := capsule.Query().
-			Table(mod.MetaData.Table.Name).
-			Where(mod.PrimaryKey, id).
-			Limit(1).
-			Update(row)
+	wheres := []QueryWhere{}
+	for _, name := range names {
+		wheres = append(wheres, QueryWhere{Column: name, Value: match[name]})
+	}
+
+	run := func(mod *Model) (maps.MapStr, bool, error) {
+		param := QueryParam{Wheres: wheres, Limit: 1}
+		if mod.Driver != "sqlite3" { // SQLite 写事务串行执行, 不支持 SELECT ... FOR UPDATE
+			param.Lock = "update"
+		}
 
+		rows, err := mod.Get(param)
 		if err != nil {
-			return 0, err
+			return nil, false, err
 		}
 
-		return any.Of(id).CInt(), nil
-	}
+		if len(rows) > 0 {
+			res, err := found(mod, rows[0])
+			return res, false, err
+		}
 
-	// 创建
-	if mod.MetaData.Option.Timestamps {
-		row.Set("created_at", dbal.Raw("CURRENT_TIMESTAMP"))
-		row.Del("deleted_at") // 忽略删除字段
-		row.Del("updated_at") // 忽略更新字段
+		row := maps.MapStrAny{}
+		for name, value := range values {
+			row[name] = value
+		}
+		for name, value := range match {
+			row[name] = value
+		}
+		res, err := mod.CreateReturning(row)
+		return res, err == nil, err
 	}
 
-	id, err := capsule.Query().
-		Table(mod.MetaData.Table.Name).
-		InsertGetID(row)
+	if mod.tx != nil { // 已在调用方事务中, 冲突后事务不可继续使用, 不重试
+		return run(mod)
+	}
 
-	if err != nil {
-		return 0, err
+	var res maps.MapStr
+	var created bool
+	transaction := func() error {
+		return transaction(func(tx *Tx) (err error) {
+			res, created, err = run(mod.inTx(tx))
+			return err
+		})
 	}
 
-	return int(id), err
+	err := transaction()
+	if err != nil && mod.uniqueMatch(names) { // 唯一键冲突: 数据已由其他请求创建, 重新读取
+		err = transaction()
+	}
+	return res, created, err
 }
 
-// MustSave 保存单条数据, 返回数据ID, 失败抛出异常
-func (mod *Model) MustSave(row maps.MapStrAny) int {
-	id, err := mod.Save(row)
-	if err != nil {
-		exception.Err(err, 500).Throw()
+// uniqueMatch 检查匹配字段是否包含唯一字段或完整的唯一索引
+func (mod *Model) uniqueMatch(names []string) bool {
+	has := map[string]bool{}
+	for _, name := range names {
+		has[name] = true
+		if mod.Columns[name].Unique {
+			return true
+		}
 	}
-	return id
+
+	for _, index := range mod.MetaData.Indexes {
+		if strings.ToLower(index.Type) != "unique" || len(index.Columns) == 0 {
+			continue
+		}
+		covered := true
+		for _, name := range index.Columns {
+			if !has[name] {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
 }
 
 // Delete 删除单条记录
 func (mod *Model) Delete(id interface{}) error {
+
+	if err := mod.readonly(); err != nil {
+		return err
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		return transaction(func(tx *Tx) error {
+			return mod.inTx(tx).Delete(id)
+		})
+	}
+
 	_, err := mod.DeleteWhere(QueryParam{
 		Wheres: []QueryWhere{
 			{
@@ -221,13 +1103,21 @@ func (mod *Model) Delete(id interface{}) error {
 func (mod *Model) MustDelete(id interface{}) {
 	err := mod.Delete(id)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
 	}
 }
 
 // Destroy 真删除单条记录
 func (mod *Model) Destroy(id interface{}) error {
-	_, err := capsule.Query().Table(mod.MetaData.Table.Name).Where("id", id).Limit(1).Delete()
+	_, err := mod.DestroyWhere(QueryParam{
+		Wheres: []QueryWhere{
+			{
+				Column: mod.PrimaryKey,
+				Value:  id,
+			},
+		},
+		Limit: 1,
+	})
 	return err
 }
 
@@ -235,13 +1125,23 @@ func (mod *Model) Destroy(id interface{}) error {
 func (mod *Model) MustDestroy(id interface{}) {
 	err := mod.Destroy(id)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
 	}
 }
 
 // Insert 插入多条数据
 func (mod *Model) Insert(columns []string, rows [][]interface{}) error {
 
+	if err := mod.readonly(); err != nil {
+		return err
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		return transaction(func(tx *Tx) error {
+			return mod.inTx(tx).Insert(columns, rows)
+		})
+	}
+
 	// 数据校验
 	errs := []ValidateResponse{}
 	columnCnt := len(columns)
@@ -260,7 +1160,7 @@ func (mod *Model) Insert(columns []string, rows [][]interface{}) error {
 			row[name] = values[cid]
 		}
 
-		rowerrs := mod.Validate(row) // 输入数据校验
+		rowerrs := append(mod.Validate(row), mod.ValidateRequired(row)...) // 输入数据校验
 		if len(rowerrs) > 0 {
 			for _, err := range rowerrs {
 				err.Line = rid
@@ -281,6 +1181,12 @@ func (mod *Model) Insert(columns []string, rows [][]interface{}) error {
 		exception.New("输入参数错误", 400).Ctx(errs).Throw()
 	}
 
+	// 使用主键生成器时填充主键
+	columns, err := mod.generateIDs(columns, rows)
+	if err != nil {
+		return err
+	}
+
 	// 添加创建时间戳
 	if mod.MetaData.Option.Timestamps {
 		columns = append(columns, "created_at")
@@ -289,24 +1195,72 @@ func (mod *Model) Insert(columns []string, rows [][]interface{}) error {
 		}
 	}
 
+	// 按记录发布事件 (BulkEventRows)、开启审计或维护树形路径时逐条写入, 记录数据ID
+	if (mod.observed() && mod.bulkRows()) || mod.MetaData.Option.Path != "" {
+		for _, values := range rows {
+			row := maps.MapStr{}
+			for i, name := range columns {
+				row[name] = values[i]
+			}
+			start := time.Now()
+			id, err := mod.insertGetID(row)
+			if err != nil {
+				return mod.conflict(err)
+			}
+			mod.stat(start, 1, 0, 1)
+			if err := mod.pathCreated(id, row); err != nil {
+				return err
+			}
+			err = mod.changed(EventCreate, id, nil, row)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	// 写入到数据库
-	return capsule.Query().
-		Table(mod.MetaData.Table.Name).
+	start := time.Now()
+	err = mod.newQuery().
+		Table(mod.TableName()).
 		Insert(rows, columns)
-
+	if err != nil {
+		return mod.conflict(err)
+	}
+	mod.stat(start, 1, 0, len(rows))
+	mod.publish(EventCreate, []interface{}{}, nil, nil)
+	return nil
 }
 
 // MustInsert 插入多条数据, 失败抛出异常
 func (mod *Model) MustInsert(columns []string, rows [][]interface{}) {
 	err := mod.Insert(columns, rows)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
 	}
 }
 
 // UpdateWhere 按条件更新记录, 返回更新行数
 func (mod *Model) UpdateWhere(param QueryParam, row maps.MapStrAny) (int, error) {
 
+	if err := mod.readonly(); err != nil {
+		return 0, err
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		var effect int
+		err := transaction(func(tx *Tx) (err error) {
+			effect, err = mod.inTx(tx).UpdateWhere(param, row)
+			return err
+		})
+		return effect, err
+	}
+
+	if mod.pathMaintained(row) {
+		return 0, fmt.Errorf("模型 %s 维护树形路径, 修改上级请使用 Update 或 Move", mod.Name)
+	}
+
+	row = copyRow(row)        // 不修改调用方数据
 	errs := mod.Validate(row) // 输入数据校验
 	if len(errs) > 0 {
 		exception.New("输入参数错误", 400).Ctx(errs).Throw()
@@ -318,25 +1272,44 @@ func (mod *Model) UpdateWhere(param QueryParam, row maps.MapStrAny) (int, error)
 		row.Set("updated_at", dbal.Raw("CURRENT_TIMESTAMP"))
 	}
 
-	// 如果不是 SQLite3 添加字段
-	if mod.Driver != "sqlite3" {
-		for name, value := range row {
-			if !strings.Contains(name, ".") {
-				new := fmt.Sprintf("%s.%s", mod.MetaData.Table.Name, name)
-				row.Set(new, value)
-				row.Del(name)
-			}
+	return mod.updateWhere(param, row)
+}
+
+// updateWhere 按条件更新记录 (数据已校验和预处理), 应用查询范围并发布变更事件
+func (mod *Model) updateWhere(param QueryParam, row maps.MapStrAny) (int, error) {
+
+	if len(row) == 0 {
+		return 0, fmt.Errorf("没有需要更新的字段")
+	}
+
+	after := eventRow(row)
+
+	// SET 仅包含输入数据中的字段, 未提供的字段保持原值 (如果不是 SQLite3 添加数据表名称)
+	set := maps.MapStrAny{}
+	for name, value := range row {
+		if mod.Driver != "sqlite3" && !strings.Contains(name, ".") {
+			name = fmt.Sprintf("%s.%s", mod.MetaData.Table.Name, name)
 		}
+		set[name] = value
 	}
 
 	param.Model = mod.Name
+	mod.bind(&param)
+	before, err := mod.eventRows(param)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
 	stack := NewQueryStack(param)
 	qb := stack.FirstQuery()
-	effect, err := qb.Update(row)
+	effect, err := qb.Update(set)
 	if err != nil {
-		return 0, err
+		return 0, mod.conflict(err)
 	}
+	mod.stat(start, 1, 0, int(effect))
 
+	err = mod.changedRows(EventUpdate, before, after)
 	return int(effect), err
 }
 
@@ -344,14 +1317,154 @@ func (mod *Model) UpdateWhere(param QueryParam, row maps.MapStrAny) (int, error)
 func (mod *Model) MustUpdateWhere(param QueryParam, row maps.MapStrAny) int {
 	effect, err := mod.UpdateWhere(param, row)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
+	}
+	return effect
+}
+
+// UpdateMany 按 keyColumn 字段值批量更新多条记录 (每条记录的更新数据不同), 返回更新行数
+// 在同一事务中执行, 更新数据相同的记录合并为一条 UPDATE ... WHERE key IN (...) 语句; 校验、查询范围和变更事件与 UpdateWhere 一致
+func (mod *Model) UpdateMany(keyColumn string, updates map[interface{}]maps.MapStr) (int, error) {
+
+	if err := mod.readonly(); err != nil {
+		return 0, err
+	}
+
+	if _, has := mod.Columns[keyColumn]; !has {
+		return 0, fmt.Errorf("字段 %s 不存在", keyColumn)
+	}
+
+	if mod.tx == nil {
+		var effect int
+		err := transaction(func(tx *Tx) (err error) {
+			effect, err = mod.inTx(tx).UpdateMany(keyColumn, updates)
+			return err
+		})
+		return effect, err
+	}
+
+	// 按更新数据分组
+	groups := map[string][]interface{}{}
+	rows := map[string]maps.MapStr{}
+	for key, row := range updates {
+		hash, err := jsoniter.MarshalToString(row)
+		if err != nil {
+			hash = fmt.Sprintf("%v", key)
+		}
+		groups[hash] = append(groups[hash], key)
+		rows[hash] = row
+	}
+
+	hashes := []string{}
+	for hash := range groups {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes) // 固定更新顺序, 减少并发死锁
+
+	total := 0
+	for _, hash := range hashes {
+		keys := groups[hash]
+		where := QueryWhere{Column: keyColumn, Value: keys[0]}
+		if len(keys) > 1 {
+			where = QueryWhere{Column: keyColumn, OP: "in", Value: keys}
+		}
+		effect, err := mod.UpdateWhere(QueryParam{Wheres: []QueryWhere{where}}, rows[hash])
+		if err != nil {
+			return total, err
+		}
+		total += effect
+	}
+	return total, nil
+}
+
+// MustUpdateMany 按 keyColumn 字段值批量更新多条记录, 返回更新行数, 失败抛出异常
+func (mod *Model) MustUpdateMany(keyColumn string, updates map[interface{}]maps.MapStr) int {
+	effect, err := mod.UpdateMany(keyColumn, updates)
+	if err != nil {
+		throwError(err)
 	}
 	return effect
 }
 
+// UpdateReturning 按条件更新记录, 返回更新后的数据 (字段格式化与 Find 一致)
+// 在事务中锁定并读取符合条件的主键, 按主键更新后读取更新后的数据; SQLite 写事务串行执行, 不加行锁
+func (mod *Model) UpdateReturning(param QueryParam, row maps.MapStrAny) ([]maps.MapStr, error) {
+
+	if err := mod.readonly(); err != nil {
+		return nil, err
+	}
+
+	if mod.tx == nil { // 读取与更新共用事务
+		var res []maps.MapStr
+		err := transaction(func(tx *Tx) (err error) {
+			res, err = mod.inTx(tx).UpdateReturning(param, row)
+			return err
+		})
+		return res, err
+	}
+
+	if mod.Driver != "sqlite3" {
+		param.Lock = "update"
+	}
+
+	// 符合条件的主键 (未指定 Limit 时分批读取全部)
+	ids := []interface{}{}
+	var err error
+	if param.Limit > 0 {
+		ids, err = mod.Pluck(mod.PrimaryKey, param)
+	} else {
+		param.Select = []interface{}{mod.PrimaryKey}
+		param.Withs = nil
+		param.WithCount = nil
+		err = mod.Each(param, 500, func(rows []maps.MapStr) error {
+			for _, row := range rows {
+				ids = append(ids, row.Get(mod.PrimaryKey))
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []maps.MapStr{}, nil
+	}
+
+	_, err = mod.UpdateWhere(QueryParam{
+		Wheres: []QueryWhere{{Column: mod.PrimaryKey, OP: "in", Value: ids}},
+		Limit:  len(ids),
+	}, row)
+	if err != nil {
+		return nil, err
+	}
+	return mod.FindMany(ids, QueryParam{})
+}
+
+// MustUpdateReturning 按条件更新记录, 返回更新后的数据, 失败抛出异常
+func (mod *Model) MustUpdateReturning(param QueryParam, row maps.MapStrAny) []maps.MapStr {
+	res, err := mod.UpdateReturning(param, row)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
 // DeleteWhere 批量删除数据, 返回更新行数
 func (mod *Model) DeleteWhere(param QueryParam) (int, error) {
 
+	if err := mod.readonly(); err != nil {
+		return 0, err
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		var effect int
+		err := transaction(func(tx *Tx) (err error) {
+			effect, err = mod.inTx(tx).DeleteWhere(param)
+			return err
+		})
+		return effect, err
+	}
+
 	// 软删除
 	if mod.MetaData.Option.SoftDeletes {
 
@@ -368,12 +1481,12 @@ func (mod *Model) DeleteWhere(param QueryParam) (int, error) {
 				data[col.Name] = dbal.Raw(fmt.Sprintf("CONCAT_WS('_', '%d')", time.Now().UnixNano()))
 				columns = append(
 					columns,
-					fmt.Sprintf("CONCAT('\"%s\":\"', `%s`, '\"')", col.Name, col.Name),
+					fmt.Sprintf("CONCAT('\"%s\":\"', %s, '\"')", col.Name, mod.quote(col.Name)),
 				)
 			} else { // 数字, 布尔型等
 				columns = append(
 					columns,
-					fmt.Sprintf("CONCAT('\"%s\":', `%s`)", col.Name, col.Name),
+					fmt.Sprintf("CONCAT('\"%s\":', %s)", col.Name, mod.quote(col.Name)),
 				)
 			}
 			if col.Nullable {
@@ -382,6 +1495,13 @@ func (mod *Model) DeleteWhere(param QueryParam) (int, error) {
 		}
 
 		param.Model = mod.Name
+		mod.bind(&param)
+		before, err := mod.eventRows(param)
+		if err != nil {
+			return 0, err
+		}
+
+		start, queries := time.Now(), 1
 		stack := NewQueryStack(param)
 		qb := stack.FirstQuery()
 
@@ -392,53 +1512,114 @@ func (mod *Model) DeleteWhere(param QueryParam) (int, error) {
 			if err != nil {
 				return 0, err
 			}
+			queries++
 		}
 
 		// 删除数据
-		field := fmt.Sprintf("%s.%s", mod.MetaData.Table.Name, "deleted_at")
-		// data["deleted_at"] = dbal.Raw("CURRENT_TIMESTAMP")
-		data[field] = dbal.Raw("CURRENT_TIMESTAMP")
+		sd := mod.softDelete()
+		field := fmt.Sprintf("%s.%s", mod.MetaData.Table.Name, sd.Column)
+		data[field] = sd.deleted()
 		effect, err := qb.Update(data)
 		if err != nil {
 			return 0, err
 		}
-		return int(effect), nil
+		mod.stat(start, queries, 0, int(effect))
+		return int(effect), mod.changedRows(EventDelete, before, nil)
 	}
 
-	return mod.DestroyWhere(param)
+	return mod.destroyWhere(param, EventDelete)
 }
 
-// sqliteDeleteWhere SQLite
+// sqlite3DeleteWhere SQLite 软删除 (SQLite 不支持 CONCAT 及 UPDATE 字段表名前缀, 唯一数据使用 json_object 备份)
 func (mod *Model) sqlite3DeleteWhere(param QueryParam) (int, error) {
 	data := maps.MapStrAny{}
+	pairs := []string{}
+	for _, col := range mod.UniqueColumns {
+		pairs = append(pairs, fmt.Sprintf("'%s', %s", col.Name, mod.quote(col.Name)))
+		if strings.ToLower(col.Type) == "string" {
+			data[col.Name] = fmt.Sprintf("%d", time.Now().UnixNano())
+		}
+		if col.Nullable {
+			data[col.Name] = nil
+		}
+	}
+
 	param.Model = mod.Name
+	mod.bind(&param)
+	before, err := mod.eventRows(param)
+	if err != nil {
+		return 0, err
+	}
+
+	start, queries := time.Now(), 1
 	stack := NewQueryStack(param)
 	qb := stack.FirstQuery()
 
+	// 备份唯一数据
+	if len(pairs) > 0 {
+		restore := dbal.Raw("json_object(" + strings.Join(pairs, ", ") + ")")
+		_, err := qb.Update(maps.MapStr{"__restore_data": restore})
+		if err != nil {
+			return 0, err
+		}
+		queries++
+	}
+
 	// 删除数据
-	// field := fmt.Sprintf("%s.%s", mod.MetaData.Table.Name, "deleted_at")
-	data["deleted_at"] = dbal.Raw("CURRENT_TIMESTAMP")
-	// data[field] = dbal.Raw("CURRENT_TIMESTAMP")
+	sd := mod.softDelete()
+	data[sd.Column] = sd.deleted()
 	effect, err := qb.Update(data)
 	if err != nil {
 		return 0, err
 	}
-	return int(effect), nil
+	mod.stat(start, queries, 0, int(effect))
+	return int(effect), mod.changedRows(EventDelete, before, nil)
 }
 
 // MustDeleteWhere 批量删除数据, 返回更新行数, 失败抛出异常
 func (mod *Model) MustDeleteWhere(param QueryParam) int {
 	effect, err := mod.DeleteWhere(param)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
 	}
 	return effect
 }
 
 // DestroyWhere 批量真删除数据, 返回更新行数
 func (mod *Model) DestroyWhere(param QueryParam) (int, error) {
+
+	if err := mod.readonly(); err != nil {
+		return 0, err
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		var effect int
+		err := transaction(func(tx *Tx) (err error) {
+			effect, err = mod.inTx(tx).DestroyWhere(param)
+			return err
+		})
+		return effect, err
+	}
+
+	return mod.destroyWhere(param, EventDestroy)
+}
+
+// destroyWhere 批量真删除数据, 按 op 发布变更事件
+func (mod *Model) destroyWhere(param QueryParam, op string) (int, error) {
 	param.Model = mod.Name
-	qb := capsule.Query().Table(mod.MetaData.Table.Name)
+	mod.bind(&param)
+
+	trashed := param
+	trashed.WithTrashed = true
+	before, err := mod.eventRows(trashed)
+	if err != nil {
+		return 0, err
+	}
+
+	mod.applyScopes(&param)
+	mod.applyGlobalScopes(&param)
+	start := time.Now()
+	qb := mod.newQuery().Table(mod.TableName())
 	for _, where := range param.Wheres {
 		param.Where(where, qb, mod)
 	}
@@ -446,34 +1627,134 @@ func (mod *Model) DestroyWhere(param QueryParam) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(effect), nil
+	mod.stat(start, 1, 0, int(effect))
+	return int(effect), mod.changedRows(op, before, nil)
 }
 
 // MustDestroyWhere 批量真删除数据, 返回更新行数, 失败抛出异常
 func (mod *Model) MustDestroyWhere(param QueryParam) int {
 	effect, err := mod.DestroyWhere(param)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
+	}
+	return effect
+}
+
+// DeleteMany 按主键批量删除数据 (软删除模型为软删除), 返回删除行数; ids 为空时不删除任何数据
+func (mod *Model) DeleteMany(ids []interface{}) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return mod.DeleteWhere(QueryParam{Wheres: []QueryWhere{{Column: mod.PrimaryKey, OP: "in", Value: ids}}})
+}
+
+// MustDeleteMany 按主键批量删除数据, 返回删除行数, 失败抛出异常
+func (mod *Model) MustDeleteMany(ids []interface{}) int {
+	effect, err := mod.DeleteMany(ids)
+	if err != nil {
+		throwError(err)
+	}
+	return effect
+}
+
+// DestroyMany 按主键批量真删除数据, 返回删除行数; ids 为空时不删除任何数据
+func (mod *Model) DestroyMany(ids []interface{}) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return mod.DestroyWhere(QueryParam{Wheres: []QueryWhere{{Column: mod.PrimaryKey, OP: "in", Value: ids}}})
+}
+
+// MustDestroyMany 按主键批量真删除数据, 返回删除行数, 失败抛出异常
+func (mod *Model) MustDestroyMany(ids []interface{}) int {
+	effect, err := mod.DestroyMany(ids)
+	if err != nil {
+		throwError(err)
+	}
+	return effect
+}
+
+// DeleteAll 删除数据表中的全部数据 (DELETE, 不应用查询范围, 不发布变更事件), 返回删除行数; confirm 须为 true, 防止误删
+// 用于测试数据清理和开发环境重置, 自增主键不重置 (重置使用 Truncate)
+func (mod *Model) DeleteAll(confirm bool) (int, error) {
+	if !confirm {
+		return 0, fmt.Errorf("DeleteAll 将删除 %s 的全部数据, 需要确认 (confirm = true)", mod.Name)
+	}
+	if err := mod.readonly(); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	effect, err := mod.newQuery().Table(mod.TableName()).Delete()
+	if err != nil {
+		return 0, err
+	}
+	mod.stat(start, 1, 0, int(effect))
+	return int(effect), nil
+}
+
+// MustDeleteAll 删除数据表中的全部数据, 返回删除行数, 失败抛出异常
+func (mod *Model) MustDeleteAll(confirm bool) int {
+	effect, err := mod.DeleteAll(confirm)
+	if err != nil {
+		throwError(err)
 	}
 	return effect
 }
 
+// Truncate 清空数据表并重置自增主键 (不发布变更事件), confirm 须为 true, 防止误删; 不能在事务中使用
+// MySQL: TRUNCATE TABLE, PostgreSQL: TRUNCATE TABLE ... RESTART IDENTITY, SQLite: DELETE 并清除 sqlite_sequence 记录
+func (mod *Model) Truncate(confirm bool) error {
+	if !confirm {
+		return fmt.Errorf("Truncate 将清空 %s 的全部数据, 需要确认 (confirm = true)", mod.Name)
+	}
+	if err := mod.readonly(); err != nil {
+		return err
+	}
+	if mod.tx != nil {
+		return fmt.Errorf("Truncate 不能在事务中使用")
+	}
+
+	db := capsule.Query().DB()
+	table := mod.quote(mod.TableName())
+	start := time.Now()
+	switch mod.Driver {
+	case "sqlite3":
+		_, err := db.Exec(fmt.Sprintf("DELETE FROM %s", table))
+		if err != nil {
+			return err
+		}
+		db.Exec("DELETE FROM sqlite_sequence WHERE name = ?", mod.TableName()) // 未使用 AUTOINCREMENT 时没有 sqlite_sequence 表
+	case "postgres":
+		_, err := db.Exec(fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY", table))
+		if err != nil {
+			return err
+		}
+	default:
+		_, err := db.Exec(fmt.Sprintf("TRUNCATE TABLE %s", table))
+		if err != nil {
+			return err
+		}
+	}
+	mod.stat(start, 1, 0, 0)
+	return nil
+}
+
+// MustTruncate 清空数据表并重置自增主键, 失败抛出异常
+func (mod *Model) MustTruncate(confirm bool) {
+	err := mod.Truncate(confirm)
+	if err != nil {
+		throwError(err)
+	}
+}
+
 // EachSave 批量保存数据, 返回数据ID集合
 func (mod *Model) EachSave(rows []map[string]interface{}, eachrow ...maps.MapStrAny) ([]int, error) {
 	messages := []string{}
 	ids := []int{}
 	for i, row := range rows {
 
-		if len(eachrow) > 0 {
-			for k, v := range eachrow[0] {
-				if v == "$index" {
-					row[k] = i
-				} else {
-					row[k] = v
-				}
-			}
-		}
-
+		eachRow(row, i, eachrow...)
 		id, err := mod.Save(row)
 		if err != nil {
 			messages = append(messages, fmt.Sprintf("第 %d 条: %s", i, err.Error()))
@@ -492,7 +1773,68 @@ func (mod *Model) EachSave(rows []map[string]interface{}, eachrow ...maps.MapStr
 func (mod *Model) MustEachSave(rows []map[string]interface{}, eachrow ...maps.MapStrAny) []int {
 	ids, err := mod.EachSave(rows, eachrow...)
 	if err != nil {
-		exception.Err(err, 500).Throw()
+		throwError(err)
 	}
 	return ids
 }
+
+// SaveResult 批量保存结果
+type SaveResult struct {
+	Created []int      `json:"created"` // 新建数据ID
+	Updated []int      `json:"updated"` // 更新数据ID
+	Failed  []RowError `json:"failed"`  // 出错数据 (Line 为数据序号, 从 0 开始)
+}
+
+// EachSaveResult 批量保存数据, 按操作类型返回保存结果; 与 Save 一致, 含主键时更新, 否则新建
+func (mod *Model) EachSaveResult(rows []map[string]interface{}, eachrow ...maps.MapStrAny) SaveResult {
+	res := SaveResult{Created: []int{}, Updated: []int{}, Failed: []RowError{}}
+	for i, row := range rows {
+		eachRow(row, i, eachrow...)
+		update := maps.MapStrAny(row).Has(mod.PrimaryKey)
+		id, err := mod.save(row) // 校验失败按行记录, 不抛出异常
+		if err != nil {
+			res.Failed = append(res.Failed, rowErrors(i, err)...)
+			continue
+		}
+		if update {
+			res.Updated = append(res.Updated, id)
+		} else {
+			res.Created = append(res.Created, id)
+		}
+	}
+	return res
+}
+
+// eachRow 写入每条数据的公共字段, 数值为 $index 时写入数据序号
+func eachRow(row map[string]interface{}, index int, eachrow ...maps.MapStrAny) {
+	if len(eachrow) == 0 {
+		return
+	}
+	for k, v := range eachrow[0] {
+		if v == "$index" {
+			row[k] = index
+		} else {
+			row[k] = v
+		}
+	}
+}
+
+// rowErrors 保存错误转换为行数据错误, 校验错误按字段展开
+func rowErrors(line int, err error) []RowError {
+	var validation *ErrValidation
+	var conflict *ErrConflict
+	responses := []ValidateResponse{}
+	if errors.As(err, &validation) {
+		responses = validation.Responses
+	} else if errors.As(err, &conflict) {
+		responses = conflict.Responses
+	}
+	if len(responses) == 0 {
+		return []RowError{{Line: line, Messages: []string{err.Error()}}}
+	}
+	res := []RowError{}
+	for _, v := range responses {
+		res = append(res, RowError{Line: line, Column: v.Column, Messages: v.Messages})
+	}
+	return res
+}
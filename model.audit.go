@@ -0,0 +1,96 @@
+package gou
+
+import (
+	"context"
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// AuditTable 审计日志数据表名称
+var AuditTable = "__audit_logs"
+
+type actorKey struct{}
+
+// WithActor 设定操作人, 用于审计日志
+func WithActor(ctx context.Context, actor interface{}) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorOf 读取上下文中的操作人
+func ActorOf(ctx context.Context) interface{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Value(actorKey{})
+}
+
+// WithContext 返回绑定上下文的模型副本, 上下文绑定事务 (WithTx) 且模型未绑定事务时使用该事务
+func (mod *Model) WithContext(ctx context.Context) *Model {
+	new := *mod
+	new.ctx = ctx
+	if new.tx == nil {
+		new.tx = TxOf(ctx)
+	}
+	return &new
+}
+
+// auditing 是否需要在事务中写入审计日志或维护树形路径 (已在事务中时返回 false)
+func (mod *Model) auditing() bool {
+	return (mod.MetaData.Option.Audit || mod.MetaData.Option.Path != "") && mod.tx == nil
+}
+
+// audit 写入审计日志 (与数据变更共用事务)
+func (mod *Model) audit(op string, id interface{}, before maps.MapStr, after maps.MapStr) error {
+	if !mod.MetaData.Option.Audit {
+		return nil
+	}
+
+	beforeJSON, err := jsoniter.MarshalToString(before)
+	if err != nil {
+		return err
+	}
+
+	afterJSON, err := jsoniter.MarshalToString(eventRow(after))
+	if err != nil {
+		return err
+	}
+
+	actor := ActorOf(mod.ctx)
+	if actor != nil {
+		actor = fmt.Sprintf("%v", actor)
+	}
+
+	return mod.newQuery().Table(tablePrefix + AuditTable).Insert(maps.MapStr{
+		"model":      mod.Name,
+		"op":         op,
+		"record_id":  fmt.Sprintf("%v", id),
+		"before":     beforeJSON,
+		"after":      afterJSON,
+		"actor":      actor,
+		"created_at": dbal.Raw("CURRENT_TIMESTAMP"),
+	})
+}
+
+// AuditMigrate 创建审计日志数据表
+func AuditMigrate() error {
+	sch := capsule.Schema()
+	table := tablePrefix + AuditTable
+	if sch.MustHasTable(table) {
+		return nil
+	}
+	return sch.CreateTable(table, func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("model", 200).Index()
+		table.String("op", 20).Index()
+		table.String("record_id", 200).Index()
+		table.JSON("before").Null()
+		table.JSON("after").Null()
+		table.String("actor", 200).Index().Null()
+		table.Timestamp("created_at").Index().Null()
+	})
+}
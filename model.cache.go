@@ -0,0 +1,209 @@
+package gou
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+)
+
+// CacheKeyPrefix FindCached 缓存键名前缀
+// 记录键名为 <前缀><模型名称>:<主键>, 保存记录的缓存版本; 查询结果键名为 <记录键名>:<版本>:<查询参数摘要>
+var CacheKeyPrefix = "gou:model:"
+
+// CacheStore 缓存存储 (如 Redis), 用于 FindCached 缓存单条数据
+type CacheStore interface {
+	Get(key string) ([]byte, bool, error)                  // 读取缓存, 不存在时返回 false
+	Set(key string, value []byte, ttl time.Duration) error // 写入缓存, ttl 为 0 时不过期
+	Del(key string) error                                  // 删除缓存
+}
+
+var cacheStore CacheStore
+var cacheStoreLock sync.RWMutex
+
+// SetCacheStore 设置 FindCached 使用的缓存存储, 为 nil 时 FindCached 直接查询数据库
+func SetCacheStore(store CacheStore) {
+	cacheStoreLock.Lock()
+	defer cacheStoreLock.Unlock()
+	cacheStore = store
+}
+
+// cacheStoreOf 读取缓存存储
+func cacheStoreOf() CacheStore {
+	cacheStoreLock.RLock()
+	defer cacheStoreLock.RUnlock()
+	return cacheStore
+}
+
+// FindCached 查询单条记录, 优先读取缓存 (cache-aside), 缓存未命中时查询数据库并写入缓存
+// 同一记录按查询参数分别缓存 (各自的缓存键); 通过模型更新、删除该记录时删除记录版本, 已缓存的查询结果不再读取 (按 ttl 过期, ttl 为 0 时需由缓存存储淘汰), 关联模型的变更不清除缓存
+// 缓存数据使用与 FliterOut 相同的 JSON 编码 (jsoniter), 读取缓存时数值为 float64, 关联数据为 map[string]interface{}
+// 未设置缓存存储、在事务中或应用全局查询范围时直接查询数据库; 缓存读写失败时记录 WARN 日志并返回数据库查询结果
+func (mod *Model) FindCached(id interface{}, param QueryParam, ttl time.Duration) (maps.MapStr, error) {
+	store := cacheStoreOf()
+	if store == nil || mod.tx != nil || mod.scoped() {
+		return mod.Find(id, param)
+	}
+
+	version, err := cacheVersion(store, mod.cacheKey(id), ttl)
+	if err != nil {
+		log.With(log.F{"key": mod.cacheKey(id)}).Warn("FindCached version: %s", err)
+		return mod.Find(id, param)
+	}
+
+	key := fmt.Sprintf("%s:%s:%s", mod.cacheKey(id), version, cacheVariant(param))
+	data, has, err := store.Get(key)
+	if err != nil {
+		log.With(log.F{"key": key}).Warn("FindCached get: %s", err)
+	} else if has {
+		row := maps.MapStr{}
+		if err := jsoniter.Unmarshal(data, &row); err == nil {
+			return row, nil
+		}
+	}
+
+	row, err := mod.Find(id, param)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = jsoniter.Marshal(row)
+	if err == nil {
+		err = store.Set(key, data, ttl)
+	}
+	if err != nil {
+		log.With(log.F{"key": key}).Warn("FindCached set: %s", err)
+	}
+	return row, nil
+}
+
+// MustFindCached 查询单条记录, 优先读取缓存, 失败抛出异常
+func (mod *Model) MustFindCached(id interface{}, param QueryParam, ttl time.Duration) maps.MapStr {
+	res, err := mod.FindCached(id, param, ttl)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// cacheKey 缓存键名
+func (mod *Model) cacheKey(id interface{}) string {
+	return fmt.Sprintf("%s%s:%v", CacheKeyPrefix, mod.Name, id)
+}
+
+// cacheVersion 读取记录的缓存版本, 不存在时创建
+// 并发创建时后写入的版本生效, 先写入版本下的查询结果不再读取
+func cacheVersion(store CacheStore, key string, ttl time.Duration) (string, error) {
+	data, has, err := store.Get(key)
+	if err != nil {
+		return "", err
+	}
+	if has {
+		return string(data), nil
+	}
+	version := NewUUID()
+	return version, store.Set(key, []byte(version), ttl)
+}
+
+// cacheVariant 查询参数摘要 (同一记录不同查询参数的缓存)
+func cacheVariant(param QueryParam) string {
+	param.Wheres = nil
+	data, _ := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(param) // 字段按名称排序
+	hash := fnv.New64a()
+	hash.Write(data)
+	return fmt.Sprintf("%x", hash.Sum64())
+}
+
+// scoped 是否应用全局查询范围 (查询结果与上下文相关, 不缓存)
+func (mod *Model) scoped() bool {
+	scopesLock.RLock()
+	list := globalScopes[mod.Name]
+	scopesLock.RUnlock()
+	param := QueryParam{without: mod.withoutScopes}
+	for _, scope := range list {
+		if !param.bypass(scope.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cached 是否需要在数据变更时清除缓存
+func (mod *Model) cached() bool {
+	return cacheStoreOf() != nil
+}
+
+// evict 删除记录的缓存版本, 事务中的变更在提交后再次删除 (避免提交前读取旧数据写入缓存)
+func (mod *Model) evict(id interface{}) {
+	store := cacheStoreOf()
+	if store == nil || id == nil {
+		return
+	}
+	key := mod.cacheKey(id)
+	del := func() {
+		if err := store.Del(key); err != nil {
+			log.With(log.F{"key": key}).Warn("Cache evict: %s", err)
+		}
+	}
+	del()
+	if mod.tx != nil {
+		mod.tx.afterCommit(del)
+	}
+}
+
+// MemoryCache 进程内缓存存储 (单实例部署或测试使用, 多实例部署请使用 Redis 等共享存储)
+type MemoryCache struct {
+	items map[string]memoryCacheItem
+	lock  sync.RWMutex
+}
+
+type memoryCacheItem struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache 创建进程内缓存存储
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: map[string]memoryCacheItem{}}
+}
+
+// Get 读取缓存
+func (cache *MemoryCache) Get(key string) ([]byte, bool, error) {
+	cache.lock.RLock()
+	defer cache.lock.RUnlock()
+	item, has := cache.items[key]
+	if !has || (!item.expires.IsZero() && time.Now().After(item.expires)) {
+		return nil, false, nil
+	}
+	return item.value, true, nil
+}
+
+// Set 写入缓存 (同时清理已过期的缓存)
+func (cache *MemoryCache) Set(key string, value []byte, ttl time.Duration) error {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	now := time.Now()
+	for name, item := range cache.items {
+		if !item.expires.IsZero() && now.After(item.expires) {
+			delete(cache.items, name)
+		}
+	}
+	item := memoryCacheItem{value: value}
+	if ttl > 0 {
+		item.expires = now.Add(ttl)
+	}
+	cache.items[key] = item
+	return nil
+}
+
+// Del 删除缓存
+func (cache *MemoryCache) Del(key string) error {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	delete(cache.items, key)
+	return nil
+}
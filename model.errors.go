@@ -0,0 +1,116 @@
+package gou
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yaoapp/kun/exception"
+)
+
+// ErrNotFound 数据不存在
+type ErrNotFound struct {
+	Model string
+	ID    interface{}
+}
+
+// ErrValidation 输入数据校验失败
+type ErrValidation struct {
+	Model     string
+	Responses []ValidateResponse
+}
+
+// ErrConflict 数据冲突 (违反唯一约束), Responses 为唯一约束校验结果, Err 为数据库返回的唯一键冲突错误
+type ErrConflict struct {
+	Model     string
+	Responses []ValidateResponse
+	Err       error
+}
+
+// ErrStale 数据已被其他请求修改 (并发更新)
+type ErrStale struct {
+	Model string
+	ID    interface{}
+}
+
+func (err *ErrNotFound) Error() string {
+	return fmt.Sprintf("ID=%v的数据不存在", err.ID)
+}
+
+func (err *ErrValidation) Error() string {
+	return "输入参数错误"
+}
+
+func (err *ErrConflict) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("数据冲突: %s", err.Err)
+	}
+	return "数据冲突"
+}
+
+// Unwrap 数据库返回的原始错误
+func (err *ErrConflict) Unwrap() error {
+	return err.Err
+}
+
+func (err *ErrStale) Error() string {
+	return fmt.Sprintf("ID=%v的数据已被修改", err.ID)
+}
+
+// ErrorCode 错误对应的 HTTP 状态码: ErrNotFound 404, ErrValidation 400, ErrConflict 和 ErrStale 409, 其他错误 500
+func ErrorCode(err error) int {
+	var notFound *ErrNotFound
+	var validation *ErrValidation
+	var conflict *ErrConflict
+	var stale *ErrStale
+	switch {
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &validation):
+		return http.StatusBadRequest
+	case errors.As(err, &conflict), errors.As(err, &stale):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// throwError 按错误类型抛出异常, 状态码为 ErrorCode, 校验结果作为异常上下文
+func throwError(err error) {
+	ex := exception.Err(err, ErrorCode(err))
+	var validation *ErrValidation
+	var conflict *ErrConflict
+	if errors.As(err, &validation) {
+		ex = ex.Ctx(validation.Responses)
+	} else if errors.As(err, &conflict) && len(conflict.Responses) > 0 {
+		ex = ex.Ctx(conflict.Responses)
+	}
+	ex.Throw()
+}
+
+// throwInvalid 校验失败 (ErrValidation) 或违反范围唯一约束 (ErrConflict 不含数据库错误) 时抛出异常 (400), 其他错误原样返回
+// 写入方法校验失败时抛出异常, 不作为错误返回
+func throwInvalid(err error) error {
+	var validation *ErrValidation
+	var conflict *ErrConflict
+	if errors.As(err, &validation) {
+		exception.New("输入参数错误", 400).Ctx(validation.Responses).Throw()
+	} else if errors.As(err, &conflict) && conflict.Err == nil {
+		exception.New("输入参数错误", 400).Ctx(conflict.Responses).Throw()
+	}
+	return err
+}
+
+// conflict 数据库唯一键冲突错误转换为 ErrConflict (SQLite, MySQL, PostgreSQL), 其他错误原样返回
+func (mod *Model) conflict(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	if strings.Contains(message, "UNIQUE constraint failed") || // SQLite
+		strings.Contains(message, "Duplicate entry") || // MySQL 1062
+		strings.Contains(message, "duplicate key value violates unique constraint") { // PostgreSQL 23505
+		return &ErrConflict{Model: mod.Name, Err: err}
+	}
+	return err
+}
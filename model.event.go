@@ -0,0 +1,200 @@
+package gou
+
+import (
+	"sync"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/dbal"
+)
+
+// 数据变更类型
+const (
+	EventCreate  = "create"
+	EventUpdate  = "update"
+	EventDelete  = "delete"
+	EventDestroy = "destroy"
+	EventRestore = "restore"
+)
+
+// EventBufferSize 每个订阅者的事件缓冲数量
+// 订阅者处理过慢、缓冲已满时, 新事件将被丢弃并记录 WARN 日志, 不会阻塞数据写入
+var EventBufferSize = 256
+
+// BulkEventRows 批量更新、删除 (UpdateWhere, DeleteWhere, DestroyWhere 等) 和批量写入 (Insert) 是否按记录发布事件, 默认不按记录发布
+// 按记录发布时, 批量更新、删除前分批读取全部受影响记录 (变更前数据) 并保留在内存中直到写入完成, 批量写入改为逐条写入
+// 未开启时, 批量更新、删除仅分批读取受影响记录的主键, 发布一个包含全部 ID 的事件 (Before 为空); 批量写入发布一个 IDs 为空的 create 事件
+// 开启审计 (option.audit) 的模型始终按记录写入审计日志和发布事件
+var BulkEventRows = false
+
+// ChangeEvent 数据变更事件
+type ChangeEvent struct {
+	Model  string        `json:"model"`
+	Op     string        `json:"op"` // create, update, delete, destroy, restore
+	IDs    []interface{} `json:"ids"`
+	Before maps.MapStr   `json:"before,omitempty"` // 变更前数据 (update, delete, destroy)
+	After  maps.MapStr   `json:"after,omitempty"`  // 写入数据 (create, update)
+}
+
+type subscriber struct {
+	events chan ChangeEvent
+}
+
+// 已注册订阅者 (按模型名称), 模型重新加载后仍然有效
+var subscribers = map[string][]*subscriber{}
+var subscribersLock sync.RWMutex
+
+// Subscribe 订阅模型数据变更事件, 事件在数据写入 (事务提交) 后异步投递, 返回取消订阅函数
+func (mod *Model) Subscribe(fn func(ChangeEvent)) func() {
+	sub := &subscriber{events: make(chan ChangeEvent, EventBufferSize)}
+	go func() {
+		for event := range sub.events {
+			fn(event)
+		}
+	}()
+
+	name := mod.Name
+	subscribersLock.Lock()
+	subscribers[name] = append(subscribers[name], sub)
+	subscribersLock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			subscribersLock.Lock()
+			defer subscribersLock.Unlock()
+			list := []*subscriber{}
+			for _, item := range subscribers[name] {
+				if item != sub {
+					list = append(list, item)
+				}
+			}
+			subscribers[name] = list
+			close(sub.events) // 已缓冲的事件处理完成后退出
+		})
+	}
+}
+
+// observed 是否需要记录变更数据 (有订阅者、Webhook 或开启审计)
+func (mod *Model) observed() bool {
+	return mod.hasSubscribers() || len(mod.MetaData.Webhooks) > 0 || mod.MetaData.Option.Audit
+}
+
+// hasSubscribers 是否有订阅者
+func (mod *Model) hasSubscribers() bool {
+	subscribersLock.RLock()
+	defer subscribersLock.RUnlock()
+	return len(subscribers[mod.Name]) > 0
+}
+
+// changed 数据变更后清除缓存, 写入审计日志并发布事件
+func (mod *Model) changed(op string, id interface{}, before maps.MapStr, after maps.MapStr) error {
+	if op != EventCreate {
+		mod.evict(id)
+	}
+	err := mod.audit(op, id, before, after)
+	if err != nil {
+		return err
+	}
+	mod.publish(op, []interface{}{id}, before, after)
+	return nil
+}
+
+// changedRows 批量变更后按记录写入审计日志并发布事件 (未按记录发布时清除缓存并发布一个包含全部 ID 的事件)
+func (mod *Model) changedRows(op string, before []maps.MapStr, after maps.MapStr) error {
+	if !mod.bulkRows() {
+		ids := []interface{}{}
+		for _, row := range before {
+			id := row.Get(mod.PrimaryKey)
+			mod.evict(id)
+			ids = append(ids, id)
+		}
+		if len(ids) > 0 {
+			mod.publish(op, ids, nil, after)
+		}
+		return nil
+	}
+
+	for _, row := range before {
+		err := mod.changed(op, row.Get(mod.PrimaryKey), row, after)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish 发布数据变更事件并发送 Webhook, 事务中的变更在提交后发布
+func (mod *Model) publish(op string, ids []interface{}, before maps.MapStr, after maps.MapStr) {
+	if !mod.hasSubscribers() && len(mod.MetaData.Webhooks) == 0 {
+		return
+	}
+
+	event := ChangeEvent{Model: mod.Name, Op: op, IDs: ids, Before: before, After: eventRow(after)}
+	if mod.tx != nil {
+		mod.tx.afterCommit(func() {
+			dispatch(event)
+			mod.webhook(event)
+		})
+		return
+	}
+	dispatch(event)
+	mod.webhook(event)
+}
+
+// dispatch 投递事件 (非阻塞)
+func dispatch(event ChangeEvent) {
+	subscribersLock.RLock()
+	defer subscribersLock.RUnlock()
+	for _, sub := range subscribers[event.Model] {
+		select {
+		case sub.events <- event:
+		default:
+			log.With(log.F{"model": event.Model, "op": event.Op, "ids": event.IDs}).Warn("ChangeEvent dropped, subscriber is too slow")
+		}
+	}
+}
+
+// bulkRows 批量变更是否按记录发布事件 (BulkEventRows 或开启审计)
+func (mod *Model) bulkRows() bool {
+	return BulkEventRows || mod.MetaData.Option.Audit
+}
+
+// eventRows 读取批量变更前的数据, 未按记录发布事件时仅读取主键 (无订阅者、未开启审计且未设置缓存存储时不查询)
+func (mod *Model) eventRows(param QueryParam) ([]maps.MapStr, error) {
+	if !mod.observed() && !mod.cached() {
+		return nil, nil
+	}
+
+	param.Select = nil
+	if !mod.bulkRows() {
+		param.Select = []interface{}{mod.PrimaryKey}
+	}
+	param.Withs = nil
+	if param.Limit > 0 {
+		return mod.Get(param)
+	}
+
+	rows := []maps.MapStr{}
+	param.Orders = nil
+	err := mod.Each(param, ExportChunkSize, func(chunk []maps.MapStr) error {
+		rows = append(rows, chunk...)
+		return nil
+	})
+	return rows, err
+}
+
+// eventRow 复制写入数据 (忽略数据库表达式)
+func eventRow(row maps.MapStr) maps.MapStr {
+	if row == nil {
+		return nil
+	}
+	res := maps.MapStr{}
+	for key, value := range row {
+		if _, ok := value.(dbal.Expression); ok {
+			continue
+		}
+		res[key] = value
+	}
+	return res
+}
@@ -0,0 +1,147 @@
+package gou
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/day"
+	"github.com/yaoapp/kun/maps"
+)
+
+// ExcelOptions Excel 导出选项
+type ExcelOptions struct {
+	SheetName    string `json:"sheet,omitempty"`  // 工作表名称, 默认为模型名称
+	FreezeHeader bool   `json:"freeze,omitempty"` // 冻结表头
+}
+
+// ExportExcel 按查询条件导出 Excel (xlsx), 首行为加粗表头, 单元格按字段类型设定格式
+func (mod *Model) ExportExcel(w io.Writer, param QueryParam, opts ExcelOptions) error {
+	sheet := opts.SheetName
+	if sheet == "" {
+		sheet = mod.Name
+	}
+
+	f := excelize.NewFile()
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	columns := mod.exportColumns(param)
+	styles, err := mod.excelStyles(f, columns)
+	if err != nil {
+		return err
+	}
+
+	// 表头
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	for i, name := range columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		label := name
+		if column := mod.Columns[name]; column.Label != "" {
+			label = column.Label
+		}
+		f.SetCellValue(sheet, cell, label)
+		f.SetCellStyle(sheet, cell, cell, bold)
+	}
+
+	if opts.FreezeHeader {
+		err = f.SetPanes(sheet, `{"freeze":true,"split":false,"x_split":0,"y_split":1,"top_left_cell":"A2","active_pane":"bottomLeft"}`)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 数据
+	line := 1
+	err = mod.Each(param, ExportChunkSize, func(rows []maps.MapStr) error {
+		for _, row := range rows {
+			line++
+			for i, name := range columns {
+				cell, _ := excelize.CoordinatesToCellName(i+1, line)
+				err := f.SetCellValue(sheet, cell, excelValue(mod.Columns[name], row.Get(name)))
+				if err != nil {
+					return err
+				}
+				if style, has := styles[name]; has {
+					f.SetCellStyle(sheet, cell, cell, style)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return f.Write(w)
+}
+
+// excelStyles 按字段类型创建单元格格式
+func (mod *Model) excelStyles(f *excelize.File, columns []string) (map[string]int, error) {
+	styles := map[string]int{}
+	for _, name := range columns {
+		column := mod.Columns[name]
+		style := &excelize.Style{}
+		switch strings.ToLower(column.Type) {
+		case "date":
+			style.NumFmt = 14 // yyyy/m/d
+		case "datetime", "datetimetz", "timestamp", "timestamptz":
+			style.NumFmt = 22 // yyyy/m/d h:mm
+		case "decimal", "unsigneddecimal", "float", "unsignedfloat", "double", "unsigneddouble":
+			format := "0"
+			if column.Scale > 0 {
+				format = "0." + strings.Repeat("0", column.Scale)
+			}
+			style.CustomNumFmt = &format
+		default:
+			continue
+		}
+
+		id, err := f.NewStyle(style)
+		if err != nil {
+			return nil, err
+		}
+		styles[name] = id
+	}
+	return styles, nil
+}
+
+// excelValue 按字段类型转换单元格数值
+func excelValue(column *Column, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	switch strings.ToLower(column.Type) {
+	case "date", "datetime", "datetimetz", "timestamp", "timestamptz":
+		if t, ok := value.(time.Time); ok {
+			return t
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", day.Of(value).Format("2006-01-02 15:04:05"), time.Local)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return t
+	case "tinyinteger", "unsignedtinyinteger", "smallinteger", "unsignedsmallinteger",
+		"integer", "unsignedinteger", "biginteger", "unsignedbiginteger",
+		"tinyincrements", "smallincrements", "increments", "bigincrements", "id":
+		return any.Of(value).CInt()
+	case "decimal", "unsigneddecimal", "float", "unsignedfloat", "double", "unsigneddouble":
+		return any.Of(value).CFloat()
+	case "json", "jsonb":
+		return exportString(value)
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string, int, int64, float64, bool:
+		return v
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,88 @@
+package gou
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/maps"
+)
+
+// ExportChunkSize 导出数据时每批读取的记录数量
+var ExportChunkSize = 500
+
+// ExportCSV 按查询条件导出 CSV (RFC 4180), 首行为字段名称
+func (mod *Model) ExportCSV(w io.Writer, param QueryParam) error {
+	columns := mod.exportColumns(param)
+	writer := csv.NewWriter(w)
+	err := writer.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	err = mod.Each(param, ExportChunkSize, func(rows []maps.MapStr) error {
+		for _, row := range rows {
+			record := []string{}
+			for _, name := range columns {
+				record = append(record, exportString(row.Get(name)))
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportColumns 导出字段清单 (未指定 Select 时导出全部字段, 隐藏字段不导出)
+func (mod *Model) exportColumns(param QueryParam) []string {
+	selects := param.Select
+	if len(selects) == 0 {
+		selects = mod.VisibleColumnNames()
+	}
+
+	except := map[string]bool{}
+	for _, name := range param.Except {
+		except[name] = true
+	}
+
+	columns := []string{}
+	for _, col := range selects {
+		name, ok := col.(string)
+		if !ok || except[name] {
+			continue
+		}
+		if column, has := mod.Columns[name]; !has || column.Hidden {
+			continue
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+// exportString 导出数值转换为字符串
+func exportString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case map[string]interface{}, maps.MapStrAny, []interface{}:
+		bytes, err := jsoniter.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(bytes)
+	}
+	return fmt.Sprintf("%v", value)
+}
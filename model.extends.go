@@ -0,0 +1,93 @@
+package gou
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/kun/exception"
+)
+
+// inherit 合并 mixins 与 extends 引用的模型定义 (引用的模型须已载入)
+// 合并顺序: mixins 按声明顺序依次合并, 然后合并 extends, 最后合并当前模型; 同名字段/索引/关联后者覆盖前者
+// 字段、索引保持首次出现的位置; Option.Timestamps, Option.SoftDeletes 任一定义开启即开启
+func (metadata MetaData) inherit(name string) MetaData {
+	bases := append([]string{}, metadata.Mixins...)
+	if metadata.Extends != "" {
+		bases = append(bases, metadata.Extends)
+	}
+	if len(bases) == 0 {
+		return metadata
+	}
+
+	merged := MetaData{}
+	for _, base := range bases {
+		if base == name {
+			exception.New("模型 %s 不能继承自身", 400, name).Throw()
+		}
+		mod, has := Models[base]
+		if !has {
+			exception.New("模型 %s 引用的模型 %s 尚未载入", 400, name, base).Throw()
+		}
+		merged = merged.merge(mod.MetaData)
+	}
+	return merged.merge(metadata)
+}
+
+// merge 合并模型定义, child 优先
+func (metadata MetaData) merge(child MetaData) MetaData {
+	res := child
+	res.Columns = []Column{}
+	res.Indexes = []Index{}
+	res.Relations = map[string]Relation{}
+
+	columns := map[string]int{}
+	for _, column := range append(append([]Column{}, metadata.Columns...), child.Columns...) {
+		if i, has := columns[column.Name]; has {
+			res.Columns[i] = column
+			continue
+		}
+		columns[column.Name] = len(res.Columns)
+		res.Columns = append(res.Columns, column)
+	}
+
+	indexes := map[string]int{}
+	for i, index := range append(append([]Index{}, metadata.Indexes...), child.Indexes...) {
+		key := index.Name
+		if key == "" {
+			key = fmt.Sprintf("#%d", i) // 未命名索引不合并
+		}
+		if i, has := indexes[key]; has {
+			res.Indexes[i] = index
+			continue
+		}
+		indexes[key] = len(res.Indexes)
+		res.Indexes = append(res.Indexes, index)
+	}
+
+	for name, rel := range metadata.Relations {
+		res.Relations[name] = rel
+	}
+	for name, rel := range child.Relations {
+		res.Relations[name] = rel
+	}
+
+	res.Option.Timestamps = metadata.Option.Timestamps || child.Option.Timestamps
+	res.Option.SoftDeletes = metadata.Option.SoftDeletes || child.Option.SoftDeletes
+	if !child.Option.SoftDeletes && metadata.Option.SoftDeletes {
+		res.SoftDelete = metadata.SoftDelete
+	}
+	if res.DefaultOrder == "" {
+		res.DefaultOrder = metadata.DefaultOrder
+	}
+	res.Webhooks = append(append([]Webhook{}, metadata.Webhooks...), child.Webhooks...)
+	return res
+}
+
+// hasColumn 是否已定义字段
+func (metadata MetaData) hasColumn(name string) bool {
+	for _, column := range metadata.Columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package gou
+
+import (
+	"strings"
+
+	"github.com/yaoapp/kun/maps"
+)
+
+// FieldsStrict 稀疏字段集 (QueryParam.Fields) 含未知字段时返回 ErrValidation (400), 默认忽略未知字段
+var FieldsStrict = false
+
+// fieldset 稀疏字段集, 按字段和关联名称记录需要输出的字段
+type fieldset struct {
+	columns map[string]bool
+	rels    map[string]map[string]bool
+}
+
+// applyFields 按稀疏字段集设置查询字段和关联查询 (覆盖 Select), 返回用于裁剪结果的字段集; 未设置时返回 nil
+// 字段为模型字段 (如 name) 或关联模型字段 (如 manu.name, 自动加载关联), 隐藏字段视为未知字段
+func (mod *Model) applyFields(param *QueryParam) (*fieldset, error) {
+	if len(param.Fields) == 0 {
+		return nil, nil
+	}
+
+	fields := &fieldset{columns: map[string]bool{}, rels: map[string]map[string]bool{}}
+	unknown := []string{}
+	selects := []interface{}{}
+	withs := map[string]With{}
+	for name, with := range param.Withs {
+		withs[name] = with
+	}
+
+	for _, field := range param.Fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		// 模型字段
+		if !strings.Contains(field, ".") {
+			if !mod.fieldVisible(field) {
+				unknown = append(unknown, field)
+				continue
+			}
+			if !fields.columns[field] {
+				fields.columns[field] = true
+				selects = append(selects, field)
+			}
+			continue
+		}
+
+		// 关联模型字段
+		parts := strings.SplitN(field, ".", 2)
+		name, column := parts[0], parts[1]
+		rel, has := mod.MetaData.Relations[name]
+		if !has {
+			unknown = append(unknown, field)
+			continue
+		}
+		related, has := Models[rel.Model]
+		if !has || !related.fieldVisible(column) {
+			unknown = append(unknown, field)
+			continue
+		}
+
+		if _, has := fields.rels[name]; !has {
+			fields.rels[name] = map[string]bool{}
+		}
+		if fields.rels[name][column] {
+			continue
+		}
+		fields.rels[name][column] = true
+		with := withs[name]
+		with.Query.Select = append(append([]interface{}{}, with.Query.Select...), column)
+		withs[name] = with
+	}
+
+	if len(unknown) > 0 && FieldsStrict {
+		return nil, &ErrValidation{Model: mod.Name, Responses: []ValidateResponse{{
+			Column:   strings.Join(unknown, ","),
+			Messages: []string{"字段不存在: " + strings.Join(unknown, ", ")},
+		}}}
+	}
+
+	// 仅关联字段时读取主键
+	if len(selects) == 0 {
+		selects = append(selects, mod.PrimaryKey)
+	}
+	param.Select = selects
+	param.Withs = withs
+	return fields, nil
+}
+
+// fieldVisible 是否为可输出的模型字段 (非隐藏字段)
+func (mod *Model) fieldVisible(name string) bool {
+	column, has := mod.Columns[name]
+	return has && !column.Hidden
+}
+
+// trim 裁剪查询结果, 仅保留字段集中的字段 (关联查询自动读取的关联键等字段移除)
+func (fields *fieldset) trim(rows []maps.MapStr) []maps.MapStr {
+	if fields == nil {
+		return rows
+	}
+	for i, row := range rows {
+		rows[i] = fields.trimRow(row)
+	}
+	return rows
+}
+
+// trimRow 裁剪单条数据
+func (fields *fieldset) trimRow(row maps.MapStr) maps.MapStr {
+	if row == nil {
+		return nil
+	}
+	res := maps.MapStr{}
+	for name := range fields.columns {
+		if row.Has(name) {
+			res[name] = row.Get(name)
+		}
+	}
+	for name, columns := range fields.rels {
+		if row.Has(name) {
+			res[name] = trimValue(row.Get(name), columns)
+		}
+	}
+	return res
+}
+
+// trimValue 裁剪关联数据 (hasOne 为单条数据, hasMany 为数据列表)
+func trimValue(value interface{}, columns map[string]bool) interface{} {
+	switch v := value.(type) {
+	case maps.MapStr:
+		return trimMap(v, columns)
+	case map[string]interface{}:
+		return trimMap(v, columns)
+	case []maps.MapStr:
+		res := []maps.MapStr{}
+		for _, item := range v {
+			res = append(res, trimMap(item, columns))
+		}
+		return res
+	case []interface{}:
+		res := []interface{}{}
+		for _, item := range v {
+			res = append(res, trimValue(item, columns))
+		}
+		return res
+	}
+	return value
+}
+
+// trimMap 保留指定字段
+func trimMap(row map[string]interface{}, columns map[string]bool) maps.MapStr {
+	res := maps.MapStr{}
+	for name := range columns {
+		if value, has := row[name]; has {
+			res[name] = value
+		}
+	}
+	return res
+}
+
+// trimPaginate 裁剪分页查询结果中的数据
+func (fields *fieldset) trimPaginate(res maps.MapStr) maps.MapStr {
+	if fields == nil || res == nil {
+		return res
+	}
+	data := "data"
+	if name, has := PaginateFormat["data"]; has {
+		data = name
+	}
+	if rows, ok := res.Get(data).([]maps.MapStr); ok {
+		res.Set(data, fields.trim(rows))
+	}
+	return res
+}
@@ -1,6 +1,12 @@
 package gou
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/maps"
 	"github.com/yaoapp/xun/dbal"
@@ -22,13 +28,75 @@ func (mod *Model) FliterIn(row maps.MapStrAny) {
 	}
 }
 
-// Filterselect 选择字段
+// Prepare 预演写入, 返回 Create, Update, Save (op) 写入前的数据 (已校验和预处理, 含时间戳), 不访问数据库, 不修改调用方数据
+// 校验失败时返回 nil 和校验结果; 数据库默认值由数据库填充, 不包含在返回数据中
+func (mod *Model) Prepare(op string, row maps.MapStr) (maps.MapStr, []ValidateResponse) {
+	op = strings.ToLower(op)
+	if op != "create" && op != "update" && op != "save" {
+		exception.New("不支持的写入操作 %s (create, update, save)", 400, op).Throw()
+	}
+
+	row = copyRow(row)
+	errs := mod.Validate(row)
+	if op == "create" || (op == "save" && !row.Has(mod.PrimaryKey)) {
+		errs = append(errs, mod.ValidateRequired(row)...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	mod.FliterIn(row)
+	if op != "update" && mod.MetaData.Option.SoftDeletes {
+		mod.softDelete().fliterIn(row) // 忽略删除字段
+	}
+	update := op == "update" || (op == "save" && row.Has(mod.PrimaryKey))
+	if op == "save" && update {
+		row.Del(mod.PrimaryKey) // 主键仅作为更新条件
+	}
+
+	if mod.MetaData.Option.Timestamps {
+		if update {
+			row.Set("updated_at", dbal.Raw("CURRENT_TIMESTAMP"))
+			if op == "save" {
+				row.Del("created_at") // 忽略创建字段
+			}
+		} else {
+			row.Set("created_at", dbal.Raw("CURRENT_TIMESTAMP"))
+			if op == "save" {
+				row.Del("updated_at") // 忽略更新字段
+			}
+		}
+	}
+	return row, nil
+}
+
+// Filterselect 选择字段 (开启 QueryPlanCache 时复用相同结构的字段解析结果)
 func (mod *Model) Filterselect(alias string, columns []interface{}, cmap map[string]ColumnMap, exportPrefix string) []interface{} {
-	res := []interface{}{}
 	if cmap == nil {
 		cmap = map[string]ColumnMap{}
 	}
 
+	key, cacheable := mod.selectPlanKey(alias, columns, exportPrefix)
+	if !cacheable {
+		return mod.filterselect(alias, columns, cmap, exportPrefix)
+	}
+	if res, has := mod.selectPlan(key, cmap); has {
+		return res
+	}
+
+	plan := map[string]ColumnMap{}
+	res := mod.filterselect(alias, columns, plan, exportPrefix)
+	storeSelectPlan(key, res, plan)
+	for name, column := range plan {
+		cmap[name] = column
+	}
+	return res
+}
+
+// filterselect 解析选择字段, 写入字段映射表
+func (mod *Model) filterselect(alias string, columns []interface{}, cmap map[string]ColumnMap, exportPrefix string) []interface{} {
+	res := []interface{}{}
+
 	for _, col := range columns {
 
 		if _, ok := col.(dbal.Expression); ok {
@@ -36,6 +104,12 @@ func (mod *Model) Filterselect(alias string, columns []interface{}, cmap map[str
 			continue
 		}
 
+		// 计算字段 {"expr": "balance * 100", "as": "balance_cents"}
+		if expr, as, ok := selectExpr(col); ok {
+			res = append(res, mod.selectExpr(alias, expr, as, cmap, exportPrefix))
+			continue
+		}
+
 		name, ok := col.(string)
 		if !ok {
 			continue
@@ -82,6 +156,109 @@ func (mod *Model) Filterselect(alias string, columns []interface{}, cmap map[str
 	return res
 }
 
+// selectFunctions 计算字段表达式可用的函数
+var selectFunctions = map[string]bool{
+	"abs": true, "round": true, "floor": true, "ceil": true, "coalesce": true, "ifnull": true, "nullif": true,
+	"lower": true, "upper": true, "length": true, "concat": true, "substr": true, "trim": true,
+}
+
+var reSelectAlias = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var reSelectToken = regexp.MustCompile(`^(\s+|[A-Za-z_][A-Za-z0-9_]*|[0-9]+(\.[0-9]+)?|'[^'\\]*'|<=|>=|<>|!=|\|\||[-+*/%(),<>=])`)
+
+// selectExpr 读取计算字段的表达式和别名
+func selectExpr(col interface{}) (string, string, bool) {
+	var values map[string]interface{}
+	switch v := col.(type) {
+	case map[string]interface{}:
+		values = v
+	case maps.MapStr:
+		values = v
+	default:
+		return "", "", false
+	}
+	expr, _ := values["expr"].(string)
+	as, _ := values["as"].(string)
+	return expr, as, true
+}
+
+// selectExpr 计算字段转换为 SQL (expr AS alias_as), 并添加到字段映射表
+// 表达式仅可使用模型字段 (隐藏字段和加密字段除外), 数值, 字符串, 运算符和 selectFunctions 中的函数; 别名不能与模型字段重名
+func (mod *Model) selectExpr(alias string, expr string, as string, cmap map[string]ColumnMap, exportPrefix string) interface{} {
+	if !reSelectAlias.MatchString(as) {
+		exception.New("计算字段别名 %s 无效", 400, as).Throw()
+	}
+	if _, has := mod.Columns[as]; has {
+		exception.New("计算字段别名 %s 与模型字段重名", 400, as).Throw()
+	}
+
+	sql := ""
+	rest := strings.TrimSpace(expr)
+	for rest != "" {
+		token := reSelectToken.FindString(rest)
+		if token == "" {
+			exception.New("计算字段表达式 %s 无效: %s", 400, expr, rest).Throw()
+		}
+		rest = rest[len(token):]
+
+		if !reSelectAlias.MatchString(token) {
+			sql = sql + token
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(rest), "(") { // 函数
+			if !selectFunctions[strings.ToLower(token)] {
+				exception.New("计算字段表达式 %s 不支持函数 %s", 400, expr, token).Throw()
+			}
+			sql = sql + token
+			continue
+		}
+
+		column, has := mod.Columns[token]
+		if !has || column.Hidden || column.Crypt != "" {
+			exception.New("计算字段表达式 %s 不能使用字段 %s", 400, expr, token).Throw()
+		}
+		field := token
+		if alias != "" {
+			field = alias + "." + token
+		}
+		sql = sql + mod.quote(field)
+	}
+
+	if sql == "" || strings.Contains(sql, "--") || strings.Contains(sql, "/*") {
+		exception.New("计算字段表达式 %s 无效", 400, expr).Throw()
+	}
+
+	varName := as
+	if alias != "" {
+		varName = alias + "_" + as
+	}
+	export := as
+	if exportPrefix != "" {
+		export = exportPrefix + "." + as
+	}
+	cmap[varName] = ColumnMap{
+		Model:  mod,
+		Column: &Column{Name: as},
+		Export: export,
+	}
+	return dbal.Raw(fmt.Sprintf("(%s) AS %s", sql, mod.quote(varName)))
+}
+
+// whereColumn 检查查询条件字段, 字段名称须为模型字段 (关联模型字段通过 QueryWhere.Rel 指定), 避免客户端传入的字段名称拼接到 SQL 中
+// 原始表达式须在代码中显式使用 dbal.Raw (JSON 及 URL 参数无法构造)
+func (mod *Model) whereColumn(col interface{}) error {
+	switch col := col.(type) {
+	case dbal.Expression:
+		return nil
+	case string:
+		if _, has := mod.Columns[col]; has {
+			return nil
+		}
+		return fmt.Errorf("查询条件字段 %s 不是模型 %s 的字段", col, mod.Name)
+	}
+	return fmt.Errorf("查询条件字段类型错误 (%T)", col)
+}
+
 // FliterWhere 选项
 func (mod *Model) FliterWhere(alias string, col interface{}) interface{} {
 	if _, ok := col.(dbal.Expression); ok {
@@ -116,6 +293,186 @@ func (mod *Model) FliterWhere(alias string, col interface{}) interface{} {
 	return name
 }
 
+// fliterWhereValue 按字段类型转换查询条件数值 (如 "1" => 1, "true" => true, ISO 时间 => 2006-01-02 15:04:05)
+// 原生表达式、null/notnull/match 条件及无法转换的数值保持不变, in 条件逐项转换
+func (mod *Model) fliterWhereValue(col interface{}, op string, value interface{}) interface{} {
+	name, ok := col.(string)
+	if !ok {
+		return value
+	}
+
+	column, has := mod.Columns[name]
+	if !has {
+		return value
+	}
+
+	switch op {
+	case "null", "notnull", "match", "ilike", "imatch", "json_contains", "date_between":
+		return value
+	case "in":
+		values := []interface{}{}
+		switch items := value.(type) {
+		case string:
+			for _, item := range strings.Split(items, ",") {
+				values = append(values, column.coerce(item))
+			}
+		case []string:
+			for _, item := range items {
+				values = append(values, column.coerce(item))
+			}
+		case []interface{}:
+			for _, item := range items {
+				values = append(values, column.coerce(item))
+			}
+		default:
+			return value
+		}
+		return values
+	}
+	return column.coerce(value)
+}
+
+// dateBetween 日期范围查询条件的起止数值, value 为 "开始日期,结束日期" 或 [开始日期, 结束日期] (格式 2006-01-02, 为空时不限制)
+// 日期按模型时区 (option.timezone, 默认 TimeZone, 未设置时为本地时区) 计算, 转换为数据库时区 (本地时区) 的 [开始日期 00:00:00, 结束日期次日 00:00:00)
+// 即包含结束日期全天; date 类型字段不转换时区
+func (mod *Model) dateBetween(col interface{}, value interface{}) (start string, end string, err error) {
+	dates := []string{}
+	switch v := value.(type) {
+	case string:
+		dates = strings.Split(v, ",")
+	case []string:
+		dates = v
+	case []interface{}:
+		for _, item := range v {
+			if item == nil {
+				dates = append(dates, "")
+				continue
+			}
+			dates = append(dates, fmt.Sprintf("%v", item))
+		}
+	}
+	if len(dates) != 2 {
+		return "", "", fmt.Errorf("日期范围 %v 格式错误, 应为 开始日期,结束日期", value)
+	}
+
+	layout, loc := "2006-01-02 15:04:05", TimeZone
+	if name, ok := col.(string); ok {
+		if column, has := mod.Columns[name]; has {
+			if strings.ToLower(column.Type) == "date" {
+				layout, loc = "2006-01-02", time.Local
+			} else if _, tz := column.timeFormat(); tz != nil {
+				loc = tz
+			}
+		}
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	bounds := []string{"", ""}
+	for i, date := range dates {
+		date = strings.TrimSpace(date)
+		if date == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return "", "", fmt.Errorf("日期 %s 格式错误, 应为 2006-01-02", date)
+		}
+		if i == 1 {
+			t = t.AddDate(0, 0, 1)
+		}
+		bounds[i] = t.In(time.Local).Format(layout)
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// jsonContains JSON 数组字段包含查询条件 (数组数值需全部包含), 字段须定义为 JSON 类型
+// MySQL: JSON_CONTAINS(field, '["vip"]'), PostgreSQL: field::jsonb @> '["vip"]', SQLite: json_each
+func (mod *Model) jsonContains(col interface{}, field interface{}, value interface{}) (string, []interface{}) {
+	name, _ := col.(string)
+	column, has := mod.Columns[name]
+	typ := ""
+	if has {
+		typ = strings.ToLower(column.Type)
+	}
+	if typ != "json" && typ != "jsonb" {
+		exception.New("字段 %v 不是 JSON 字段, 不支持 json_contains 查询", 400, col).Throw()
+	}
+
+	values := []interface{}{}
+	switch items := value.(type) {
+	case []interface{}:
+		values = items
+	case []string:
+		for _, item := range items {
+			values = append(values, item)
+		}
+	default:
+		values = append(values, value)
+	}
+
+	quoted := mod.quote(fmt.Sprintf("%v", field))
+	switch mod.Driver {
+	case "mysql", "postgres":
+		data, err := jsoniter.MarshalToString(values)
+		if err != nil {
+			exception.Err(err, 400).Throw()
+		}
+		if mod.Driver == "mysql" {
+			return fmt.Sprintf("JSON_CONTAINS(%s, ?)", quoted), []interface{}{data}
+		}
+		return fmt.Sprintf("%s::jsonb @> ?::jsonb", quoted), []interface{}{data}
+	}
+
+	conds := []string{}
+	for range values {
+		conds = append(conds, fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value = ?)", quoted))
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", values
+}
+
+// ilike 不区分大小写的模糊查询条件 (imatch 匹配包含 value 的数值), 不受字段排序规则影响
+// PostgreSQL: field ILIKE ?; MySQL, SQLite: LOWER(field) LIKE LOWER(?)
+// 注意: LOWER(field) 无法使用字段上的普通索引, 大数据表可使用不区分大小写的排序规则 (如 utf8mb4_general_ci) 配合 like 查询, 或建立 LOWER(field) 函数索引
+func (mod *Model) ilike(field interface{}, op string, value interface{}) (string, []interface{}) {
+	pattern := fmt.Sprintf("%v", value)
+	if op == "imatch" {
+		pattern = "%" + pattern + "%"
+	}
+
+	quoted := ""
+	if expr, ok := field.(dbal.Expression); ok {
+		quoted = expr.GetValue()
+	} else {
+		quoted = mod.quote(fmt.Sprintf("%v", field))
+	}
+
+	if mod.Driver == "postgres" {
+		return fmt.Sprintf("%s ILIKE ?", quoted), []interface{}{pattern}
+	}
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", quoted), []interface{}{pattern}
+}
+
+// quote 按数据库驱动转义标识符 (MySQL 使用反引号, 其他使用双引号), 如 user.key => `user`.`key`
+func (mod *Model) quote(identifier string) string {
+	return quoteIdentifier(mod.Driver, identifier)
+}
+
+// quoteIdentifier 按数据库驱动转义标识符, 以 . 分隔的各部分分别转义
+func quoteIdentifier(driver string, identifier string) string {
+	mark := `"`
+	if driver == "mysql" {
+		mark = "`"
+	}
+
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		parts[i] = mark + strings.ReplaceAll(part, mark, mark+mark) + mark
+	}
+	return strings.Join(parts, ".")
+}
+
 // FliterOut 输出前过滤解码
 func (mod *Model) FliterOut(row maps.MapStrAny) {
 	for name, value := range row {
@@ -130,3 +487,12 @@ func (mod *Model) FliterOut(row maps.MapStrAny) {
 		column.FliterOut(value, row)
 	}
 }
+
+// copyRow 复制输入数据 (浅复制)
+func copyRow(row maps.MapStrAny) maps.MapStrAny {
+	res := maps.MapStrAny{}
+	for name, value := range row {
+		res[name] = value
+	}
+	return res
+}
@@ -1,16 +1,21 @@
 package gou
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/yaoapp/gou/helper"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/kun/str"
 	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
 )
 
 // Models 已载入模型
@@ -22,6 +27,46 @@ func SetModelLogger(output io.Writer, level log.Level) {
 	log.SetOutput(output)
 }
 
+// tablePrefix 数据表前缀
+var tablePrefix string
+
+// SetTablePrefix 设定数据表前缀, 查询、写入和数据迁移使用 前缀+数据表名称 (模型描述文件无需修改)
+// 查询时数据表别名仍为模型描述中的数据表名称, 条件中 数据表名称.字段 的写法保持可用
+func SetTablePrefix(prefix string) {
+	tablePrefix = prefix
+}
+
+// TableName 数据表名称 (含数据表前缀)
+func (mod *Model) TableName() string {
+	return tablePrefix + mod.MetaData.Table.Name
+}
+
+// slowQueryThreshold 慢查询阈值, 为 0 时不记录慢查询
+var slowQueryThreshold time.Duration
+
+// SetSlowQueryThreshold 设定慢查询阈值, 执行时间超过阈值的模型查询以 WARN 级别记录 SQL、绑定参数和耗时, 设为 0 关闭
+func SetSlowQueryThreshold(d time.Duration) {
+	slowQueryThreshold = d
+}
+
+// slowQuery 查询执行时间超过阈值时记录慢查询日志
+func slowQuery(name string, start time.Time, qb query.Query) {
+	if slowQueryThreshold <= 0 {
+		return
+	}
+
+	duration := time.Since(start)
+	if duration < slowQueryThreshold {
+		return
+	}
+
+	log.With(log.F{
+		"sql":      qb.ToSQL(),
+		"bindings": qb.GetBindings(),
+		"duration": duration.String()}).
+		Warn("%s 慢查询 (%s)", name, duration)
+}
+
 // LoadModelReturn 加载数据模型
 func LoadModelReturn(source string, name string) (model *Model, err error) {
 	defer func() { err = exception.Catch(recover()) }()
@@ -31,9 +76,19 @@ func LoadModelReturn(source string, name string) (model *Model, err error) {
 
 // LoadModel 载入数据模型
 func LoadModel(source string, name string) *Model {
+	mod := parseModel(source, name)
+	Models[name] = mod
+	clearQueryPlans()
+	return mod
+}
+
+// parseModel 解析数据模型描述
+func parseModel(source string, name string) *Model {
 	var input io.Reader = nil
+	format := ""
 	if strings.HasPrefix(source, "file://") {
 		filename := strings.TrimPrefix(source, "file://")
+		format = helper.FileFormat(filename)
 		file, err := os.Open(filename)
 		if err != nil {
 			exception.Err(err, 400).Throw()
@@ -45,7 +100,11 @@ func LoadModel(source string, name string) *Model {
 	}
 
 	metadata := MetaData{}
-	err := helper.UnmarshalFile(input, &metadata)
+	content, err := helper.ReadFile(input)
+	if err != nil {
+		exception.Err(err, 400).Throw()
+	}
+	err = helper.UnmarshalFileFormat(bytes.NewReader(helper.ExpandEnv(content)), format, &metadata)
 	if err != nil {
 		exception.Err(err, 400).Throw()
 	}
@@ -53,7 +112,7 @@ func LoadModel(source string, name string) *Model {
 	mod := &Model{
 		Name:     name,
 		Source:   source,
-		MetaData: metadata,
+		MetaData: metadata.inherit(name),
 	}
 
 	// 解析常用数值
@@ -64,17 +123,13 @@ func LoadModel(source string, name string) *Model {
 
 	// 补充字段(软删除)
 	if mod.MetaData.Option.SoftDeletes {
-		mod.MetaData.Columns = append(mod.MetaData.Columns, Column{
-			Label:    "删除标记",
-			Name:     "deleted_at",
-			Type:     "timestamp",
-			Comment:  "删除标记",
-			Nullable: true,
-		})
+		if column := mod.softDelete().column(); column != nil && !mod.MetaData.hasColumn(column.Name) {
+			mod.MetaData.Columns = append(mod.MetaData.Columns, *column)
+		}
 	}
 
 	// 补充时间戳(软删除)
-	if mod.MetaData.Option.Timestamps {
+	if mod.MetaData.Option.Timestamps && !mod.MetaData.hasColumn("created_at") {
 		mod.MetaData.Columns = append(mod.MetaData.Columns,
 			Column{
 				Label:    "创建时间",
@@ -97,11 +152,11 @@ func LoadModel(source string, name string) *Model {
 		mod.MetaData.Columns[i].model = mod // 链接所属模型
 		columns[column.Name] = &mod.MetaData.Columns[i]
 		columnNames = append(columnNames, column.Name)
-		if strings.ToLower(column.Type) == "id" {
+		if strings.ToLower(column.Type) == "id" || column.Primary {
 			PrimaryKey = column.Name
 		}
-		// 唯一字段
-		if column.Unique {
+		// 唯一字段 (范围唯一约束不包含已软删除的数据时, 软删除同样释放字段数值)
+		if column.Unique || (column.UniqueScope != nil && column.UniqueScope.IgnoreDeleted) {
 			uniqueColumns = append(uniqueColumns, columns[column.Name])
 		}
 	}
@@ -124,20 +179,58 @@ func LoadModel(source string, name string) *Model {
 	mod.UniqueColumns = uniqueColumns
 	mod.Driver = capsule.Schema().MustGetConnection().Config.Driver
 
-	Models[name] = mod
+	// 默认排序
+	if _, err := mod.defaultOrders(); err != nil {
+		exception.Err(err, 400).Throw()
+	}
+
+	// 数据变更通知
+	if err := mod.webhooks(); err != nil {
+		exception.Err(err, 400).Throw()
+	}
 	return mod
 }
 
-// Reload 更新模型
+// Reload 重新读取模型描述, 原地更新当前模型, 已持有的 *Model 引用保持有效
+// 替换: MetaData, Columns, ColumnNames, PrimaryKey, PrimaryKeys, UniqueColumns, Driver
+// 保留: Name, Source; 查询范围 (Scope, GlobalScope) 与事件订阅 (Subscribe) 按模型名称注册, 重新加载后继续生效
 func (mod *Model) Reload() *Model {
-	mod = LoadModel(mod.Source, mod.Name)
+	new := parseModel(mod.Source, mod.Name)
+	mod.MetaData = new.MetaData
+	mod.Columns = new.Columns
+	mod.ColumnNames = new.ColumnNames
+	mod.PrimaryKey = new.PrimaryKey
+	mod.PrimaryKeys = new.PrimaryKeys
+	mod.UniqueColumns = new.UniqueColumns
+	mod.Driver = new.Driver
+	for i := range mod.MetaData.Columns {
+		mod.MetaData.Columns[i].model = mod // 链接所属模型
+	}
+
+	Models[mod.Name] = mod
+	clearQueryPlans()
 	return mod
 }
 
 // Migrate 数据迁移
 func (mod *Model) Migrate(force bool) {
-	table := mod.MetaData.Table.Name
+	table := mod.TableName()
 	schema := capsule.Schema()
+
+	// 审计日志
+	if mod.MetaData.Option.Audit {
+		err := AuditMigrate()
+		if err != nil {
+			exception.Err(err, 500).Throw()
+		}
+	}
+
+	// 数据库视图
+	if mod.MetaData.View {
+		mod.SchemaViewCreate()
+		return
+	}
+
 	if force {
 		schema.DropTableIfExists(table)
 	}
@@ -162,8 +255,76 @@ func Select(name string) *Model {
 	return mod
 }
 
+// ListModels 已加载模型名称清单 (按名称排序)
+func ListModels() []string {
+	names := []string{}
+	for name := range Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Describe 模型描述信息 (字段、关联关系、索引、主键)
+func (mod *Model) Describe() ModelInfo {
+	info := ModelInfo{
+		Name:       mod.Name,
+		Label:      mod.MetaData.Name,
+		Table:      mod.TableName(),
+		Comment:    mod.MetaData.Table.Comment,
+		PrimaryKey: mod.PrimaryKey,
+		Columns:    []ColumnInfo{},
+		Relations:  map[string]Relation{},
+		Indexes:    append([]Index{}, mod.MetaData.Indexes...),
+		Option:     mod.MetaData.Option,
+	}
+
+	for _, column := range mod.MetaData.Columns {
+		info.Columns = append(info.Columns, ColumnInfo{
+			Name:      column.Name,
+			Label:     column.Label,
+			Type:      column.Type,
+			Comment:   column.Comment,
+			Length:    column.Length,
+			Precision: column.Precision,
+			Scale:     column.Scale,
+			Nullable:  column.Nullable,
+			Option:    column.Option,
+			Default:   column.Default,
+			Index:     column.Index,
+			Unique:    column.Unique,
+			Primary:   column.Primary || column.Name == mod.PrimaryKey,
+			Hidden:    column.Hidden,
+			Crypt:     column.Crypt != "",
+		})
+	}
+
+	for name, rel := range mod.MetaData.Relations {
+		rel.Name = name
+		info.Relations[name] = rel
+	}
+	return info
+}
+
+// VisibleColumnNames 默认查询字段清单 (不含隐藏字段)
+func (mod *Model) VisibleColumnNames() []interface{} {
+	names := []interface{}{}
+	for _, name := range mod.ColumnNames {
+		if column, has := mod.Columns[name.(string)]; has && column.Hidden {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
 // Validate 数值校验
-func (mod *Model) Validate(row maps.MapStrAny) []ValidateResponse {
+func (mod *Model) Validate(row maps.MapStrAny, locale ...string) []ValidateResponse {
+	lang := LocaleOf(mod.ctx)
+	if len(locale) > 0 {
+		lang = locale[0]
+	}
+
 	res := []ValidateResponse{}
 	for name, value := range row {
 		column, has := mod.Columns[name]
@@ -176,7 +337,7 @@ func (mod *Model) Validate(row maps.MapStrAny) []ValidateResponse {
 			continue
 		}
 
-		success, messages := column.Validate(value, row)
+		success, messages := column.Validate(value, row, lang)
 		if !success {
 			res = append(res, ValidateResponse{
 				Column:   column.Name,
@@ -186,3 +347,37 @@ func (mod *Model) Validate(row maps.MapStrAny) []ValidateResponse {
 	}
 	return res
 }
+
+// ValidateRequired 创建数据时的必填字段校验: 不允许为空 (nullable: false) 且没有默认值的字段须提供非 null 数值
+// 自增主键和自动生成 (generate) 的字段除外; 更新数据允许只提供部分字段, 不做此校验
+func (mod *Model) ValidateRequired(row maps.MapStrAny, locale ...string) []ValidateResponse {
+	lang := LocaleOf(mod.ctx)
+	if len(locale) > 0 {
+		lang = locale[0]
+	}
+	if lang == "" {
+		lang = defaultLocale
+	}
+
+	res := []ValidateResponse{}
+	for _, column := range mod.MetaData.Columns {
+		if !column.required() || row.Get(column.Name) != nil {
+			continue
+		}
+		if column.Name == mod.PrimaryKey { // 主键生成器填充主键
+			if fn, _ := mod.idGenerator(); fn != nil {
+				continue
+			}
+		}
+		label := column.Label
+		if label == "" {
+			label = column.Name
+		}
+		message, _ := translate(lang, "validation.required", true)
+		res = append(res, ValidateResponse{
+			Column:   column.Name,
+			Messages: []string{str.Bind(message, map[string]interface{}{"label": label, "name": column.Name})},
+		})
+	}
+	return res
+}
@@ -0,0 +1,136 @@
+package gou
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/maps"
+)
+
+// IDGenerators 主键生成器, 主键字段设置 "generate": "uuid" 等时按名称选用 (不区分大小写)
+var IDGenerators = map[string]func() interface{}{
+	"uuid": func() interface{} { return NewUUID() },
+}
+
+var idGenerators = map[string]func() interface{}{}
+var idGeneratorsLock sync.RWMutex
+
+// SetIDGenerator 设置模型主键生成器 (优先于主键字段的 generate 设置), fn 为 nil 时移除
+// 创建数据未提供主键时, 写入前调用生成器填充主键, Create 返回生成的主键
+func SetIDGenerator(mod string, fn func() interface{}) {
+	idGeneratorsLock.Lock()
+	defer idGeneratorsLock.Unlock()
+	if fn == nil {
+		delete(idGenerators, mod)
+		return
+	}
+	idGenerators[mod] = fn
+}
+
+// NewUUID 生成 UUID (版本 4)
+func NewUUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// idGenerator 模型主键生成器, 自增主键返回 nil
+func (mod *Model) idGenerator() (func() interface{}, error) {
+	idGeneratorsLock.RLock()
+	fn, has := idGenerators[mod.Name]
+	idGeneratorsLock.RUnlock()
+	if has {
+		return fn, nil
+	}
+
+	column, has := mod.Columns[mod.PrimaryKey]
+	if !has {
+		return nil, nil
+	}
+	name := strings.ToLower(column.Generate)
+	if name == "" || name == "increment" {
+		return nil, nil
+	}
+	fn, has = IDGenerators[name]
+	if !has {
+		return nil, fmt.Errorf("模型 %s 主键生成器 %s 不存在", mod.Name, column.Generate)
+	}
+	return fn, nil
+}
+
+// insertGetID 写入单条数据, 返回主键
+// 使用主键生成器时, 未提供主键则先生成主键, 按普通 INSERT 写入, 不读取 LastInsertId 或 RETURNING;
+// 自增主键仍使用 InsertGetID (PostgreSQL: INSERT ... RETURNING 主键)
+func (mod *Model) insertGetID(row maps.MapStrAny) (interface{}, error) {
+	fn, err := mod.idGenerator()
+	if err != nil {
+		return nil, err
+	}
+
+	if fn == nil {
+		id, err := mod.newQuery().
+			Table(mod.TableName()).
+			InsertGetID(row, mod.PrimaryKey)
+		return int(id), err
+	}
+
+	if !row.Has(mod.PrimaryKey) || row.Get(mod.PrimaryKey) == nil {
+		row.Set(mod.PrimaryKey, fn())
+	}
+	id := row.Get(mod.PrimaryKey)
+	err = mod.newQuery().Table(mod.TableName()).Insert(row)
+	return id, err
+}
+
+// intID 主键转换为整数, 非数字主键 (如 UUID) 返回 0
+func intID(id interface{}) int {
+	switch v := id.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case string:
+		if v == "" || strings.Trim(v, "0123456789") != "" {
+			return 0
+		}
+	}
+	return any.Of(id).CInt()
+}
+
+// generateIDs 批量写入时填充主键 (未提供主键字段时追加主键字段), 返回写入字段清单
+func (mod *Model) generateIDs(columns []string, rows [][]interface{}) ([]string, error) {
+	fn, err := mod.idGenerator()
+	if err != nil || fn == nil {
+		return columns, err
+	}
+
+	index := -1
+	for i, name := range columns {
+		if name == mod.PrimaryKey {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		columns = append(append([]string{}, columns...), mod.PrimaryKey)
+		for i := range rows {
+			rows[i] = append(rows[i], fn())
+		}
+		return columns, nil
+	}
+	for i := range rows {
+		if rows[i][index] == nil {
+			rows[i][index] = fn()
+		}
+	}
+	return columns, nil
+}
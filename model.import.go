@@ -0,0 +1,173 @@
+package gou
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/maps"
+)
+
+// ImportChunkSize 导入数据时每批写入的记录数量
+var ImportChunkSize = 500
+
+// RowError 行数据错误
+type RowError struct {
+	Line     int      `json:"line"`             // 出错行 (CSV 从 1 开始, 含表头)
+	Column   string   `json:"column,omitempty"` // 出错字段
+	Messages []string `json:"messages"`         // 错误描述
+}
+
+// ImportCSV 读取 CSV 并在事务中分批写入, 返回成功导入数量和出错行信息
+// mapping 为 模型字段 => CSV 表头 映射表 (与 Import.Mapping 一致), 为空时按表头同名字段导入
+func (mod *Model) ImportCSV(r io.Reader, mapping map[string]string) (int, []RowError) {
+	errs := []RowError{}
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, append(errs, RowError{Line: 1, Messages: []string{err.Error()}})
+	}
+
+	// 表头与模型字段对应关系
+	columns := []string{}
+	indexes := []int{}
+	for i, name := range header {
+		column := mod.csvColumn(strings.TrimSpace(name), mapping)
+		if column == "" {
+			continue
+		}
+		columns = append(columns, column)
+		indexes = append(indexes, i)
+	}
+
+	if len(columns) == 0 {
+		return 0, append(errs, RowError{Line: 1, Messages: []string{"CSV表头与模型字段不匹配"}})
+	}
+
+	// 读取并校验数据
+	line := 1
+	lines := []int{}
+	rows := [][]interface{}{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+
+		if err != nil {
+			errs = append(errs, RowError{Line: line, Messages: []string{err.Error()}})
+			if _, ok := err.(*csv.ParseError); ok {
+				continue
+			}
+			break
+		}
+
+		row := maps.MapStrAny{}
+		for i, column := range columns {
+			value := ""
+			if indexes[i] < len(record) {
+				value = record[indexes[i]]
+			}
+			row[column] = castCSVValue(mod.Columns[column], value)
+		}
+
+		res := mod.Validate(row)
+		if len(res) > 0 {
+			for _, v := range res {
+				errs = append(errs, RowError{Line: line, Column: v.Column, Messages: v.Messages})
+			}
+			continue
+		}
+
+		values := []interface{}{}
+		for _, column := range columns {
+			values = append(values, row[column])
+		}
+		rows = append(rows, values)
+		lines = append(lines, line)
+	}
+
+	if len(rows) == 0 {
+		return 0, errs
+	}
+
+	// 分批写入
+	err = transaction(func(tx *Tx) error {
+		txmod := mod.inTx(tx)
+		for start := 0; start < len(rows); start += ImportChunkSize {
+			end := start + ImportChunkSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+
+			err := txmod.Insert(append([]string{}, columns...), rows[start:end])
+			if err != nil {
+				return fmt.Errorf("第%d行至第%d行写入失败: %s", lines[start], lines[end-1], err.Error())
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, append(errs, RowError{Line: 0, Messages: []string{err.Error()}})
+	}
+
+	return len(rows), errs
+}
+
+// csvColumn 读取 CSV 表头对应的模型字段
+func (mod *Model) csvColumn(name string, mapping map[string]string) string {
+	for column, field := range mapping {
+		if field == name {
+			if _, has := mod.Columns[column]; has {
+				return column
+			}
+		}
+	}
+
+	if len(mapping) > 0 {
+		return ""
+	}
+
+	if _, has := mod.Columns[name]; has {
+		return name
+	}
+	return ""
+}
+
+// castCSVValue 按字段类型转换 CSV 数值
+func castCSVValue(column *Column, value string) interface{} {
+	if value == "" && column.Nullable {
+		return nil
+	}
+
+	switch strings.ToLower(column.Type) {
+	case "tinyinteger", "unsignedtinyinteger", "smallinteger", "unsignedsmallinteger",
+		"integer", "unsignedinteger", "biginteger", "unsignedbiginteger",
+		"tinyincrements", "smallincrements", "increments", "bigincrements", "id":
+		if v, err := strconv.Atoi(value); err == nil {
+			return v
+		}
+	case "decimal", "unsigneddecimal", "float", "unsignedfloat", "double", "unsigneddouble":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	case "json", "jsonb":
+		var v interface{}
+		if err := jsoniter.UnmarshalFromString(value, &v); err == nil {
+			return v
+		}
+	}
+	return value
+}
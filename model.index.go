@@ -0,0 +1,245 @@
+package gou
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// IndexSuggestion 索引建议
+type IndexSuggestion struct {
+	Columns []string `json:"columns"`
+	Reasons []string `json:"reasons"` // 建议来源, 如 scope:active, relation:manu
+}
+
+// SuggestIndexes 按查询范围 (命名/全局) 的查询条件和排序, 以及关联关系的外键字段, 建议需要创建的索引
+// 已有索引 (主键, 唯一字段, index 字段, indexes) 的前缀字段与建议相同时不再建议; 仅含低基数字段 (enum, boolean) 的索引不建议
+func (mod *Model) SuggestIndexes() []IndexSuggestion {
+	suggestions := []IndexSuggestion{}
+	add := func(columns []string, reason string) {
+		if len(columns) == 0 || mod.lowCardinality(columns) || mod.indexed(columns) {
+			return
+		}
+		for i, suggestion := range suggestions {
+			if strings.Join(suggestion.Columns, ",") == strings.Join(columns, ",") {
+				suggestions[i].Reasons = append(suggestions[i].Reasons, reason)
+				return
+			}
+		}
+		suggestions = append(suggestions, IndexSuggestion{Columns: columns, Reasons: []string{reason}})
+	}
+
+	// 查询范围
+	scopesLock.RLock()
+	names := []string{}
+	for name := range scopes[mod.Name] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fns := map[string]func(param *QueryParam){}
+	for _, name := range names {
+		fns[name] = scopes[mod.Name][name]
+	}
+	globals := append([]globalScope{}, globalScopes[mod.Name]...)
+	scopesLock.RUnlock()
+
+	for _, name := range names {
+		param, ok := scopeParam(fns[name])
+		if ok {
+			add(mod.indexColumns(param), "scope:"+name)
+		}
+	}
+	for _, scope := range globals {
+		fn := scope.fn
+		param, ok := scopeParam(func(param *QueryParam) { fn(context.Background(), param) })
+		if ok {
+			add(mod.indexColumns(param), "global_scope:"+scope.name)
+		}
+	}
+
+	// 当前模型的外键字段
+	relations := []string{}
+	for name := range mod.MetaData.Relations {
+		relations = append(relations, name)
+	}
+	sort.Strings(relations)
+	for _, name := range relations {
+		rel := mod.MetaData.Relations[name]
+		switch rel.Type {
+		case RelHasOne, RelHasMany:
+			if rel.Foreign != mod.PrimaryKey && mod.hasColumns(rel.Foreign) {
+				add([]string{rel.Foreign}, "relation:"+name)
+			}
+		case RelMorphTo:
+			if mod.hasColumns(rel.Morph, rel.Foreign) {
+				add([]string{rel.Morph, rel.Foreign}, "relation:"+name)
+			}
+		}
+	}
+
+	// 其他模型关联当前模型的字段
+	models := []string{}
+	for name := range Models {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+	for _, name := range models {
+		for relName, rel := range Models[name].MetaData.Relations {
+			if rel.Model != mod.Name || rel.Key == mod.PrimaryKey {
+				continue
+			}
+			reason := fmt.Sprintf("relation:%s.%s", name, relName)
+			switch rel.Type {
+			case RelHasOne, RelHasMany:
+				if mod.hasColumns(rel.Key) {
+					add([]string{rel.Key}, reason)
+				}
+			case RelMorphMany:
+				if mod.hasColumns(rel.Morph, rel.Key) {
+					add([]string{rel.Key, rel.Morph}, reason)
+				}
+			}
+		}
+	}
+
+	return suggestions
+}
+
+// CreateIndexes 按索引建议创建索引 (索引名称为 {字段}_index), 并添加到模型索引定义
+func (mod *Model) CreateIndexes(suggestions []IndexSuggestion) error {
+	if len(suggestions) == 0 {
+		return nil
+	}
+
+	indexes := []Index{}
+	for _, suggestion := range suggestions {
+		indexes = append(indexes, Index{
+			Name:    strings.Join(suggestion.Columns, "_") + "_index",
+			Columns: suggestion.Columns,
+			Type:    "index",
+		})
+	}
+
+	err := capsule.Schema().AlterTable(mod.TableName(), func(table schema.Blueprint) {
+		for _, index := range indexes {
+			index.SetIndex(table)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	mod.MetaData.Indexes = append(mod.MetaData.Indexes, indexes...)
+	return nil
+}
+
+// MustCreateIndexes 按索引建议创建索引, 失败抛出异常
+func (mod *Model) MustCreateIndexes(suggestions []IndexSuggestion) {
+	err := mod.CreateIndexes(suggestions)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+}
+
+// scopeParam 执行查询范围, 读取查询范围添加的查询条件和排序 (查询范围执行失败时返回 false)
+func scopeParam(fn func(param *QueryParam)) (param QueryParam, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	fn(&param)
+	return param, true
+}
+
+// indexColumns 查询条件和排序对应的索引字段: 等值条件字段 (高基数在前), 第一个范围条件或排序字段
+// 含 orwhere 或关联字段条件时无法使用索引, 返回空
+func (mod *Model) indexColumns(param QueryParam) []string {
+	equals := []string{}
+	ranges := []string{}
+	for _, where := range param.Wheres {
+		name, ok := where.Column.(string)
+		if !ok || where.Rel != "" || where.Wheres != nil || strings.ToLower(where.Method) == "orwhere" {
+			return nil
+		}
+		if !mod.hasColumns(name) {
+			continue
+		}
+		switch strings.ToLower(where.OP) {
+		case "", "eq", "in", "null":
+			equals = append(equals, name)
+		case "gt", "ge", "lt", "le", "like":
+			ranges = append(ranges, name)
+		}
+	}
+
+	sort.SliceStable(equals, func(i, j int) bool {
+		return !mod.lowCardinality([]string{equals[i]}) && mod.lowCardinality([]string{equals[j]})
+	})
+
+	columns := append([]string{}, equals...)
+	if len(ranges) > 0 {
+		columns = append(columns, ranges[0])
+	} else if len(param.Orders) > 0 && param.Orders[0].Rel == "" && mod.hasColumns(param.Orders[0].Column) {
+		columns = append(columns, param.Orders[0].Column)
+	}
+
+	res := []string{}
+	exists := map[string]bool{}
+	for _, name := range columns {
+		if !exists[name] {
+			exists[name] = true
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// indexed 已有索引的前缀字段是否与 columns 相同
+func (mod *Model) indexed(columns []string) bool {
+	indexes := [][]string{mod.MetaData.Table.PrimaryKeys, {mod.PrimaryKey}}
+	for _, column := range mod.MetaData.Columns {
+		if column.Unique || column.Index || column.Primary {
+			indexes = append(indexes, []string{column.Name})
+		}
+	}
+	for _, index := range mod.MetaData.Indexes {
+		indexes = append(indexes, index.Columns)
+	}
+
+	for _, index := range indexes {
+		if len(index) < len(columns) {
+			continue
+		}
+		if strings.Join(index[:len(columns)], ",") == strings.Join(columns, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+// lowCardinality 字段是否均为低基数字段 (enum, boolean)
+func (mod *Model) lowCardinality(columns []string) bool {
+	for _, name := range columns {
+		switch strings.ToLower(mod.Columns[name].Type) {
+		case "enum", "boolean":
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// hasColumns 字段是否均为模型字段
+func (mod *Model) hasColumns(names ...string) bool {
+	for _, name := range names {
+		if _, has := mod.Columns[name]; !has || name == "" {
+			return false
+		}
+	}
+	return true
+}
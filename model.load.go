@@ -0,0 +1,206 @@
+package gou
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Load 为已读取的记录批量加载关联数据 (每个关联一次 WhereIn 查询, morphTo 按类型值分别查询), 结果写入 rows
+// 支持 hasOne, hasMany, morphMany, morphTo 关联; hasMany 的 Limit 按每条记录分别生效; hasOne 关联数据不存在时为 nil (设置 Default 时为默认值)
+func (mod *Model) Load(rows []maps.MapStr, withs map[string]With) error {
+	names := []string{}
+	for name := range withs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rel, has := mod.MetaData.Relations[name]
+		if !has {
+			return fmt.Errorf("模型 %s 未定义关联 %s", mod.Name, name)
+		}
+		rel.Name = name
+		with := withs[name]
+
+		var err error
+		switch rel.Type {
+		case RelHasOne, RelHasMany, RelMorphMany:
+			err = mod.loadHas(rows, rel, with)
+		case RelMorphTo:
+			err = mod.loadMorphTo(rows, rel, with)
+		default:
+			err = fmt.Errorf("关联 %s (%s) 不支持 Load", name, rel.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MustLoad 为已读取的记录批量加载关联数据, 失败抛出异常
+func (mod *Model) MustLoad(rows []maps.MapStr, withs map[string]With) {
+	err := mod.Load(rows, withs)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+}
+
+// loadHas 加载 hasOne, hasMany, morphMany 关联数据
+func (mod *Model) loadHas(rows []maps.MapStr, rel Relation, with With) error {
+	ids := []interface{}{}
+	exists := map[string]bool{}
+	for _, row := range rows {
+		if !row.Has(rel.Foreign) {
+			return fmt.Errorf("数据缺少关联字段 %s", rel.Foreign)
+		}
+		id := row.Get(rel.Foreign)
+		if id == nil || exists[fmt.Sprintf("%v", id)] {
+			continue
+		}
+		exists[fmt.Sprintf("%v", id)] = true
+		ids = append(ids, id)
+	}
+
+	param := with.Query
+	param.Wheres = append([]QueryWhere{}, param.Wheres...)
+	if rel.Type == RelMorphMany {
+		value := rel.MorphValue
+		if value == "" {
+			value = mod.Name
+		}
+		param.Wheres = append(param.Wheres, QueryWhere{Column: rel.Morph, Value: value})
+	}
+	param.Wheres = append(param.Wheres, QueryWhere{Column: rel.Key, OP: "in", Value: ids})
+	param.keepColumn(rel.Key)
+	limit := param.Limit
+	param.Limit = math.MaxInt32 // 按每条记录分别限制数量
+	if rel.Type == RelHasOne {
+		limit = 1
+	}
+
+	related := map[string][]maps.MapStr{}
+	if len(ids) > 0 {
+		items, err := mod.related(rel.Model).loadGet(param)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			id := fmt.Sprintf("%v", item.Get(rel.Key))
+			if limit > 0 && len(related[id]) >= limit {
+				continue
+			}
+			related[id] = append(related[id], item)
+		}
+		if with.HideKeys && rel.Type != RelHasOne {
+			for _, item := range items {
+				delete(item, rel.Key)
+				if rel.Type == RelMorphMany {
+					delete(item, rel.Morph)
+				}
+			}
+		}
+	}
+
+	for _, row := range rows {
+		items := related[fmt.Sprintf("%v", row.Get(rel.Foreign))]
+		if rel.Type != RelHasOne {
+			if items == nil {
+				items = []maps.MapStr{}
+			}
+			row[rel.Name] = items
+			continue
+		}
+		if len(items) > 0 {
+			row[rel.Name] = items[0]
+		} else if with.Default != nil {
+			row[rel.Name] = maps.MapStr(copyRow(with.Default))
+		} else {
+			row[rel.Name] = nil
+		}
+	}
+	return nil
+}
+
+// loadMorphTo 加载 morphTo 关联数据 (按类型值分组, 每个类型值一次查询)
+func (mod *Model) loadMorphTo(rows []maps.MapStr, rel Relation, with With) error {
+	key := rel.Key
+	if key == "" {
+		key = "id"
+	}
+
+	types := []string{}
+	groups := map[string][]interface{}{}
+	for _, row := range rows {
+		typ, id := row.Get(rel.Morph), row.Get(rel.Foreign)
+		if typ == nil || id == nil {
+			continue
+		}
+		name := fmt.Sprintf("%v", typ)
+		if _, has := groups[name]; !has {
+			types = append(types, name)
+		}
+		groups[name] = append(groups[name], id)
+	}
+
+	related := map[string]map[string]maps.MapStr{}
+	for _, typ := range types {
+		name := typ
+		if len(rel.Models) > 0 {
+			model, has := rel.Models[typ]
+			if !has {
+				continue
+			}
+			name = model
+		}
+		if _, has := Models[name]; !has {
+			continue
+		}
+
+		param := with.Query
+		param.Wheres = append(append([]QueryWhere{}, param.Wheres...), QueryWhere{Column: key, OP: "in", Value: groups[typ]})
+		param.keepColumn(key)
+		param.Limit = len(groups[typ])
+		items, err := mod.related(name).loadGet(param)
+		if err != nil {
+			return err
+		}
+		related[typ] = map[string]maps.MapStr{}
+		for _, item := range items {
+			related[typ][fmt.Sprintf("%v", item.Get(key))] = item
+		}
+	}
+
+	for _, row := range rows {
+		row[rel.Name] = nil
+		typ, id := row.Get(rel.Morph), row.Get(rel.Foreign)
+		if typ == nil || id == nil {
+			continue
+		}
+		if item, has := related[fmt.Sprintf("%v", typ)][fmt.Sprintf("%v", id)]; has {
+			row[rel.Name] = item
+		}
+	}
+	return nil
+}
+
+// related 关联模型 (绑定当前模型的事务和上下文)
+func (mod *Model) related(name string) *Model {
+	new := *Select(name)
+	new.tx, new.ctx, new.withoutScopes = mod.tx, mod.ctx, mod.withoutScopes
+	return &new
+}
+
+// loadGet 按条件查询, 返回查询错误
+func (mod *Model) loadGet(param QueryParam) (rows []maps.MapStr, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Catch(r)
+		}
+	}()
+	return mod.Get(param)
+}
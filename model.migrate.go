@@ -1,33 +1,309 @@
 package gou
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/xun/capsule"
 	"github.com/yaoapp/xun/dbal/schema"
 )
 
+// TableDiff 数据表结构与模型定义的差别
+type TableDiff struct {
+	Add    []string // 数据表中缺少的字段
+	Change []string // 类型、长度、可空等定义有变化的字段
+	Extra  []string // 模型未定义的字段 (升级时保留)
+}
+
+// Empty 数据表结构与模型定义一致
+func (diff TableDiff) Empty() bool {
+	return len(diff.Add) == 0 && len(diff.Change) == 0
+}
+
 // SchemaTableUpgrade 旧表数据结构差别对比后升级
+// 新增字段并修改有变化的字段, 不删除模型未定义的字段; SQLite 不支持修改字段, 通过重建数据表升级
 func (mod *Model) SchemaTableUpgrade() {
+	diff := mod.SchemaTableDiff()
+	if diff.Empty() {
+		return
+	}
+
+	if mod.Driver == "sqlite3" {
+		mod.sqlite3Rebuild(diff)
+		return
+	}
+
+	columns := map[string]Column{}
+	for _, column := range mod.MetaData.Columns {
+		columns[column.Name] = column
+	}
+
+	custom := []Column{}
+	err := capsule.Schema().AlterTable(mod.TableName(), func(table schema.Blueprint) {
+		for _, name := range diff.Add {
+			column, has := columns[name]
+			if !has {
+				mod.schemaBuiltinColumn(table, name)
+				continue
+			}
+			if column.DDL(mod.Driver) != "" {
+				custom = append(custom, column)
+				continue
+			}
+			col := column.SetType(table)
+			column.SetOption(col)
+		}
+
+		// 修改字段 (索引保持不变)
+		for _, name := range diff.Change {
+			column, has := columns[name]
+			if !has || column.DDL(mod.Driver) != "" {
+				continue
+			}
+			col := column.SetType(table)
+			if column.Comment != "" {
+				col.SetComment(column.Comment)
+			}
+			if column.Default != nil {
+				col.SetDefault(column.Default)
+			}
+			if column.Nullable {
+				col.Null()
+			}
+		}
+	})
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+
+	for _, column := range custom {
+		sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+			mod.quote(mod.TableName()),
+			mod.quote(column.Name),
+			column.DDL(mod.Driver),
+		)
+		_, err := capsule.Query().DB().Exec(sql)
+		if err != nil {
+			exception.Err(err, 500).Throw()
+		}
+	}
 }
 
 // SchemaTableDiff 旧表数据结构差别对比
-func (mod *Model) SchemaTableDiff() {
+// 按模型定义创建临时数据表, 与数据库中的数据表逐个字段对比类型、长度、精度、可空和主键
+func (mod *Model) SchemaTableDiff() TableDiff {
+	sch := capsule.Schema()
+	temp := "__diff_" + mod.TableName()
+	sch.MustDropTableIfExists(temp)
+	mod.schemaTableCreate(temp)
+	defer sch.MustDropTableIfExists(temp)
+
+	current := sch.MustGetTable(mod.TableName()).GetColumns()
+	defined := sch.MustGetTable(temp).GetColumns()
+
+	diff := TableDiff{Add: []string{}, Change: []string{}, Extra: []string{}}
+	for _, name := range sortedColumns(defined) {
+		column, has := current[name]
+		if !has {
+			diff.Add = append(diff.Add, name)
+			continue
+		}
+		if !sameColumn(column, defined[name]) {
+			diff.Change = append(diff.Change, name)
+		}
+	}
+
+	for _, name := range sortedColumns(current) {
+		if _, has := defined[name]; !has {
+			diff.Extra = append(diff.Extra, name)
+		}
+	}
+	return diff
+}
+
+// sameColumn 字段定义是否一致
+func sameColumn(a, b *schema.Column) bool {
+	return a.Type == b.Type &&
+		a.Nullable == b.Nullable &&
+		a.Column.Primary == b.Column.Primary &&
+		sameInt(a.Length, b.Length) &&
+		sameInt(a.Precision, b.Precision) &&
+		sameInt(a.Scale, b.Scale) &&
+		sameInt(a.DateTimePrecision, b.DateTimePrecision) &&
+		strings.Join(a.Option, ",") == strings.Join(b.Option, ",")
+}
+
+func sameInt(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sortedColumns 按名称排序的字段列表
+func sortedColumns(columns map[string]*schema.Column) []string {
+	names := []string{}
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaBuiltinColumn 添加模型选项生成的字段 (时间戳、软删除、追溯ID)
+func (mod *Model) schemaBuiltinColumn(table schema.Blueprint, name string) {
+	switch name {
+	case "created_at":
+		table.Timestamp(name).NotNull().SetDefaultRaw("NOW()").Index()
+	case "updated_at":
+		table.Timestamp(name).Null().Index()
+	case "deleted_at":
+		table.SoftDeletes()
+	case "__restore_data":
+		table.JSON(name).Null()
+	case "__tracking_id":
+		table.BigInteger(name).Index().Null()
+	}
+}
+
+// sqlite3Rebuild SQLite 重建数据表 (SQLite 不支持修改、删除字段)
+// 先将数据复制到按模型定义创建的临时表, 复制成功后重建数据表并复制回来; 模型未定义的字段按原类型保留
+func (mod *Model) sqlite3Rebuild(diff TableDiff) {
+	sch := capsule.Schema()
+	db := capsule.Query().DB()
+	table := mod.TableName()
+	temp := "__rebuild_" + table
+
+	types := map[string]string{}
+	rows, err := db.Query("SELECT name, type FROM pragma_table_info(?)", table)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+	for rows.Next() {
+		name, typ := "", ""
+		if err := rows.Scan(&name, &typ); err != nil {
+			rows.Close()
+			exception.Err(err, 500).Throw()
+		}
+		types[name] = typ
+	}
+	rows.Close()
+
+	create := func(name string) {
+		mod.schemaTableCreate(name)
+		for _, column := range diff.Extra {
+			sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", mod.quote(name), mod.quote(column), types[column])
+			if _, err := db.Exec(sql); err != nil {
+				exception.Err(err, 500).Throw()
+			}
+		}
+	}
+
+	// 复制原数据表的全部字段, 新增的字段使用默认值
+	columns := []string{}
+	for _, name := range sortedColumns(sch.MustGetTable(table).GetColumns()) {
+		columns = append(columns, mod.quote(name))
+	}
+	fields := strings.Join(columns, ", ")
+
+	sch.MustDropTableIfExists(temp)
+	create(temp)
+	_, err = db.Exec(fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", mod.quote(temp), fields, fields, mod.quote(table)))
+	if err != nil {
+		sch.MustDropTableIfExists(temp)
+		exception.New("数据表 %s 升级失败, 数据未修改: %s", 500, table, err).Throw()
+	}
+
+	sch.MustDropTable(table)
+	create(table)
+	_, err = db.Exec(fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", mod.quote(table), mod.quote(temp)))
+	if err != nil {
+		exception.New("数据表 %s 升级失败, 数据保存在 %s: %s", 500, table, temp, err).Throw()
+	}
+	sch.MustDropTable(temp)
+}
+
+// SchemaViewCreate 创建或替换数据库视图, 未设置视图定义 (Table.SQL) 时不做处理
+func (mod *Model) SchemaViewCreate() {
+	if mod.MetaData.Table.SQL == "" {
+		return
+	}
+
+	view := mod.quote(mod.TableName())
+	db := capsule.Query().DB()
+	_, err := db.Exec(fmt.Sprintf("DROP VIEW IF EXISTS %s", view))
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+
+	_, err = db.Exec(fmt.Sprintf("CREATE VIEW %s AS %s", view, mod.MetaData.Table.SQL))
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+}
+
+// readonly 视图模型不支持写入
+func (mod *Model) readonly() error {
+	if mod.MetaData.View {
+		return fmt.Errorf("模型 %s 为只读视图 (read-only view), 不支持写入", mod.Name)
+	}
+	return nil
 }
 
 // SchemaTableCreate 创建新的数据表
 func (mod *Model) SchemaTableCreate() {
+	mod.schemaTableCreate(mod.TableName())
+
+	// 添加默认值
+	for _, row := range mod.MetaData.Values {
+		mod.MustCreate(row)
+	}
+}
+
+// schemaTableCreate 按模型定义创建数据表结构 (字段、索引), 不写入默认值
+func (mod *Model) schemaTableCreate(name string) {
+
+	// 自定义类型字段, 数据表创建后添加
+	customs := map[string]Column{}
+	for _, column := range mod.MetaData.Columns {
+		if column.DDL(mod.Driver) != "" {
+			customs[column.Name] = column
+		}
+	}
+
+	// 引用自定义类型字段的索引, 自定义字段添加后创建
+	indexes := []Index{}
+	pending := []Index{}
+	for _, index := range append(append([]Index{}, mod.MetaData.Indexes...), mod.uniqueScopeIndexes()...) {
+		custom := false
+		for _, name := range index.Columns {
+			if _, has := customs[name]; has {
+				custom = true
+			}
+		}
+		if custom {
+			pending = append(pending, index)
+			continue
+		}
+		indexes = append(indexes, index)
+	}
 
 	sch := capsule.Schema()
-	err := sch.CreateTable(mod.MetaData.Table.Name, func(table schema.Blueprint) {
+	err := sch.CreateTable(name, func(table schema.Blueprint) {
 
 		// 创建字段
 		for _, column := range mod.MetaData.Columns {
+			if _, has := customs[column.Name]; has {
+				continue
+			}
 			col := column.SetType(table)
 			column.SetOption(col)
 		}
 
 		// 创建索引
-		for _, index := range mod.MetaData.Indexes {
+		for _, index := range indexes {
 			index.SetIndex(table)
 		}
 
@@ -38,7 +314,9 @@ func (mod *Model) SchemaTableCreate() {
 
 		// 软删除
 		if mod.MetaData.Option.SoftDeletes {
-			table.SoftDeletes()
+			if sd := mod.softDelete(); sd.Format == SoftDeleteTimestamp && sd.Column == "deleted_at" {
+				table.SoftDeletes()
+			}
 			table.JSON("__restore_data").Null()
 		}
 
@@ -53,8 +331,39 @@ func (mod *Model) SchemaTableCreate() {
 		exception.Err(err, 500).Throw()
 	}
 
-	// 添加默认值
-	for _, row := range mod.MetaData.Values {
-		mod.MustCreate(row)
+	// 添加自定义类型字段
+	for _, column := range mod.MetaData.Columns {
+		if _, has := customs[column.Name]; !has {
+			continue
+		}
+		sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+			mod.quote(name),
+			mod.quote(column.Name),
+			column.DDL(mod.Driver),
+		)
+		_, err := capsule.Query().DB().Exec(sql)
+		if err != nil {
+			exception.Err(err, 500).Throw()
+		}
+
+		if column.Unique {
+			pending = append(pending, Index{Name: column.Name + "_unique", Columns: []string{column.Name}, Type: "unique"})
+		} else if column.Index {
+			pending = append(pending, Index{Name: column.Name + "_index", Columns: []string{column.Name}, Type: "index"})
+		}
+	}
+
+	if len(pending) > 0 {
+		err = sch.AlterTable(name, func(table schema.Blueprint) {
+			for _, index := range pending {
+				index.SetIndex(table)
+			}
+		})
+		if err != nil {
+			exception.Err(err, 500).Throw()
+		}
 	}
+
+	// 全文索引
+	mod.createFulltextIndexes(name)
 }
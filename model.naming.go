@@ -0,0 +1,229 @@
+package gou
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/yaoapp/kun/maps"
+)
+
+// 字段命名风格
+const (
+	SnakeCase = "snake" // manu_id (默认, 与数据表字段一致)
+	CamelCase = "camel" // manuId
+)
+
+// namingStrategy 模型处理器输入输出数据的字段命名风格
+var namingStrategy = SnakeCase
+
+// SetNamingStrategy 设定模型处理器 (models.*) 输入输出数据的字段命名风格, 默认 SnakeCase
+// CamelCase: 输出数据 (含关联数据) 字段名称转换为 camelCase, 输入数据和查询条件同时接受 camelCase 字段名称
+// JSON 字段的内容保持不变; Go 接口 (Model.Find 等) 始终使用数据表字段名称
+func SetNamingStrategy(strategy string) {
+	namingStrategy = strategy
+}
+
+// namingOut 按命名风格转换输出数据的字段名称, 关联数据按关联模型递归转换
+func (mod *Model) namingOut(value interface{}) interface{} {
+	if namingStrategy != CamelCase {
+		return value
+	}
+
+	switch v := value.(type) {
+	case maps.MapStr:
+		return mod.namingOutRow(v)
+	case map[string]interface{}:
+		return mod.namingOutRow(v)
+	case []maps.MapStr:
+		res := []maps.MapStr{}
+		for _, row := range v {
+			res = append(res, mod.namingOutRow(row))
+		}
+		return res
+	case []interface{}:
+		res := []interface{}{}
+		for _, item := range v {
+			res = append(res, mod.namingOut(item))
+		}
+		return res
+	}
+	return value
+}
+
+// namingOutRow 转换单条数据的字段名称
+func (mod *Model) namingOutRow(row maps.MapStr) maps.MapStr {
+	res := maps.MapStr{}
+	for key, value := range row {
+		if mod != nil {
+			if rel, has := mod.MetaData.Relations[key]; has {
+				related, _ := Models[rel.Model] // morphTo 关联模型不确定, 仅转换字段名称
+				value = related.namingOut(value)
+			}
+		}
+		res[camelCase(key)] = value
+	}
+	return res
+}
+
+// namingOutPaginate 转换分页数据的字段名称
+func (mod *Model) namingOutPaginate(res maps.MapStr) maps.MapStr {
+	if namingStrategy != CamelCase {
+		return res
+	}
+
+	data := "data"
+	if name, has := PaginateFormat["data"]; has {
+		data = name
+	}
+
+	out := maps.MapStr{}
+	for key, value := range res {
+		if key == data {
+			value = mod.namingOut(value)
+		}
+		out[camelCase(key)] = value
+	}
+	return out
+}
+
+// namingIn 按命名风格转换输入数据的字段名称 (camelCase 字段名称转换为对应的数据表字段)
+func (mod *Model) namingIn(row maps.MapStrAny) maps.MapStrAny {
+	if namingStrategy != CamelCase {
+		return row
+	}
+
+	res := maps.MapStrAny{}
+	for key, value := range row {
+		res[mod.namingColumn(key)] = value
+	}
+	return res
+}
+
+// namingRows 转换多条输入数据的字段名称
+func (mod *Model) namingRows(rows []map[string]interface{}) []map[string]interface{} {
+	if namingStrategy != CamelCase {
+		return rows
+	}
+
+	res := []map[string]interface{}{}
+	for _, row := range rows {
+		res = append(res, mod.namingIn(row))
+	}
+	return res
+}
+
+// namingColumns 转换字段名称列表
+func (mod *Model) namingColumns(columns []string) []string {
+	if namingStrategy != CamelCase {
+		return columns
+	}
+
+	res := []string{}
+	for _, name := range columns {
+		res = append(res, mod.namingColumn(name))
+	}
+	return res
+}
+
+// namingParam 转换查询参数中的字段名称 (select, wheres, orders, 关联查询参数)
+func (mod *Model) namingParam(param *QueryParam) {
+	if namingStrategy != CamelCase {
+		return
+	}
+
+	for i, col := range param.Select {
+		if name, ok := col.(string); ok {
+			param.Select[i] = mod.namingColumn(name)
+		}
+	}
+
+	wheres := []QueryWhere{}
+	for _, where := range param.Wheres {
+		wheres = append(wheres, mod.namingWhere(where))
+	}
+	param.Wheres = wheres
+
+	for i, order := range param.Orders {
+		if order.Rel == "" {
+			param.Orders[i].Column = mod.namingColumn(order.Column)
+		}
+	}
+
+	for i, field := range param.Fields {
+		if !strings.Contains(field, ".") {
+			param.Fields[i] = mod.namingColumn(field)
+		}
+	}
+
+	for name, with := range param.Withs { // 关联查询参数按关联模型转换
+		rel, has := mod.MetaData.Relations[name]
+		if !has {
+			continue
+		}
+		related, has := Models[rel.Model]
+		if !has {
+			continue
+		}
+		related.namingParam(&with.Query)
+		param.Withs[name] = with
+	}
+}
+
+// namingWhere 转换查询条件中的字段名称 (含分组条件)
+func (mod *Model) namingWhere(where QueryWhere) QueryWhere {
+	if name, ok := where.Column.(string); ok && where.Rel == "" {
+		where.Column = mod.namingColumn(name)
+	}
+	if where.Wheres != nil {
+		wheres := []QueryWhere{}
+		for _, sub := range where.Wheres {
+			wheres = append(wheres, mod.namingWhere(sub))
+		}
+		where.Wheres = wheres
+	}
+	return where
+}
+
+// namingColumn camelCase 字段名称对应的数据表字段, 非模型字段保持不变
+func (mod *Model) namingColumn(name string) string {
+	if _, has := mod.Columns[name]; has {
+		return name
+	}
+	if snake := snakeCase(name); snake != name {
+		if _, has := mod.Columns[snake]; has {
+			return snake
+		}
+	}
+	return name
+}
+
+// camelCase manu_id => manuId (以 _ 开头的内部字段保持不变)
+func camelCase(name string) string {
+	if strings.HasPrefix(name, "_") || !strings.Contains(name, "_") {
+		return name
+	}
+
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// snakeCase manuId => manu_id
+func snakeCase(name string) string {
+	var builder strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				builder.WriteRune('_')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
@@ -0,0 +1,179 @@
+package gou
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/dbal"
+)
+
+// 树形路径 (Option.Path): 数据的上级主键路径, 如 1/2/4 (根节点为主键); 创建、修改上级 (Update, Save, Move) 时自动维护
+// 下级数据查询为 WHERE path LIKE '1/2/%', Descendants, Ancestors 设置树形路径时不再递归查询
+
+// Move 移动数据到新的上级 (parent 为 nil 时移动为根节点), 在事务中更新数据及全部下级数据的树形路径
+func (mod *Model) Move(id interface{}, parent interface{}) error {
+	if mod.MetaData.Option.Path == "" || mod.MetaData.Option.Parent == "" {
+		return fmt.Errorf("模型 %s 未设置树形路径 (option.path, option.parent)", mod.Name)
+	}
+
+	if mod.tx == nil {
+		return transaction(func(tx *Tx) error {
+			return mod.inTx(tx).Move(id, parent)
+		})
+	}
+
+	old, err := mod.pathOf(id)
+	if err != nil {
+		return err
+	}
+	path, err := mod.childPath(parent, id)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(path, old+"/") {
+		return fmt.Errorf("ID=%v的数据不能移动到自身或下级数据", id)
+	}
+
+	column := mod.MetaData.Option.Path
+	_, err = mod.newQuery().Table(mod.TableName()).
+		Where(mod.PrimaryKey, id).
+		Update(maps.MapStr{mod.MetaData.Option.Parent: parent, column: path})
+	if err != nil {
+		return err
+	}
+
+	// 下级数据: 新路径 + 原路径中当前数据之后的部分
+	prefix := "'" + strings.ReplaceAll(path, "'", "''") + "'"
+	rest := fmt.Sprintf("SUBSTR(%s, %d)", mod.quote(column), len(old)+1)
+	value := fmt.Sprintf("%s || %s", prefix, rest)
+	if mod.Driver == "mysql" {
+		value = fmt.Sprintf("CONCAT(%s, %s)", prefix, rest)
+	}
+	_, err = mod.newQuery().Table(mod.TableName()).
+		Where(column, "like", old+"/%").
+		Update(maps.MapStr{column: dbal.Raw(value)})
+	return err
+}
+
+// MustMove 移动数据到新的上级, 失败抛出异常
+func (mod *Model) MustMove(id interface{}, parent interface{}) {
+	err := mod.Move(id, parent)
+	if err != nil {
+		throwError(err)
+	}
+}
+
+// pathCreated 创建数据后写入树形路径
+func (mod *Model) pathCreated(id interface{}, row maps.MapStrAny) error {
+	if mod.MetaData.Option.Path == "" {
+		return nil
+	}
+	path, err := mod.childPath(row.Get(mod.MetaData.Option.Parent), id)
+	if err != nil {
+		return err
+	}
+	_, err = mod.newQuery().Table(mod.TableName()).
+		Where(mod.PrimaryKey, id).
+		Update(maps.MapStr{mod.MetaData.Option.Path: path})
+	return err
+}
+
+// pathUpdate 更新数据的上级字段时更新树形路径 (修改上级字段外的数据后调用 Move)
+func (mod *Model) pathUpdate(id interface{}, row maps.MapStrAny) error {
+	if mod.tx == nil {
+		return transaction(func(tx *Tx) error {
+			return mod.inTx(tx).pathUpdate(id, row)
+		})
+	}
+
+	parent := row.Get(mod.MetaData.Option.Parent)
+	row = copyRow(row)
+	row.Del(mod.MetaData.Option.Parent)
+	row.Del(mod.MetaData.Option.Path)
+	row.Del(mod.PrimaryKey)
+	if len(row) > 0 {
+		if err := mod.Update(id, row); err != nil {
+			return err
+		}
+	}
+	return mod.Move(id, parent)
+}
+
+// pathMaintained 写入数据含上级字段时是否需要维护树形路径
+func (mod *Model) pathMaintained(row maps.MapStrAny) bool {
+	return mod.MetaData.Option.Path != "" && mod.MetaData.Option.Parent != "" && row.Has(mod.MetaData.Option.Parent)
+}
+
+// childPath 上级数据下的树形路径 (上级路径/主键), parent 为 nil 时为主键
+func (mod *Model) childPath(parent interface{}, id interface{}) (string, error) {
+	if parent == nil {
+		return fmt.Sprintf("%v", id), nil
+	}
+	path, err := mod.pathOf(parent)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%v", path, id), nil
+}
+
+// pathOf 读取数据的树形路径
+func (mod *Model) pathOf(id interface{}) (string, error) {
+	row, err := mod.newQuery().Table(mod.TableName()).
+		Select(mod.MetaData.Option.Path).
+		Where(mod.PrimaryKey, id).
+		Limit(1).First()
+	if err != nil {
+		return "", err
+	}
+	if row.IsEmpty() {
+		return "", &ErrNotFound{Model: mod.Name, ID: id}
+	}
+	return fmt.Sprintf("%v", row.Get(mod.MetaData.Option.Path)), nil
+}
+
+// pathIDs 按树形路径读取上级或下级数据主键 (按层级排序, 同层级按主键排序)
+func (mod *Model) pathIDs(id interface{}, depth int, up bool) ([]interface{}, error) {
+	path, err := mod.pathOf(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []interface{}{}
+	if up {
+		parts := strings.Split(path, "/")
+		for i := len(parts) - 2; i >= 0 && len(ids) < depth; i-- {
+			ids = append(ids, parts[i])
+		}
+		return ids, nil
+	}
+
+	column := mod.MetaData.Option.Path
+	rows, err := mod.newQuery().Table(mod.TableName()).
+		Select(mod.PrimaryKey, column).
+		Where(column, "like", path+"/%").
+		OrderBy(mod.PrimaryKey).
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.Count(path, "/")
+	type node struct {
+		id    interface{}
+		depth int
+	}
+	nodes := []node{}
+	for _, row := range rows {
+		level := strings.Count(fmt.Sprintf("%v", row[column]), "/") - base
+		if level <= depth {
+			nodes = append(nodes, node{id: row[mod.PrimaryKey], depth: level})
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].depth < nodes[j].depth })
+	for _, node := range nodes {
+		ids = append(ids, node.id)
+	}
+	return ids, nil
+}
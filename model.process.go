@@ -24,6 +24,8 @@ var ModelHandlers = map[string]ProcessHandler{
 	"updatewhere":         processUpdateWhere,
 	"deletewhere":         processDeleteWhere,
 	"destroywhere":        processDestroyWhere,
+	"deletemany":          processDeleteMany,
+	"destroymany":         processDestroyMany,
 	"eachsave":            processEachSave,
 	"eachsaveafterdelete": processEachSaveAfterDelete,
 }
@@ -31,29 +33,31 @@ var ModelHandlers = map[string]ProcessHandler{
 // processFind 运行模型 MustFind
 func processFind(process *Process) interface{} {
 	process.ValidateArgNums(2)
-	mod := Select(process.Class)
+	mod := process.model()
 	params, ok := AnyToQueryParam(process.Args[1])
 	if !ok {
 		params = QueryParam{}
 	}
-	return mod.MustFind(process.Args[0], params)
+	mod.namingParam(&params)
+	return mod.namingOut(mod.MustFind(process.Args[0], params))
 }
 
 // processGet 运行模型 MustGet
 func processGet(process *Process) interface{} {
 	process.ValidateArgNums(1)
-	mod := Select(process.Class)
+	mod := process.model()
 	params, ok := AnyToQueryParam(process.Args[0])
 	if !ok {
 		exception.New("第1个查询参数错误 %v", 400, process.Args[0]).Throw()
 	}
-	return mod.MustGet(params)
+	mod.namingParam(&params)
+	return mod.namingOut(mod.MustGet(params))
 }
 
 // processPaginate 运行模型 MustPaginate
 func processPaginate(process *Process) interface{} {
 	process.ValidateArgNums(3)
-	mod := Select(process.Class)
+	mod := process.model()
 	params, ok := AnyToQueryParam(process.Args[0])
 	if !ok {
 		exception.New("第1个查询参数错误 %v", 400, process.Args[0]).Throw()
@@ -61,23 +65,24 @@ func processPaginate(process *Process) interface{} {
 
 	page := any.Of(process.Args[1]).CInt()
 	pagesize := any.Of(process.Args[2]).CInt()
-	return mod.MustPaginate(params, page, pagesize)
+	mod.namingParam(&params)
+	return mod.namingOutPaginate(mod.MustPaginate(params, page, pagesize))
 }
 
 // processCreate 运行模型 MustCreate
 func processCreate(process *Process) interface{} {
 	process.ValidateArgNums(1)
-	mod := Select(process.Class)
-	row := any.Of(process.Args[0]).Map().MapStrAny
+	mod := process.model()
+	row := mod.namingIn(any.Of(process.Args[0]).Map().MapStrAny)
 	return mod.MustCreate(row)
 }
 
 // processUpdate 运行模型 MustUpdate
 func processUpdate(process *Process) interface{} {
 	process.ValidateArgNums(2)
-	mod := Select(process.Class)
+	mod := process.model()
 	id := process.Args[0]
-	row := any.Of(process.Args[1]).Map().MapStrAny
+	row := mod.namingIn(any.Of(process.Args[1]).Map().MapStrAny)
 	mod.MustUpdate(id, row)
 	return nil
 }
@@ -85,15 +90,15 @@ func processUpdate(process *Process) interface{} {
 // processSave 运行模型 MustSave
 func processSave(process *Process) interface{} {
 	process.ValidateArgNums(1)
-	mod := Select(process.Class)
-	row := any.Of(process.Args[0]).Map().MapStrAny
+	mod := process.model()
+	row := mod.namingIn(any.Of(process.Args[0]).Map().MapStrAny)
 	return mod.MustSave(row)
 }
 
 // processDelete 运行模型 MustDelete
 func processDelete(process *Process) interface{} {
 	process.ValidateArgNums(1)
-	mod := Select(process.Class)
+	mod := process.model()
 	mod.MustDelete(process.Args[0])
 	return nil
 }
@@ -101,7 +106,7 @@ func processDelete(process *Process) interface{} {
 // processDestroy 运行模型 MustDestroy
 func processDestroy(process *Process) interface{} {
 	process.ValidateArgNums(1)
-	mod := Select(process.Class)
+	mod := process.model()
 	mod.MustDestroy(process.Args[0])
 	return nil
 }
@@ -109,7 +114,7 @@ func processDestroy(process *Process) interface{} {
 // processInsert 运行模型 MustInsert
 func processInsert(process *Process) interface{} {
 	process.ValidateArgNums(2)
-	mod := Select(process.Class)
+	mod := process.model()
 	var colums = []string{}
 	colums, ok := process.Args[0].([]string)
 	if !ok {
@@ -139,60 +144,77 @@ func processInsert(process *Process) interface{} {
 		}
 	}
 
-	mod.MustInsert(colums, rows)
+	mod.MustInsert(mod.namingColumns(colums), rows)
 	return nil
 }
 
 // processUpdateWhere 运行模型 MustUpdateWhere
 func processUpdateWhere(process *Process) interface{} {
 	process.ValidateArgNums(2)
-	mod := Select(process.Class)
+	mod := process.model()
 	params, ok := AnyToQueryParam(process.Args[0])
 	if !ok {
 		exception.New("第1个查询参数错误 %v", 400, process.Args[0]).Throw()
 	}
-	row := any.Of(process.Args[1]).Map().MapStrAny
+	row := mod.namingIn(any.Of(process.Args[1]).Map().MapStrAny)
+	mod.namingParam(&params)
 	return mod.MustUpdateWhere(params, row)
 }
 
 // processDeleteWhere 运行模型 MustDeleteWhere
 func processDeleteWhere(process *Process) interface{} {
 	process.ValidateArgNums(1)
-	mod := Select(process.Class)
+	mod := process.model()
 	params, ok := AnyToQueryParam(process.Args[0])
 	if !ok {
 		params = QueryParam{}
 	}
+	mod.namingParam(&params)
 	return mod.MustDeleteWhere(params)
 }
 
 // processDestroyWhere 运行模型 MustDestroyWhere
 func processDestroyWhere(process *Process) interface{} {
 	process.ValidateArgNums(1)
-	mod := Select(process.Class)
+	mod := process.model()
 	params, ok := AnyToQueryParam(process.Args[0])
 	if !ok {
 		params = QueryParam{}
 	}
+	mod.namingParam(&params)
 	return mod.MustDestroyWhere(params)
 }
 
+// processDeleteMany 运行模型 MustDeleteMany
+func processDeleteMany(process *Process) interface{} {
+	process.ValidateArgNums(1)
+	mod := process.model()
+	return mod.MustDeleteMany(process.argsIDs(0))
+}
+
+// processDestroyMany 运行模型 MustDestroyMany
+func processDestroyMany(process *Process) interface{} {
+	process.ValidateArgNums(1)
+	mod := process.model()
+	return mod.MustDestroyMany(process.argsIDs(0))
+}
+
 // processEachSave 运行模型 MustEachSave
 func processEachSave(process *Process) interface{} {
 	process.ValidateArgNums(1)
-	mod := Select(process.Class)
+	mod := process.model()
 	rows := process.ArgsRecords(0)
 	eachrow := map[string]interface{}{}
 	if process.NumOfArgsIs(2) {
 		eachrow = process.ArgsMap(1)
 	}
-	return mod.MustEachSave(rows, eachrow)
+	return mod.MustEachSave(mod.namingRows(rows), mod.namingIn(eachrow))
 }
 
 // processEachSaveAfterDelete 运行模型 MustDeleteWhere 后 MustEachSave
 func processEachSaveAfterDelete(process *Process) interface{} {
 	process.ValidateArgNums(2)
-	mod := Select(process.Class)
+	mod := process.model()
 	eachrow := map[string]interface{}{}
 	ids := []int{}
 	if v, ok := process.Args[0].([]int); ok {
@@ -209,12 +231,12 @@ func processEachSaveAfterDelete(process *Process) interface{} {
 	if len(ids) > 0 {
 		mod.MustDeleteWhere(QueryParam{Wheres: []QueryWhere{{Column: "id", OP: "in", Value: ids}}})
 	}
-	return mod.MustEachSave(rows, eachrow)
+	return mod.MustEachSave(mod.namingRows(rows), mod.namingIn(eachrow))
 }
 
 // processSelectOption 运行模型 MustGet
 func processSelectOption(process *Process) interface{} {
-	mod := Select(process.Class)
+	mod := process.model()
 	keyword := "%%"
 	if process.NumOfArgs() > 0 {
 		keyword = fmt.Sprintf("%%%s%%", process.ArgsString(0))
@@ -0,0 +1,124 @@
+package gou
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yaoapp/kun/exception"
+)
+
+// LinkModels 检查全部已载入模型的关联定义, 在全部模型载入后调用
+// 关联模型须已载入, 关联键 (key) 须为关联模型的字段, 外键 (foreign) 须为当前模型的字段; 含 "." 的字段 (引用查询别名) 不检查
+// hasOneThrough, hasManyThrough 逐级检查, 每级外键为上一级模型的字段; 树形结构上级字段和路径字段须存在
+func LinkModels() error {
+	names := []string{}
+	for name := range Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mod := Models[name]
+		if parent := mod.MetaData.Option.Parent; parent != "" && !mod.linkColumn(parent) {
+			return fmt.Errorf("模型 %s 上级字段 %s 不存在", name, parent)
+		}
+		if path := mod.MetaData.Option.Path; path != "" && (mod.MetaData.Option.Parent == "" || !mod.linkColumn(path)) {
+			return fmt.Errorf("模型 %s 树形路径字段 %s 不存在或未设置上级字段", name, path)
+		}
+		rels := []string{}
+		for rel := range mod.MetaData.Relations {
+			rels = append(rels, rel)
+		}
+		sort.Strings(rels)
+		for _, rel := range rels {
+			if err := mod.linkRelation(mod.MetaData.Relations[rel]); err != nil {
+				return fmt.Errorf("模型 %s 关联 %s: %s", name, rel, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MustLinkModels 检查全部已载入模型的关联定义, 失败抛出异常
+func MustLinkModels() {
+	err := LinkModels()
+	if err != nil {
+		exception.Err(err, 400).Throw()
+	}
+}
+
+// linkRelation 检查关联定义
+func (mod *Model) linkRelation(rel Relation) error {
+	switch rel.Type {
+	case "hasOne", "hasMany", "morphMany":
+		related, err := mod.linkHas(rel)
+		if err != nil {
+			return err
+		}
+		if rel.Type == "morphMany" && !related.linkColumn(rel.Morph) {
+			return fmt.Errorf("类型字段 %s 不是模型 %s 的字段", rel.Morph, rel.Model)
+		}
+		return nil
+
+	case "hasOneThrough", "hasManyThrough":
+		if len(rel.Links) == 0 {
+			return fmt.Errorf("%s 缺少 links", rel.Type)
+		}
+		prev := mod
+		for _, link := range rel.Links {
+			related, err := prev.linkHas(link)
+			if err != nil {
+				return err
+			}
+			prev = related
+		}
+		return nil
+
+	case "morphTo":
+		for _, column := range []string{rel.Morph, rel.Foreign} {
+			if !mod.linkColumn(column) {
+				return fmt.Errorf("字段 %s 不是模型 %s 的字段", column, mod.Name)
+			}
+		}
+		key := rel.Key
+		if key == "" {
+			key = "id"
+		}
+		for _, name := range rel.Models {
+			related, has := Models[name]
+			if !has {
+				return fmt.Errorf("引用的模型 %s 尚未载入", name)
+			}
+			if !related.linkColumn(key) {
+				return fmt.Errorf("关联键 %s 不是模型 %s 的字段", key, name)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("不支持的关联类型 %s", rel.Type)
+}
+
+// linkHas 检查 hasOne, hasMany 关联 (关联键为关联模型字段, 外键为当前模型字段), 返回关联模型
+func (mod *Model) linkHas(rel Relation) (*Model, error) {
+	related, has := Models[rel.Model]
+	if !has {
+		return nil, fmt.Errorf("引用的模型 %s 尚未载入", rel.Model)
+	}
+	if !related.linkColumn(rel.Key) {
+		return nil, fmt.Errorf("关联键 %s 不是模型 %s 的字段", rel.Key, rel.Model)
+	}
+	if !mod.linkColumn(rel.Foreign) {
+		return nil, fmt.Errorf("外键 %s 不是模型 %s 的字段", rel.Foreign, mod.Name)
+	}
+	return related, nil
+}
+
+// linkColumn 关联字段是否存在 (含 "." 的字段引用查询别名, 不检查)
+func (mod *Model) linkColumn(name string) bool {
+	if strings.Contains(name, ".") {
+		return true
+	}
+	_, has := mod.Columns[name]
+	return has
+}
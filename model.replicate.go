@@ -0,0 +1,153 @@
+package gou
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/dbal"
+)
+
+// Replicate 复制单条数据, 返回新创建数据ID
+// 复制数据库中的原始数值 (加密字段不重新加密), 忽略主键、唯一字段、自动生成字段、时间戳、软删除标记和树形路径; overrides 按 Create 校验和预处理后覆盖复制的数值
+// relations 为同时复制的 hasMany 关联 (关联数据的关联键指向新数据); 复制在同一事务中执行
+// 组合唯一索引的字段照常复制, 需在 overrides 中修改, 否则返回 ErrConflict
+func (mod *Model) Replicate(id interface{}, overrides maps.MapStr, relations ...string) (int, error) {
+
+	if err := mod.readonly(); err != nil {
+		return 0, err
+	}
+
+	if mod.tx == nil {
+		var newID int
+		err := transaction(func(tx *Tx) (err error) {
+			newID, err = mod.inTx(tx).Replicate(id, overrides, relations...)
+			return err
+		})
+		return newID, err
+	}
+
+	qb := mod.newQuery().Table(mod.TableName()).Where(mod.PrimaryKey, id)
+	if mod.MetaData.Option.SoftDeletes {
+		QueryParam{}.Where(mod.softDelete().notDeleted(), qb, mod)
+	}
+	res, err := qb.Limit(1).First()
+	if err != nil {
+		return 0, err
+	}
+	if res.IsEmpty() {
+		return 0, &ErrNotFound{Model: mod.Name, ID: id}
+	}
+	source := maps.MapStr(res)
+
+	row := maps.MapStrAny{}
+	for _, column := range mod.MetaData.Columns {
+		if mod.replicable(column) {
+			row[column.Name] = source.Get(column.Name)
+		}
+	}
+
+	if len(overrides) > 0 {
+		values := copyRow(overrides)
+		values.Del(mod.PrimaryKey)
+		if errs := mod.Validate(values); len(errs) > 0 {
+			return 0, &ErrValidation{Model: mod.Name, Responses: errs}
+		}
+		mod.FliterIn(values)
+		for name, value := range values {
+			row[name] = value
+		}
+	}
+
+	if mod.MetaData.Option.Timestamps {
+		row.Set("created_at", dbal.Raw("CURRENT_TIMESTAMP"))
+	}
+
+	start := time.Now()
+	newID, err := mod.insertGetID(row)
+	if err != nil {
+		return 0, mod.conflict(err)
+	}
+	mod.stat(start, 1, 0, 1)
+
+	if err := mod.pathCreated(newID, row); err != nil {
+		return 0, err
+	}
+	if err := mod.changed(EventCreate, newID, nil, row); err != nil {
+		return 0, err
+	}
+
+	for _, name := range relations {
+		if err := mod.replicateRelation(name, source, row, newID); err != nil {
+			return 0, err
+		}
+	}
+	return intID(newID), nil
+}
+
+// MustReplicate 复制单条数据, 返回新创建数据ID, 失败抛出异常
+func (mod *Model) MustReplicate(id interface{}, overrides maps.MapStr, relations ...string) int {
+	newID, err := mod.Replicate(id, overrides, relations...)
+	if err != nil {
+		throwError(err)
+	}
+	return newID
+}
+
+// replicateRelation 复制 hasMany 关联数据, 关联键设置为新数据的外键数值
+func (mod *Model) replicateRelation(name string, source maps.MapStr, row maps.MapStrAny, newID interface{}) error {
+	rel, has := mod.MetaData.Relations[name]
+	if !has {
+		return fmt.Errorf("模型 %s 关联 %s 不存在", mod.Name, name)
+	}
+	if rel.Type != "hasMany" {
+		return fmt.Errorf("模型 %s 关联 %s 类型为 %s, 仅支持复制 hasMany 关联", mod.Name, name, rel.Type)
+	}
+
+	foreign := row.Get(rel.Foreign)
+	if rel.Foreign == mod.PrimaryKey {
+		foreign = newID
+	}
+
+	related := Select(rel.Model).inTx(mod.tx)
+	qb := related.newQuery().Table(related.TableName()).Select(related.PrimaryKey).Where(rel.Key, source.Get(rel.Foreign))
+	if related.MetaData.Option.SoftDeletes {
+		QueryParam{}.Where(related.softDelete().notDeleted(), qb, related)
+	}
+	rows, err := qb.OrderBy(related.PrimaryKey).Get()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range rows {
+		_, err := related.Replicate(item.Get(related.PrimaryKey), maps.MapStr{rel.Key: foreign})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replicable 复制数据时是否复制字段数值
+func (mod *Model) replicable(column Column) bool {
+	name := column.Name
+	if name == mod.PrimaryKey || column.Unique || column.UniqueScope != nil || column.Generate != "" || strings.HasPrefix(name, "__") {
+		return false
+	}
+
+	if name == mod.MetaData.Option.Path {
+		return false
+	}
+	if mod.MetaData.Option.Timestamps && (name == "created_at" || name == "updated_at") {
+		return false
+	}
+
+	if mod.MetaData.Option.SoftDeletes {
+		sd := mod.softDelete()
+		if sd.Format != SoftDeleteStatus && name == sd.Column {
+			return false
+		}
+	}
+	return true
+}
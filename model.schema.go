@@ -0,0 +1,155 @@
+package gou
+
+import (
+	"strings"
+
+	"github.com/yaoapp/kun/any"
+)
+
+// JSONSchema 模型数据结构 (JSON Schema draft-07), 用于前端表单生成和提交前校验
+// 字段类型、可空、枚举、长度及校验规则 (min, max, enum, pattern, minLength, maxLength, email) 转换为约束
+// 隐藏字段 (hidden) 不输出, 密码字段标记为 writeOnly, 主键和自动维护的时间戳、软删除字段标记为 readOnly
+func (mod *Model) JSONSchema() map[string]interface{} {
+	readonly := map[string]bool{mod.PrimaryKey: true}
+	if mod.MetaData.Option.Timestamps {
+		readonly["created_at"] = true
+		readonly["updated_at"] = true
+	}
+	if mod.MetaData.Option.SoftDeletes {
+		readonly[mod.softDelete().Column] = true
+	}
+
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, column := range mod.MetaData.Columns {
+		if column.Hidden {
+			continue
+		}
+
+		schema := column.jsonSchema()
+		if readonly[column.Name] {
+			schema["readOnly"] = true
+		} else if !column.Nullable && column.Default == nil && column.DefaultRaw == "" {
+			required = append(required, column.Name)
+		}
+		properties[column.Name] = schema
+	}
+
+	schema := map[string]interface{}{
+		"$schema":    "http://json-schema.org/draft-07/schema#",
+		"$id":        mod.Name,
+		"title":      mod.MetaData.Name,
+		"type":       "object",
+		"properties": properties,
+	}
+	if mod.MetaData.Table.Comment != "" {
+		schema["description"] = mod.MetaData.Table.Comment
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// jsonSchema 字段数据结构 (JSON Schema)
+func (column Column) jsonSchema() map[string]interface{} {
+	schema := column.schemaType()
+	if column.Nullable {
+		schema["type"] = []interface{}{schema["type"], "null"}
+	}
+	if column.Label != "" {
+		schema["title"] = column.Label
+	}
+	if column.Comment != "" {
+		schema["description"] = column.Comment
+	}
+	if column.Default != nil {
+		schema["default"] = column.Default
+	}
+	if column.writeOnly() {
+		schema["writeOnly"] = true
+	}
+
+	// 校验规则
+	patterns := []interface{}{}
+	for _, v := range column.Validations {
+		if len(v.Args) == 0 && v.Method != "email" {
+			continue
+		}
+		switch v.Method {
+		case "min":
+			schema["minimum"] = any.Of(v.Args[0]).CFloat()
+		case "max":
+			schema["maximum"] = any.Of(v.Args[0]).CFloat()
+		case "enum":
+			schema["enum"] = v.Args
+		case "pattern":
+			patterns = append(patterns, map[string]interface{}{"pattern": v.Args[0]})
+		case "minLength":
+			schema["minLength"] = any.Of(v.Args[0]).CInt()
+		case "maxLength":
+			length := any.Of(v.Args[0]).CInt()
+			if max, has := schema["maxLength"].(int); !has || length < max {
+				schema["maxLength"] = length
+			}
+		case "email":
+			schema["format"] = "email"
+		}
+	}
+
+	if len(patterns) == 1 {
+		schema["pattern"] = patterns[0].(map[string]interface{})["pattern"]
+	} else if len(patterns) > 1 { // 多个正则需同时满足
+		schema["allOf"] = patterns
+	}
+	return schema
+}
+
+// writeOnly 密码字段 (哈希存储) 只写不读
+func (column Column) writeOnly() bool {
+	return strings.HasPrefix(strings.ToUpper(column.Crypt), "PASSWORD")
+}
+
+// schemaType 字段类型对应的数据结构 (type, format, enum, maxLength)
+func (column Column) schemaType() map[string]interface{} {
+	schema := map[string]interface{}{}
+	switch strings.ToLower(column.Type) {
+	case "id", "tinyinteger", "unsignedtinyinteger", "tinyincrements",
+		"smallinteger", "unsignedsmallinteger", "smallincrements",
+		"integer", "unsignedinteger", "increments", "year":
+		schema["type"] = "integer"
+	case "biginteger", "unsignedbiginteger", "bigincrements":
+		schema["type"] = "integer"
+		schema["format"] = "int64"
+	case "decimal", "unsigneddecimal", "float", "unsignedfloat", "double", "unsigneddouble":
+		schema["type"] = "number"
+	case "boolean":
+		schema["type"] = "boolean"
+	case "json", "jsonb":
+		schema["type"] = "object"
+	case "enum":
+		schema["type"] = "string"
+		if len(column.Option) > 0 {
+			schema["enum"] = column.Option
+		}
+	case "date":
+		schema["type"] = "string"
+		schema["format"] = "date"
+	case "datetime", "datetimetz", "timestamp", "timestamptz":
+		schema["type"] = "string"
+		schema["format"] = "date-time"
+	case "uuid":
+		schema["type"] = "string"
+		schema["format"] = "uuid"
+	case "binary":
+		schema["type"] = "string"
+		schema["format"] = "binary"
+	default:
+		schema["type"] = "string"
+	}
+
+	if column.Length > 0 && schema["type"] == "string" && schema["format"] == nil {
+		schema["maxLength"] = column.Length
+	}
+	return schema
+}
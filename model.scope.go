@@ -0,0 +1,121 @@
+package gou
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yaoapp/kun/exception"
+)
+
+// 已注册查询范围 (按模型名称), 模型重新加载后仍然有效
+var scopes = map[string]map[string]func(param *QueryParam){}
+var scopesLock sync.RWMutex
+
+// 已注册全局查询范围 (按模型名称, 按注册顺序应用)
+var globalScopes = map[string][]globalScope{}
+
+type globalScope struct {
+	name string
+	fn   func(ctx context.Context, param *QueryParam)
+}
+
+// Scope 注册命名查询范围, 查询时通过 QueryParam.Scopes 引用
+func (mod *Model) Scope(name string, fn func(param *QueryParam)) *Model {
+	scopesLock.Lock()
+	defer scopesLock.Unlock()
+	if _, has := scopes[mod.Name]; !has {
+		scopes[mod.Name] = map[string]func(param *QueryParam){}
+	}
+	scopes[mod.Name][name] = fn
+	return mod
+}
+
+// applyScopes 应用查询参数引用的命名查询范围
+func (mod *Model) applyScopes(param *QueryParam) {
+	if len(param.Scopes) == 0 {
+		return
+	}
+
+	scopesLock.RLock()
+	fns := []func(param *QueryParam){}
+	for _, name := range param.Scopes {
+		fn, has := scopes[mod.Name][name]
+		if !has {
+			scopesLock.RUnlock()
+			exception.New("模型 %s 未定义查询范围 %s", 400, mod.Name, name).Throw()
+		}
+		fns = append(fns, fn)
+	}
+	scopesLock.RUnlock()
+
+	param.Scopes = nil
+	param.Wheres = append([]QueryWhere{}, param.Wheres...)
+	param.Orders = append([]QueryOrder{}, param.Orders...)
+	for _, fn := range fns {
+		fn(param)
+	}
+}
+
+// GlobalScope 注册全局查询范围, 该模型的每个查询和更新/删除 (含关联 hasMany 查询) 都会自动应用
+// fn 通过 ctx 读取租户等请求信息 (Model.WithContext 绑定), 未绑定上下文时为 context.Background()
+// hasOne 关联查询为 JOIN 查询, 不应用关联模型的全局查询范围
+func (mod *Model) GlobalScope(name string, fn func(ctx context.Context, param *QueryParam)) *Model {
+	scopesLock.Lock()
+	defer scopesLock.Unlock()
+	list := []globalScope{}
+	for _, scope := range globalScopes[mod.Name] {
+		if scope.name != name {
+			list = append(list, scope)
+		}
+	}
+	globalScopes[mod.Name] = append(list, globalScope{name: name, fn: fn})
+	return mod
+}
+
+// WithoutGlobalScope 返回不应用指定全局查询范围的模型副本 (如管理员操作)
+func (mod *Model) WithoutGlobalScope(names ...string) *Model {
+	new := *mod
+	new.withoutScopes = append(append([]string{}, mod.withoutScopes...), names...)
+	return &new
+}
+
+// bind 将模型绑定的事务、上下文和不应用的全局查询范围传递给查询参数
+func (mod *Model) bind(param *QueryParam) {
+	param.tx = mod.tx
+	param.ctx = mod.ctx
+	param.without = mod.withoutScopes
+}
+
+// applyGlobalScopes 应用全局查询范围
+func (mod *Model) applyGlobalScopes(param *QueryParam) {
+	scopesLock.RLock()
+	list := globalScopes[mod.Name]
+	scopesLock.RUnlock()
+	if len(list) == 0 {
+		return
+	}
+
+	ctx := param.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	param.Wheres = append([]QueryWhere{}, param.Wheres...)
+	param.Orders = append([]QueryOrder{}, param.Orders...)
+	for _, scope := range list {
+		if param.bypass(scope.name) {
+			continue
+		}
+		scope.fn(ctx, param)
+	}
+}
+
+// bypass 是否不应用指定的全局查询范围
+func (param QueryParam) bypass(name string) bool {
+	for _, without := range param.without {
+		if without == name {
+			return true
+		}
+	}
+	return false
+}
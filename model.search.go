@@ -0,0 +1,103 @@
+package gou
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/xun/capsule"
+)
+
+// FulltextConfig PostgreSQL 全文检索配置 (to_tsvector, plainto_tsquery 使用, 创建全文索引与检索须一致)
+var FulltextConfig = "simple"
+
+// search 全文检索查询条件, 各字段条件以 OR 连接
+// 全文索引字段 MySQL: MATCH(field) AGAINST(?), PostgreSQL: to_tsvector('simple', field) @@ plainto_tsquery('simple', ?); 其他字段 field LIKE %keyword%
+func (mod *Model) search(alias string, search QuerySearch) (string, []interface{}) {
+	columns := search.Columns
+	if len(columns) == 0 {
+		for _, column := range mod.MetaData.Columns {
+			if mod.fulltext(column.Name) {
+				columns = append(columns, column.Name)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		exception.New("模型 %s 未设置全文索引字段, 请指定检索字段", 400, mod.Name).Throw()
+	}
+
+	conds := []string{}
+	bindings := []interface{}{}
+	for _, name := range columns {
+		column, has := mod.Columns[name]
+		if !has {
+			exception.New("检索字段 %s 不存在", 400, name).Throw()
+		}
+		if column.Crypt != "" {
+			exception.New("加密字段 %s 不支持检索", 400, name).Throw()
+		}
+
+		field := name
+		if alias != "" {
+			field = alias + "." + name
+		}
+		field = mod.quote(field)
+
+		if mod.fulltext(name) {
+			switch mod.Driver {
+			case "mysql":
+				conds = append(conds, fmt.Sprintf("MATCH(%s) AGAINST(?)", field))
+				bindings = append(bindings, search.Keyword)
+				continue
+			case "postgres":
+				conds = append(conds, fmt.Sprintf("to_tsvector('%s', %s) @@ plainto_tsquery('%s', ?)", FulltextConfig, field, FulltextConfig))
+				bindings = append(bindings, search.Keyword)
+				continue
+			}
+		}
+		conds = append(conds, fmt.Sprintf("%s LIKE ?", field))
+		bindings = append(bindings, "%"+search.Keyword+"%")
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", bindings
+}
+
+// fulltext 字段是否设置了全文索引 (字段 fulltext 属性, 或单字段 fulltext 类型索引)
+func (mod *Model) fulltext(name string) bool {
+	if column, has := mod.Columns[name]; has && column.Fulltext {
+		return true
+	}
+	for _, index := range mod.MetaData.Indexes {
+		if strings.ToLower(index.Type) == "fulltext" && len(index.Columns) == 1 && index.Columns[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// createFulltextIndexes 为设置 fulltext 属性的字段创建全文索引 (SQLite 不支持, 忽略)
+// MySQL: FULLTEXT 索引 {字段}_fulltext; PostgreSQL: GIN 表达式索引 {数据表}_{字段}_fulltext
+func (mod *Model) createFulltextIndexes(table string) {
+	db := capsule.Query().DB()
+	for _, column := range mod.MetaData.Columns {
+		if !column.Fulltext {
+			continue
+		}
+
+		sql := ""
+		switch mod.Driver {
+		case "mysql":
+			sql = fmt.Sprintf("ALTER TABLE %s ADD FULLTEXT INDEX %s (%s)",
+				mod.quote(table), mod.quote(column.Name+"_fulltext"), mod.quote(column.Name))
+		case "postgres":
+			sql = fmt.Sprintf("CREATE INDEX %s ON %s USING GIN (to_tsvector('%s', %s))",
+				mod.quote(strings.ReplaceAll(table, ".", "_")+"_"+column.Name+"_fulltext"), mod.quote(table), FulltextConfig, mod.quote(column.Name))
+		default:
+			continue
+		}
+
+		_, err := db.Exec(sql)
+		if err != nil {
+			exception.Err(err, 500).Throw()
+		}
+	}
+}
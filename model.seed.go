@@ -0,0 +1,169 @@
+package gou
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Seed 写入初始数据, 按 uniqueBy 字段匹配已有数据 (存在则更新, 不存在则创建), 重复执行不会产生重复数据
+// 全部数据在同一事务中写入, 任意一条失败时回滚
+func (mod *Model) Seed(rows []maps.MapStr, uniqueBy []string) error {
+
+	if len(uniqueBy) == 0 {
+		return fmt.Errorf("模型 %s 未指定数据匹配字段", mod.Name)
+	}
+
+	if mod.tx == nil {
+		return transaction(func(tx *Tx) error {
+			return mod.inTx(tx).Seed(rows, uniqueBy)
+		})
+	}
+
+	for i, row := range rows {
+		match := maps.MapStr{}
+		for _, name := range uniqueBy {
+			value, has := row[name]
+			if !has {
+				return fmt.Errorf("第 %d 条: 缺少匹配字段 %s", i, name)
+			}
+			match[name] = value
+		}
+
+		values := maps.MapStr{}
+		for name, value := range row {
+			if _, has := match[name]; !has {
+				values[name] = value
+			}
+		}
+
+		_, _, err := mod.UpdateOrCreate(match, values)
+		if err != nil {
+			return fmt.Errorf("第 %d 条: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// MustSeed 写入初始数据, 失败抛出异常
+func (mod *Model) MustSeed(rows []maps.MapStr, uniqueBy []string) {
+	err := mod.Seed(rows, uniqueBy)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+}
+
+// SeedAll 按模型依赖顺序 (关联数据先写入) 写入多个模型的初始数据, 全部数据在同一事务中写入
+// 数据匹配字段依次为: 第一个唯一索引的字段, 第一个唯一字段, 主键
+func SeedAll(data map[string][]maps.MapStr) error {
+	names, err := seedOrder(data)
+	if err != nil {
+		return err
+	}
+
+	return transaction(func(tx *Tx) error {
+		for _, name := range names {
+			mod := tx.Select(name)
+			err := mod.Seed(data[name], mod.seedKeys())
+			if err != nil {
+				return fmt.Errorf("模型 %s %s", name, err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+// MustSeedAll 写入多个模型的初始数据, 失败抛出异常
+func MustSeedAll(data map[string][]maps.MapStr) {
+	err := SeedAll(data)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+}
+
+// seedKeys 写入初始数据时的匹配字段
+func (mod *Model) seedKeys() []string {
+	for _, index := range mod.MetaData.Indexes {
+		if strings.ToLower(index.Type) == "unique" && len(index.Columns) > 0 {
+			return index.Columns
+		}
+	}
+	for _, column := range mod.UniqueColumns {
+		if column.UniqueScope == nil {
+			return []string{column.Name}
+		}
+	}
+	return []string{mod.PrimaryKey}
+}
+
+// seedOrder 按 hasOne/hasMany 关联关系排序模型, 被引用的模型在前
+func seedOrder(data map[string][]maps.MapStr) ([]string, error) {
+	names := []string{}
+	for name := range data {
+		if _, has := Models[name]; !has {
+			return nil, fmt.Errorf("Model:%s; 尚未加载", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// deps[A][B] A 依赖 B (B 先写入)
+	deps := map[string]map[string]bool{}
+	for _, name := range names {
+		deps[name] = map[string]bool{}
+	}
+	for _, name := range names {
+		mod := Models[name]
+		for _, rel := range mod.MetaData.Relations {
+			related, has := Models[rel.Model]
+			if !has || rel.Model == name || deps[rel.Model] == nil {
+				continue
+			}
+			if rel.Type != RelHasOne && rel.Type != RelHasMany {
+				continue
+			}
+			if rel.Key == related.PrimaryKey && rel.Foreign != mod.PrimaryKey { // 外键在当前模型
+				deps[name][rel.Model] = true
+			} else if rel.Foreign == mod.PrimaryKey && rel.Key != related.PrimaryKey { // 外键在关联模型
+				deps[rel.Model][name] = true
+			}
+		}
+	}
+
+	order := []string{}
+	done := map[string]bool{}
+	for len(order) < len(names) {
+		next := ""
+		for _, name := range names {
+			if done[name] {
+				continue
+			}
+			ready := true
+			for dep := range deps[name] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = name
+				break
+			}
+		}
+		if next == "" {
+			pending := []string{}
+			for _, name := range names {
+				if !done[name] {
+					pending = append(pending, name)
+				}
+			}
+			return nil, fmt.Errorf("模型 %s 存在循环依赖", strings.Join(pending, ", "))
+		}
+		done[next] = true
+		order = append(order, next)
+	}
+	return order, nil
+}
@@ -0,0 +1,222 @@
+package gou
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/dbal"
+)
+
+// 软删除标记格式
+const (
+	SoftDeleteTimestamp = "timestamp" // 删除时间, NULL 为未删除
+	SoftDeleteBoolean   = "boolean"   // true 为已删除
+	SoftDeleteStatus    = "status"    // 字段值为 Value 时已删除
+)
+
+// softDelete 返回补全默认值的软删除策略
+func (mod *Model) softDelete() SoftDelete {
+	sd := mod.MetaData.SoftDelete
+	sd.Format = strings.ToLower(sd.Format)
+	if sd.Format == "" {
+		sd.Format = SoftDeleteTimestamp
+	}
+	if sd.Column == "" {
+		sd.Column = "deleted_at"
+		if sd.Format == SoftDeleteBoolean {
+			sd.Column = "is_deleted"
+		} else if sd.Format == SoftDeleteStatus {
+			sd.Column = "status"
+		}
+	}
+	if sd.Format == SoftDeleteStatus && sd.Value == nil {
+		sd.Value = "deleted"
+	}
+	return sd
+}
+
+// column 软删除标记字段定义 (status 格式使用已定义的字段, 返回 nil)
+func (sd SoftDelete) column() *Column {
+	switch sd.Format {
+	case SoftDeleteBoolean:
+		return &Column{Label: "删除标记", Name: sd.Column, Type: "boolean", Comment: "删除标记", Default: false, Index: true}
+	case SoftDeleteStatus:
+		return nil
+	}
+	return &Column{Label: "删除标记", Name: sd.Column, Type: "timestamp", Comment: "删除标记", Nullable: true}
+}
+
+// notDeleted 未删除数据查询条件
+func (sd SoftDelete) notDeleted() QueryWhere {
+	switch sd.Format {
+	case SoftDeleteBoolean:
+		return QueryWhere{Wheres: []QueryWhere{
+			{Column: sd.Column, Value: false},
+			{Column: sd.Column, OP: "null", Method: "orWhere"},
+		}}
+	case SoftDeleteStatus:
+		return QueryWhere{Wheres: []QueryWhere{
+			{Column: sd.Column, OP: "ne", Value: sd.Value},
+			{Column: sd.Column, OP: "null", Method: "orWhere"},
+		}}
+	}
+	return QueryWhere{Column: sd.Column, OP: "null"}
+}
+
+// notDeletedSQL 未删除数据查询条件 (SQL 片段, 用于子查询), column 为已转义的删除标记字段
+func (sd SoftDelete) notDeletedSQL(column string) string {
+	switch sd.Format {
+	case SoftDeleteBoolean:
+		return fmt.Sprintf("(%s = %s OR %s IS NULL)", column, "false", column)
+	case SoftDeleteStatus:
+		return fmt.Sprintf("(%s <> %s OR %s IS NULL)", column, sqlString(sd.Value), column)
+	}
+	return column + " IS NULL"
+}
+
+// sqlString SQL 字符串常量
+func sqlString(value interface{}) string {
+	return "'" + strings.ReplaceAll(fmt.Sprintf("%v", value), "'", "''") + "'"
+}
+
+// isDeleted 已删除数据查询条件
+func (sd SoftDelete) isDeleted() QueryWhere {
+	switch sd.Format {
+	case SoftDeleteBoolean:
+		return QueryWhere{Column: sd.Column, Value: true}
+	case SoftDeleteStatus:
+		return QueryWhere{Column: sd.Column, Value: sd.Value}
+	}
+	return QueryWhere{Column: sd.Column, OP: "notnull"}
+}
+
+// fliterIn 忽略写入数据中的删除标记字段 (status 格式的状态字段为业务字段, 不做处理)
+func (sd SoftDelete) fliterIn(row maps.MapStrAny) {
+	if sd.Format != SoftDeleteStatus {
+		row.Del(sd.Column)
+	}
+}
+
+// deleted 删除标记字段的已删除数值
+func (sd SoftDelete) deleted() interface{} {
+	switch sd.Format {
+	case SoftDeleteBoolean:
+		return true
+	case SoftDeleteStatus:
+		return sd.Value
+	}
+	return dbal.Raw("CURRENT_TIMESTAMP")
+}
+
+// restored 删除标记字段的恢复数值
+func (sd SoftDelete) restored() (interface{}, error) {
+	switch sd.Format {
+	case SoftDeleteBoolean:
+		return false, nil
+	case SoftDeleteStatus:
+		if sd.Restore == nil {
+			return nil, fmt.Errorf("软删除策略未设置恢复后的状态值(restore)")
+		}
+		return sd.Restore, nil
+	}
+	return nil, nil
+}
+
+// Restore 恢复软删除的记录 (同时恢复删除时备份的唯一字段数值)
+func (mod *Model) Restore(id interface{}) error {
+
+	if err := mod.readonly(); err != nil {
+		return err
+	}
+
+	if !mod.MetaData.Option.SoftDeletes {
+		return fmt.Errorf("模型 %s 未开启软删除", mod.Name)
+	}
+
+	if mod.auditing() { // 审计日志与数据写入共用事务
+		return transaction(func(tx *Tx) error {
+			return mod.inTx(tx).Restore(id)
+		})
+	}
+
+	sd := mod.softDelete()
+	value, err := sd.restored()
+	if err != nil {
+		return err
+	}
+
+	// 仅恢复已删除的数据 (应用全局查询范围)
+	param := QueryParam{
+		Model:       mod.Name,
+		WithTrashed: true,
+		Wheres: []QueryWhere{
+			{Column: mod.PrimaryKey, Value: id},
+			sd.isDeleted(),
+		},
+		Limit: 1,
+	}
+	mod.bind(&param)
+
+	selects := param
+	selects.Select = []interface{}{mod.PrimaryKey, dbal.Raw(mod.quote(mod.MetaData.Table.Name+".__restore_data") + " as __restore_data")}
+	rows := NewQueryStack(selects).Run()
+	if len(rows) == 0 {
+		return fmt.Errorf("ID=%v的数据不存在或未删除", id)
+	}
+
+	data := maps.MapStrAny{}
+	switch backup := rows[0].Get("__restore_data").(type) {
+	case string:
+		err = decodeRestoreData([]byte(backup), &data)
+	case []byte:
+		err = decodeRestoreData(backup, &data)
+	}
+	if err != nil {
+		return err
+	}
+
+	data[sd.Column] = value
+	data["__restore_data"] = nil
+	update := maps.MapStrAny{}
+	for name, value := range data {
+		switch {
+		case mod.Driver == "sqlite3" && name != sd.Column && name != "__restore_data":
+			// SQLite 使用 json_extract 读取备份数值, 保留原数据类型
+			update[name] = dbal.Raw(fmt.Sprintf("json_extract(%s, '$.%s')", mod.quote("__restore_data"), name))
+		case mod.Driver == "sqlite3":
+			update[name] = value
+		default:
+			update[fmt.Sprintf("%s.%s", mod.MetaData.Table.Name, name)] = value
+		}
+	}
+
+	start := time.Now()
+	effect, err := NewQueryStack(param).FirstQuery().Update(update)
+	if err != nil {
+		return mod.conflict(err) // 恢复的唯一字段数值已被占用
+	}
+	mod.stat(start, 1, 0, int(effect))
+	if effect == 0 {
+		return fmt.Errorf("ID=%v的数据不存在或未删除", id)
+	}
+	return mod.changed(EventRestore, id, nil, data)
+}
+
+// decodeRestoreData 解析唯一数据备份, 数字按原文保留 (避免大整数精度丢失)
+func decodeRestoreData(backup []byte, data *maps.MapStrAny) error {
+	decoder := json.NewDecoder(bytes.NewReader(backup))
+	decoder.UseNumber()
+	return decoder.Decode(data)
+}
+
+// MustRestore 恢复软删除的记录, 失败抛出异常
+func (mod *Model) MustRestore(id interface{}) {
+	err := mod.Restore(id)
+	if err != nil {
+		throwError(err)
+	}
+}
@@ -0,0 +1,55 @@
+package gou
+
+import (
+	"sync"
+	"time"
+)
+
+// ModelStats 模型查询统计 (自进程启动或上次 ResetStats 起累计)
+type ModelStats struct {
+	Queries     int64         `json:"queries"`      // 执行的SQL语句数量
+	RowsRead    int64         `json:"rows_read"`    // 读取的记录数
+	RowsWritten int64         `json:"rows_written"` // 写入 (创建/更新/删除) 的记录数
+	Duration    time.Duration `json:"duration"`     // 累计执行时间
+}
+
+var stats = map[string]*ModelStats{}
+var statsLock sync.Mutex
+
+// Stats 读取各模型的查询统计 (模型名称 => 统计数据), 返回数据为副本
+func Stats() map[string]ModelStats {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	res := map[string]ModelStats{}
+	for name, stat := range stats {
+		res[name] = *stat
+	}
+	return res
+}
+
+// ResetStats 清空查询统计
+func ResetStats() {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	stats = map[string]*ModelStats{}
+}
+
+// stat 累计模型查询统计
+func (mod *Model) stat(start time.Time, queries int, read int, written int) {
+	if mod == nil {
+		return
+	}
+
+	duration := time.Since(start)
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	stat, has := stats[mod.Name]
+	if !has {
+		stat = &ModelStats{}
+		stats[mod.Name] = stat
+	}
+	stat.Queries += int64(queries)
+	stat.RowsRead += int64(read)
+	stat.RowsWritten += int64(written)
+	stat.Duration += duration
+}
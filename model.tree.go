@@ -0,0 +1,136 @@
+package gou
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/kun/maps"
+)
+
+// TreeMaxDepth 树形结构遍历的最大层级 (未指定层级时使用, 数据存在环时避免无限遍历)
+var TreeMaxDepth = 100
+
+// Descendants 读取树形结构 (Option.Parent 上级字段) 的下级数据, depth 为读取层级 (<= 0 时为 TreeMaxDepth)
+// 结果按层级排序, 同层级按主键排序; 支持的数据库使用递归查询 (WITH RECURSIVE), 否则逐层查询
+// 层级遍历包含已软删除的数据, 结果应用查询范围和软删除条件
+func (mod *Model) Descendants(id interface{}, depth int) ([]maps.MapStr, error) {
+	return mod.treeRows(id, depth, false)
+}
+
+// MustDescendants 读取树形结构的下级数据, 失败抛出异常
+func (mod *Model) MustDescendants(id interface{}, depth int) []maps.MapStr {
+	res, err := mod.Descendants(id, depth)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// Ancestors 读取树形结构的上级数据, 结果从直接上级到根节点排列
+func (mod *Model) Ancestors(id interface{}) ([]maps.MapStr, error) {
+	return mod.treeRows(id, 0, true)
+}
+
+// MustAncestors 读取树形结构的上级数据, 失败抛出异常
+func (mod *Model) MustAncestors(id interface{}) []maps.MapStr {
+	res, err := mod.Ancestors(id)
+	if err != nil {
+		throwError(err)
+	}
+	return res
+}
+
+// treeRows 按层级顺序读取上级或下级数据
+func (mod *Model) treeRows(id interface{}, depth int, up bool) ([]maps.MapStr, error) {
+	if mod.MetaData.Option.Parent == "" {
+		return nil, fmt.Errorf("模型 %s 未设置上级字段 (option.parent)", mod.Name)
+	}
+	if depth <= 0 || depth > TreeMaxDepth {
+		depth = TreeMaxDepth
+	}
+
+	var ids []interface{}
+	var err error
+	if mod.MetaData.Option.Path != "" { // 树形路径
+		ids, err = mod.pathIDs(id, depth, up)
+	} else {
+		ids, err = mod.treeRecursive(id, depth, up)
+		if err != nil {
+			ids, err = mod.treeIterative(id, depth, up)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := mod.FindMany(ids, QueryParam{})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// treeRecursive 递归查询 (WITH RECURSIVE) 按层级读取主键, 同一数据按最小层级读取; MySQL 8.0 以下不支持, 返回错误
+func (mod *Model) treeRecursive(id interface{}, depth int, up bool) ([]interface{}, error) {
+	q := mod.quote
+	table, pk, parent := q(mod.TableName()), q(mod.PrimaryKey), q(mod.MetaData.Option.Parent)
+	join := fmt.Sprintf("%s.%s = %s.%s", q("__t__"), parent, q("__tree__"), q("__id__")) // 下级: 上级字段为已读取数据的主键
+	if up {
+		join = fmt.Sprintf("%s.%s = %s.%s", q("__t__"), pk, q("__tree__"), q("__parent__")) // 上级: 已读取数据的上级字段
+	}
+
+	sql := fmt.Sprintf(
+		"WITH RECURSIVE %s (%s, %s, %s) AS ("+
+			"SELECT %s, %s, 0 FROM %s WHERE %s = ? "+
+			"UNION ALL SELECT %s.%s, %s.%s, %s.%s + 1 FROM %s AS %s INNER JOIN %s ON %s WHERE %s.%s < ?"+
+			") SELECT %s, MIN(%s) AS %s FROM %s WHERE %s > 0 GROUP BY %s ORDER BY %s, %s",
+		q("__tree__"), q("__id__"), q("__parent__"), q("__depth__"),
+		pk, parent, table, pk,
+		q("__t__"), pk, q("__t__"), parent, q("__tree__"), q("__depth__"), table, q("__t__"), q("__tree__"), join, q("__tree__"), q("__depth__"),
+		q("__id__"), q("__depth__"), q("__depth__"), q("__tree__"), q("__depth__"), q("__id__"), q("__depth__"), q("__id__"),
+	)
+
+	rows, err := mod.newQuery().SQL(sql, id, depth).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []interface{}{}
+	for _, row := range rows {
+		if fmt.Sprintf("%v", row["__id__"]) == fmt.Sprintf("%v", id) { // 数据存在环
+			continue
+		}
+		ids = append(ids, row["__id__"])
+	}
+	return ids, nil
+}
+
+// treeIterative 逐层查询读取主键, 已读取的数据不再遍历 (数据存在环时终止)
+func (mod *Model) treeIterative(id interface{}, depth int, up bool) ([]interface{}, error) {
+	ids := []interface{}{}
+	visited := map[string]bool{fmt.Sprintf("%v", id): true}
+	frontier := []interface{}{id}
+	for level := 0; level < depth && len(frontier) > 0; level++ {
+		column, where := mod.PrimaryKey, mod.MetaData.Option.Parent // 下级: 上级字段为上一层数据主键的数据
+		if up {
+			column, where = mod.MetaData.Option.Parent, mod.PrimaryKey // 上级: 上一层数据的上级字段
+		}
+
+		rows, err := mod.newQuery().Table(mod.TableName()).Select(column).WhereIn(where, frontier).OrderBy(column).Get()
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = []interface{}{}
+		for _, row := range rows {
+			value := row[column]
+			key := fmt.Sprintf("%v", value)
+			if value == nil || visited[key] {
+				continue
+			}
+			visited[key] = true
+			ids = append(ids, value)
+			frontier = append(frontier, value)
+		}
+	}
+	return ids, nil
+}
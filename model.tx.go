@@ -0,0 +1,108 @@
+package gou
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+)
+
+// Tx 数据库事务
+type Tx struct {
+	qb      query.Query
+	commits []func() // 事务提交后执行
+}
+
+// TxModels 绑定事务的模型句柄
+type TxModels interface {
+	Select(name string) *Model // 读取绑定事务的模型, Create/Update/Find 等操作使用事务查询构建器
+	Query() query.Query        // 绑定事务的查询构建器
+}
+
+// Transaction 在事务中运行 handler, handler 返回错误时回滚, 否则提交
+// handler 中通过 m.Select 读取的模型绑定该事务; handler 发生异常 (panic) 时回滚后继续抛出
+func Transaction(handler func(m TxModels) error) error {
+	return transaction(func(tx *Tx) error { return handler(tx) })
+}
+
+// transaction 在事务中运行 handler (模型内部写入使用)
+func transaction(handler func(tx *Tx) error) error {
+	tx := &Tx{commits: []func(){}}
+	var recovered interface{}
+	err := capsule.Query().Transaction(func(qb query.Query) (err error) {
+		tx.qb = qb
+		defer func() {
+			if r := recover(); r != nil {
+				recovered = r
+				err = fmt.Errorf("事务异常: %v", r)
+			}
+		}()
+		return handler(tx)
+	})
+	if recovered != nil {
+		panic(recovered)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range tx.commits {
+		fn()
+	}
+	return nil
+}
+
+type txKey struct{}
+
+// WithTx 绑定事务到上下文, Model.WithContext 读取的模型使用该事务 (见 RequestTransaction)
+func WithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxOf 读取上下文中的事务, 未绑定时返回 nil
+func TxOf(ctx context.Context) *Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// Query 返回绑定事务的查询构建器
+func (tx *Tx) Query() query.Query {
+	return tx.qb.New()
+}
+
+// Select 读取绑定事务的模型
+func (tx *Tx) Select(name string) *Model {
+	return Select(name).inTx(tx)
+}
+
+// afterCommit 注册事务提交后执行的函数
+func (tx *Tx) afterCommit(fn func()) {
+	tx.commits = append(tx.commits, fn)
+}
+
+// inTx 返回绑定事务的模型副本
+func (mod *Model) inTx(tx *Tx) *Model {
+	new := *mod
+	new.tx = tx
+	return &new
+}
+
+// newQuery 创建查询构建器 (已绑定事务时使用事务查询构建器)
+func (mod *Model) newQuery() query.Query {
+	if mod.tx != nil {
+		return mod.tx.Query()
+	}
+	return capsule.Query()
+}
+
+// newQuery 创建查询构建器 (已绑定事务时使用事务查询构建器)
+func (param QueryParam) newQuery() query.Query {
+	if param.tx != nil {
+		return param.tx.Query()
+	}
+	return capsule.Query()
+}
@@ -1,6 +1,8 @@
 package gou
 
 import (
+	"context"
+
 	"github.com/yaoapp/kun/maps"
 )
 
@@ -13,6 +15,7 @@ const (
 	RelHasManyThrough = "hasManyThrough" // 1 v n ( t1 <-> t2 <-> t3)
 	RelBelongsToMany  = "belongsToMany"  // 1 v1 / 1 v n / n v n
 	RelMorphOne       = "morphOne"
+	RelMorphTo        = "morphTo" // inverse morphOne / morphMany ( type + id )
 	RelMorphMany      = "morphMany"
 	RelMorphToMany    = "morphToMany"
 	RelMorphByMany    = "morphByMany"
@@ -30,17 +33,66 @@ type Model struct {
 	PrimaryKey    string             // 主键(单一主键)
 	PrimaryKeys   []string           // 主键(联合主键)
 	UniqueColumns []*Column          // 唯一字段清单
+	tx            *Tx                // 绑定的事务
+	ctx           context.Context    // 绑定的上下文
+	withoutScopes []string           // 不应用的全局查询范围
+}
+
+// ModelInfo 模型描述信息
+type ModelInfo struct {
+	Name       string              `json:"name"`                // 模型名称
+	Label      string              `json:"label,omitempty"`     // 元数据名称
+	Table      string              `json:"table"`               // 数据表名称
+	Comment    string              `json:"comment,omitempty"`   // 数据表注释
+	PrimaryKey string              `json:"primary"`             // 主键
+	Columns    []ColumnInfo        `json:"columns"`             // 字段 (含软删除、时间戳字段)
+	Relations  map[string]Relation `json:"relations,omitempty"` // 关联关系
+	Indexes    []Index             `json:"indexes,omitempty"`   // 索引
+	Option     Option              `json:"option"`              // 模型配置选项
+}
+
+// ColumnInfo 字段描述信息
+type ColumnInfo struct {
+	Name      string      `json:"name"`
+	Label     string      `json:"label,omitempty"`
+	Type      string      `json:"type"`
+	Comment   string      `json:"comment,omitempty"`
+	Length    int         `json:"length,omitempty"`
+	Precision int         `json:"precision,omitempty"`
+	Scale     int         `json:"scale,omitempty"`
+	Nullable  bool        `json:"nullable,omitempty"`
+	Option    []string    `json:"option,omitempty"`
+	Default   interface{} `json:"default,omitempty"`
+	Index     bool        `json:"index,omitempty"`
+	Unique    bool        `json:"unique,omitempty"`
+	Primary   bool        `json:"primary,omitempty"`
+	Hidden    bool        `json:"hidden,omitempty"`
+	Crypt     bool        `json:"crypt,omitempty"` // 加密字段
 }
 
 // MetaData 元数据
 type MetaData struct {
-	Name      string              `json:"name,omitempty"`      // 元数据名称
-	Table     Table               `json:"table,omitempty"`     // 数据表选项
-	Columns   []Column            `json:"columns,omitempty"`   // 字段定义
-	Indexes   []Index             `json:"indexes,omitempty"`   // 索引定义
-	Relations map[string]Relation `json:"relations,omitempty"` // 映射关系定义
-	Values    []maps.MapStrAny    `json:"values,omitempty"`    // 初始数值
-	Option    Option              `json:"option,omitempty"`    // 元数据配置
+	Name         string              `json:"name,omitempty"`          // 元数据名称
+	Table        Table               `json:"table,omitempty"`         // 数据表选项
+	Columns      []Column            `json:"columns,omitempty"`       // 字段定义
+	Indexes      []Index             `json:"indexes,omitempty"`       // 索引定义
+	Relations    map[string]Relation `json:"relations,omitempty"`     // 映射关系定义
+	Values       []maps.MapStrAny    `json:"values,omitempty"`        // 初始数值
+	Option       Option              `json:"option,omitempty"`        // 元数据配置
+	SoftDelete   SoftDelete          `json:"soft_delete,omitempty"`   // 软删除策略 (Option.SoftDeletes 开启时有效)
+	View         bool                `json:"view,omitempty"`          // 数据库视图 (只读模型, 视图定义 Table.SQL)
+	Extends      string              `json:"extends,omitempty"`       // 继承的模型名称 (须已载入, 当前模型定义优先)
+	Mixins       []string            `json:"mixins,omitempty"`        // 混入的模型名称列表 (按顺序合并, 先于 extends)
+	DefaultOrder string              `json:"default_order,omitempty"` // 默认排序 (如 "id desc", "sort, id desc"), Get, Paginate 未指定排序时使用
+	Webhooks     []Webhook           `json:"webhooks,omitempty"`      // 数据变更通知 (事务提交后 POST 变更事件)
+}
+
+// SoftDelete 软删除策略
+type SoftDelete struct {
+	Column  string      `json:"column,omitempty"`  // 删除标记字段, 默认 deleted_at
+	Format  string      `json:"format,omitempty"`  // timestamp 删除时间(默认), boolean 删除标记, status 状态值
+	Value   interface{} `json:"value,omitempty"`   // status: 已删除状态值, 默认 deleted
+	Restore interface{} `json:"restore,omitempty"` // status: 恢复后的状态值
 }
 
 // Column the field description struct
@@ -63,16 +115,27 @@ type Column struct {
 	Crypt       string       `json:"crypt,omitempty"`    // AES, PASSWORD, AES-256, AES-128, PASSWORD-HASH, ...
 	Validations []Validation `json:"validations,omitempty"`
 	Index       bool         `json:"index,omitempty"`
-	Unique      bool         `json:"unique,omitempty"`
+	Unique      bool         `json:"unique,omitempty"`       // 唯一字段, 设置为对象 {"scope": [...], "ignore_deleted": true} 时为范围唯一 (UniqueScope)
+	UniqueScope *UniqueScope `json:"unique_scope,omitempty"` // 范围唯一约束
+	Fulltext    bool         `json:"fulltext,omitempty"`     // 全文索引 (MySQL FULLTEXT, PostgreSQL GIN to_tsvector), 用于 QueryParam.Search
 	Primary     bool         `json:"primary,omitempty"`
+	Hidden      bool         `json:"hidden,omitempty"` // 默认查询及导出时隐藏
 	model       *Model
 }
 
+// UniqueScope 范围唯一约束, 字段数值在范围字段相同的数据中唯一 (如 mobile 在同一 manu_id 中唯一)
+// 数据校验时查询数据库检查; 创建数据表时创建 (范围字段..., 字段) 组合唯一索引
+type UniqueScope struct {
+	Scope         []string `json:"scope,omitempty"`          // 范围字段
+	IgnoreDeleted bool     `json:"ignore_deleted,omitempty"` // 不包含已软删除的数据 (软删除时备份并释放字段数值, 同唯一字段)
+}
+
 // Validation the field validation struct
 type Validation struct {
-	Method  string        `json:"method"`
-	Args    []interface{} `json:"args,omitempty"`
-	Message string        `json:"message,omitempty"`
+	Method   string            `json:"method"`
+	Args     []interface{}     `json:"args,omitempty"`
+	Message  string            `json:"message,omitempty"`  // 校验消息 (默认语言) 或语言消息键
+	Messages map[string]string `json:"messages,omitempty"` // 多语言校验消息 locale => 消息
 }
 
 // ValidateResponse 数据校验返回结果
@@ -98,6 +161,7 @@ type Table struct {
 	Collation   string   `json:"collation"`
 	Charset     string   `json:"charset"`
 	PrimaryKeys []string `json:"primarykeys"`
+	SQL         string   `json:"sql,omitempty"` // 视图定义 (SELECT 语句, View 为 true 时有效, 为空时不创建视图)
 }
 
 // Relation the new xun model relation
@@ -109,6 +173,10 @@ type Relation struct {
 	Foreign string     `json:"foreign,omitempty"`
 	Links   []Relation `json:"links,omitempty"`
 	Query   QueryParam `json:"query,omitempty"`
+
+	Morph      string            `json:"morph,omitempty"`       // 多态关联类型字段
+	MorphValue string            `json:"morph_value,omitempty"` // morphMany 类型值 (默认为当前模型名称)
+	Models     map[string]string `json:"models,omitempty"`      // morphTo 类型值与模型名称映射 (设置后忽略未映射的类型值, 未设置时类型值即模型名称)
 }
 
 // Option 模型配置选项
@@ -119,6 +187,14 @@ type Option struct {
 	Constraints bool `json:"constraints,omitempty"`  // + 约束定义
 	Permission  bool `json:"permission,omitempty"`   // + __permission 字段
 	Logging     bool `json:"logging,omitempty"`      // + __logging_id 字段
+	Audit       bool `json:"audit,omitempty"`        // 数据变更写入审计日志
+	MaxPageSize int  `json:"max_pagesize,omitempty"` // 分页查询每页最大记录数, 默认使用 MaxPageSize
+
+	Parent string `json:"parent,omitempty"` // 树形结构上级字段 (引用当前模型主键), Descendants, Ancestors 使用
+	Path   string `json:"path,omitempty"`   // 树形路径字段 (如 1/2/4), 创建和修改上级时自动维护, 需同时设置 parent
+
+	TimeFormat string `json:"time_format,omitempty"` // 日期时间字段输出格式 (Go layout), 默认使用 TimeFormat
+	TimeZone   string `json:"timezone,omitempty"`    // 日期时间字段输出时区 (如 Asia/Shanghai), 默认使用 TimeZone
 }
 
 // ColumnMap ColumnMap 字段映射
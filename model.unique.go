@@ -0,0 +1,123 @@
+package gou
+
+import (
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/kun/str"
+)
+
+// ValidateUnique 范围唯一约束 (UniqueScope) 校验, 查询数据库检查范围内是否已存在相同数值
+// id 为更新数据的主键 (创建数据为 nil), 更新数据未提供的范围字段读取原数据; 字段数值为 null 时不检查
+func (mod *Model) ValidateUnique(row maps.MapStrAny, id interface{}, locale ...string) []ValidateResponse {
+	lang := LocaleOf(mod.ctx)
+	if len(locale) > 0 {
+		lang = locale[0]
+	}
+	if lang == "" {
+		lang = defaultLocale
+	}
+
+	res := []ValidateResponse{}
+	var current maps.MapStr // 更新前的数据
+	for _, column := range mod.MetaData.Columns {
+		if column.UniqueScope == nil {
+			continue
+		}
+
+		names := append([]string{column.Name}, column.UniqueScope.Scope...)
+		changed := false
+		for _, name := range names {
+			if row.Has(name) {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		values := map[string]interface{}{}
+		for _, name := range names {
+			switch {
+			case row.Has(name):
+				values[name] = row.Get(name)
+			case id != nil:
+				if current == nil {
+					current = mod.uniqueCurrent(id)
+				}
+				values[name] = current.Get(name)
+			default:
+				values[name] = nil
+			}
+		}
+		if values[column.Name] == nil {
+			continue
+		}
+
+		if !mod.uniqueExists(column, values, id) {
+			continue
+		}
+		label := column.Label
+		if label == "" {
+			label = column.Name
+		}
+		message, _ := translate(lang, "validation.unique", true)
+		res = append(res, ValidateResponse{
+			Column:   column.Name,
+			Messages: []string{str.Bind(message, map[string]interface{}{"label": label, "name": column.Name, "input": values[column.Name]})},
+		})
+	}
+	return res
+}
+
+// uniqueCurrent 读取更新前的数据 (含已软删除的数据)
+func (mod *Model) uniqueCurrent(id interface{}) maps.MapStr {
+	rows := mod.MustGet(QueryParam{
+		Wheres:      []QueryWhere{{Column: mod.PrimaryKey, Value: id}},
+		WithTrashed: true,
+		Limit:       1,
+	})
+	if len(rows) == 0 {
+		return maps.MapStr{}
+	}
+	return rows[0]
+}
+
+// uniqueExists 范围内是否已存在相同数值的其他数据 (不应用查询范围)
+func (mod *Model) uniqueExists(column Column, values map[string]interface{}, id interface{}) bool {
+	qb := mod.newQuery().Table(mod.TableName()).Select(mod.PrimaryKey)
+	for name, value := range values {
+		if value == nil {
+			qb.WhereNull(name)
+			continue
+		}
+		qb.Where(name, value)
+	}
+	if id != nil {
+		qb.Where(mod.PrimaryKey, "<>", id)
+	}
+	if column.UniqueScope.IgnoreDeleted && mod.MetaData.Option.SoftDeletes {
+		QueryParam{}.Where(mod.softDelete().notDeleted(), qb, mod)
+	}
+
+	row, err := qb.Limit(1).First()
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+	return !row.IsEmpty()
+}
+
+// uniqueScopeIndexes 范围唯一约束的组合唯一索引 (范围字段..., 字段), 索引名称为 {字段}_scope_unique
+func (mod *Model) uniqueScopeIndexes() []Index {
+	indexes := []Index{}
+	for _, column := range mod.MetaData.Columns {
+		if column.UniqueScope == nil {
+			continue
+		}
+		indexes = append(indexes, Index{
+			Name:    column.Name + "_scope_unique",
+			Columns: append(append([]string{}, column.UniqueScope.Scope...), column.Name),
+			Type:    "unique",
+		})
+	}
+	return indexes
+}
@@ -0,0 +1,193 @@
+package gou
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+)
+
+// WebhookQueueSize 待发送的 Webhook 请求队列长度, 队列已满时丢弃新请求并记录 WARN 日志, 不会阻塞数据写入
+var WebhookQueueSize = 1024
+
+// WebhookWorkers 发送 Webhook 请求的并发数
+var WebhookWorkers = 4
+
+// WebhookRetries 发送失败 (请求错误或非 2xx 响应) 后的重试次数
+var WebhookRetries = 5
+
+// WebhookBackoff 首次重试的等待时长, 之后每次重试加倍 (1s, 2s, 4s ...)
+var WebhookBackoff = time.Second
+
+// WebhookClient 发送 Webhook 请求的 HTTP 客户端
+var WebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookSignatureHeader 请求签名头, 数值为 sha256=<请求数据的 HMAC-SHA256 十六进制签名>, 未设置密钥时不签名
+var WebhookSignatureHeader = "X-Webhook-Signature"
+
+// WebhookEventHeader 事件类型头, 数值为 <模型名称>.<变更类型>, 如 user.create
+var WebhookEventHeader = "X-Webhook-Event"
+
+// Webhook 数据变更通知, 数据写入 (事务提交) 后 POST 变更事件 (ChangeEvent JSON, 不含隐藏字段)
+type Webhook struct {
+	On      []string          `json:"on,omitempty"`      // 变更类型 create, update, delete, destroy, restore; 未设置时为全部类型
+	URL     string            `json:"url"`               // 接收地址 (http, https)
+	Secret  string            `json:"secret,omitempty"`  // 签名密钥 (可使用环境变量 ${NAME})
+	Headers map[string]string `json:"headers,omitempty"` // 附加请求头
+}
+
+type webhookJob struct {
+	hook    Webhook
+	event   string
+	body    []byte
+	attempt int
+}
+
+var webhookQueue chan *webhookJob
+var webhookOnce sync.Once
+
+// webhooks 校验模型 Webhook 定义
+func (mod *Model) webhooks() error {
+	for _, hook := range mod.MetaData.Webhooks {
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("模型 %s Webhook 地址 %s 错误", mod.Name, hook.URL)
+		}
+		for _, op := range hook.On {
+			switch op {
+			case EventCreate, EventUpdate, EventDelete, EventDestroy, EventRestore:
+			default:
+				return fmt.Errorf("模型 %s Webhook 变更类型 %s 错误", mod.Name, op)
+			}
+		}
+	}
+	return nil
+}
+
+// webhook 按变更类型发送 Webhook 请求 (加入发送队列)
+func (mod *Model) webhook(event ChangeEvent) {
+	if len(mod.MetaData.Webhooks) == 0 {
+		return
+	}
+
+	event.Before = mod.webhookRow(event.Before)
+	event.After = mod.webhookRow(event.After)
+	body, err := jsoniter.Marshal(event)
+	if err != nil {
+		log.Error("Webhook %s.%s: %s", event.Model, event.Op, err)
+		return
+	}
+
+	for _, hook := range mod.MetaData.Webhooks {
+		if !hook.listen(event.Op) {
+			continue
+		}
+		enqueueWebhook(&webhookJob{hook: hook, event: event.Model + "." + event.Op, body: body})
+	}
+}
+
+// webhookRow 移除隐藏字段
+func (mod *Model) webhookRow(row maps.MapStr) maps.MapStr {
+	if row == nil {
+		return nil
+	}
+	res := maps.MapStr{}
+	for key, value := range row {
+		if column, has := mod.Columns[key]; has && column.Hidden {
+			continue
+		}
+		res[key] = value
+	}
+	return res
+}
+
+// listen 是否通知该变更类型
+func (hook Webhook) listen(op string) bool {
+	if len(hook.On) == 0 {
+		return true
+	}
+	for _, on := range hook.On {
+		if on == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign 请求数据签名 (HMAC-SHA256 十六进制), 接收方使用相同密钥校验请求头 WebhookSignatureHeader
+func (hook Webhook) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// enqueueWebhook 加入发送队列 (非阻塞, 首次调用时启动发送协程)
+func enqueueWebhook(job *webhookJob) {
+	webhookOnce.Do(func() {
+		webhookQueue = make(chan *webhookJob, WebhookQueueSize)
+		for i := 0; i < WebhookWorkers; i++ {
+			go func() {
+				for job := range webhookQueue {
+					job.send()
+				}
+			}()
+		}
+	})
+
+	select {
+	case webhookQueue <- job:
+	default:
+		log.With(log.F{"url": job.hook.URL, "event": job.event}).Warn("Webhook dropped, queue is full")
+	}
+}
+
+// send 发送请求, 失败时按指数退避重新加入发送队列
+func (job *webhookJob) send() {
+	err := job.post()
+	if err == nil {
+		return
+	}
+
+	if job.attempt >= WebhookRetries {
+		log.With(log.F{"url": job.hook.URL, "event": job.event, "attempts": job.attempt + 1}).Error("Webhook failed: %s", err)
+		return
+	}
+	delay := WebhookBackoff << job.attempt
+	job.attempt++
+	time.AfterFunc(delay, func() { enqueueWebhook(job) })
+}
+
+// post 发送 POST 请求, 非 2xx 响应返回错误
+func (job *webhookJob) post() error {
+	req, err := http.NewRequest("POST", job.hook.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, job.event)
+	if job.hook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, job.hook.Sign(job.body))
+	}
+	for name, value := range job.hook.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := WebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -1,11 +1,30 @@
 package gou
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
 	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/kun/maps"
 	"github.com/yaoapp/kun/utils"
 	"github.com/yaoapp/xun/capsule"
@@ -19,6 +38,182 @@ func TestLoadModel(t *testing.T) {
 	assert.Equal(t, user.Source, source)
 }
 
+func TestLoadModelYAMLAndTOML(t *testing.T) {
+	mod := LoadModel(`
+name: YAML模型
+table:
+  name: yaml_test
+columns:
+  - { name: id, type: ID }
+  - { name: name, type: string, length: 80, index: true }
+option:
+  timestamps: true
+`, "yaml_test")
+	defer delete(Models, "yaml_test")
+	assert.Equal(t, "YAML模型", mod.MetaData.Name)
+	assert.Equal(t, "yaml_test", mod.MetaData.Table.Name)
+	assert.Equal(t, 80, mod.Columns["name"].Length)
+	assert.True(t, mod.Columns["name"].Index)
+	assert.Contains(t, mod.Columns, "created_at")
+
+	dir, err := ioutil.TempDir("", "gou-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := path.Join(dir, "toml_test.toml")
+	err = ioutil.WriteFile(filename, []byte(`
+name = "TOML模型"
+
+[table]
+name = "toml_test"
+
+[[columns]]
+name = "id"
+type = "ID"
+
+[[columns]]
+name = "name"
+type = "string"
+length = 80
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod = LoadModel("file://"+filename, "toml_test")
+	defer delete(Models, "toml_test")
+	assert.Equal(t, "TOML模型", mod.MetaData.Name)
+	assert.Equal(t, "toml_test", mod.MetaData.Table.Name)
+	assert.Equal(t, 80, mod.Columns["name"].Length)
+
+	_, err = LoadModelReturn("name: [", "yaml_error")
+	assert.NotNil(t, err)
+}
+
+func TestWatchDefinitions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gou-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	assert.NotNil(t, WatchDefinitions(dir)) // 非开发模式
+
+	filename := path.Join(dir, "watch_test.json")
+	source := `{"name": "%s", "table": { "name": "watch_test" }, "columns": [{ "name": "id", "type": "ID" }]}`
+	err = ioutil.WriteFile(filename, []byte(fmt.Sprintf(source, "监听")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod := LoadModel("file://"+filename, "watch_test")
+	defer delete(Models, "watch_test")
+
+	DevMode = true
+	defer func() { DevMode = false }()
+	defer StopWatchDefinitions()
+	assert.Nil(t, WatchDefinitions(dir))
+
+	err = ioutil.WriteFile(filename, []byte(fmt.Sprintf(source, "已更新")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(WatchDelay + 500*time.Millisecond)
+	assert.Equal(t, "已更新", mod.MetaData.Name)
+
+	err = ioutil.WriteFile(filename, []byte(`{"name": `), 0644) // 描述错误时保留原模型
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(WatchDelay + 500*time.Millisecond)
+	assert.Equal(t, "已更新", Select("watch_test").MetaData.Name)
+}
+
+func TestLoadModelEnv(t *testing.T) {
+	os.Setenv("GOU_TEST_TABLE_PREFIX", "env_")
+	defer os.Unsetenv("GOU_TEST_TABLE_PREFIX")
+	mod := LoadModel(`{
+		"name": "${GOU_TEST_MODEL_NAME:-环境变量}",
+		"table": { "name": "${GOU_TEST_TABLE_PREFIX}test", "comment": "$${literal}" },
+		"columns": [{ "name": "id", "type": "ID" }]
+	}`, "env_test")
+	defer delete(Models, "env_test")
+	assert.Equal(t, "环境变量", mod.MetaData.Name)
+	assert.Equal(t, "env_test", mod.MetaData.Table.Name)
+	assert.Equal(t, "${literal}", mod.MetaData.Table.Comment)
+}
+
+func TestLinkModels(t *testing.T) {
+	assert.Nil(t, LinkModels())
+
+	LoadModel(`{
+		"name": "关联检查",
+		"columns": [{ "name": "id", "type": "ID" }, { "name": "manu_id", "type": "integer" }],
+		"relations": { "manu": { "type": "hasOne", "model": "manus", "key": "id", "foreign": "manu_id" } }
+	}`, "link_test")
+	defer delete(Models, "link_test")
+	err := LinkModels()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "模型 link_test 关联 manu: 引用的模型 manus 尚未载入")
+
+	LoadModel(`{
+		"name": "关联检查",
+		"columns": [{ "name": "id", "type": "ID" }, { "name": "manu_id", "type": "integer" }],
+		"relations": { "manu": { "type": "hasOne", "model": "manu", "key": "id", "foreign": "manuid" } }
+	}`, "link_test")
+	err = LinkModels()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "外键 manuid 不是模型")
+	assert.Panics(t, func() { MustLinkModels() })
+}
+
+func TestLoadModelExtends(t *testing.T) {
+	LoadModel(`{
+		"name": "基础模型",
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "status", "type": "string", "length": 20, "default": "enabled" }
+		],
+		"indexes": [{ "name": "status_index", "columns": ["status"], "type": "index" }],
+		"option": { "timestamps": true }
+	}`, "extends_base")
+	defer delete(Models, "extends_base")
+	LoadModel(`{
+		"name": "标签混入",
+		"columns": [{ "name": "tags", "type": "json", "nullable": true }]
+	}`, "extends_mixin")
+	defer delete(Models, "extends_mixin")
+
+	mod := LoadModel(`{
+		"name": "继承模型",
+		"extends": "extends_base",
+		"mixins": ["extends_mixin"],
+		"table": { "name": "extends_test" },
+		"columns": [
+			{ "name": "status", "type": "string", "length": 40, "default": "draft" },
+			{ "name": "title", "type": "string" }
+		]
+	}`, "extends_test")
+	defer delete(Models, "extends_test")
+
+	names := []string{}
+	for _, column := range mod.MetaData.Columns {
+		names = append(names, column.Name)
+	}
+	assert.Equal(t, []string{"tags", "id", "status", "created_at", "updated_at", "title"}, names)
+	assert.Equal(t, "id", mod.PrimaryKey)
+	assert.Equal(t, 40, mod.Columns["status"].Length)
+	assert.Equal(t, "draft", mod.Columns["status"].Default)
+	assert.True(t, mod.MetaData.Option.Timestamps)
+	assert.Len(t, mod.MetaData.Indexes, 1)
+	assert.Equal(t, "extends_test", mod.MetaData.Table.Name)
+	assert.Equal(t, "继承模型", mod.MetaData.Name)
+
+	_, err := LoadModelReturn(`{ "extends": "extends_missing", "columns": [] }`, "extends_error")
+	assert.NotNil(t, err)
+}
+
 func TestModelReload(t *testing.T) {
 	user := Select("user")
 	user.Reload()
@@ -26,6 +221,88 @@ func TestModelReload(t *testing.T) {
 	assert.Equal(t, user.Name, "user")
 }
 
+func TestModelReloadPreserves(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "重新加载",
+		"table": { "name": "reload_test" },
+		"columns": [{ "name": "id", "type": "ID" }]
+	}`, "reload_test")
+	defer delete(Models, "reload_test")
+	mod.Scope("first", func(param *QueryParam) { param.Limit = 1 })
+	unsubscribe := mod.Subscribe(func(ChangeEvent) {})
+	defer unsubscribe()
+
+	mod.Source = `{
+		"name": "重新加载",
+		"table": { "name": "reload_test" },
+		"columns": [{ "name": "id", "type": "ID" }, { "name": "name", "type": "string" }]
+	}`
+	reloaded := mod.Reload()
+	assert.True(t, reloaded == mod)
+	assert.True(t, Select("reload_test") == mod)
+	assert.Contains(t, mod.Columns, "name")
+	assert.True(t, mod.Columns["name"].model == mod)
+	assert.NotPanics(t, func() {
+		param := QueryParam{Scopes: []string{"first"}}
+		mod.applyScopes(&param)
+		assert.Equal(t, 1, param.Limit)
+	})
+	assert.True(t, mod.hasSubscribers())
+}
+
+func TestModelDescribe(t *testing.T) {
+	names := ListModels()
+	assert.Contains(t, names, "user")
+	assert.Contains(t, names, "manu")
+	assert.True(t, sort.StringsAreSorted(names))
+
+	info := Select("user").Describe()
+	assert.Equal(t, "user", info.Name)
+	assert.Equal(t, "用户", info.Label)
+	assert.Equal(t, "id", info.PrimaryKey)
+	assert.Equal(t, len(Select("user").MetaData.Columns), len(info.Columns))
+
+	columns := map[string]ColumnInfo{}
+	for _, column := range info.Columns {
+		columns[column.Name] = column
+	}
+	assert.True(t, columns["id"].Primary)
+	assert.Equal(t, "string", columns["key"].Type)
+	assert.True(t, columns["key"].Unique)
+	assert.Contains(t, columns, "created_at")
+
+	assert.Equal(t, "manu", info.Relations["manu"].Name)
+	assert.Equal(t, "hasOne", info.Relations["manu"].Type)
+	assert.NotEmpty(t, info.Indexes)
+}
+
+func TestModelJSONSchema(t *testing.T) {
+	schema := maps.MapStr(Select("user").JSONSchema()).Dot()
+	assert.Equal(t, "object", schema.Get("type"))
+	assert.Equal(t, "用户", schema.Get("title"))
+	assert.Equal(t, true, schema.Get("properties.id.readOnly"))
+	assert.Equal(t, true, schema.Get("properties.created_at.readOnly"))
+	assert.Equal(t, "integer", schema.Get("properties.id.type"))
+	assert.Equal(t, 40, schema.Get("properties.name.maxLength")) // 取字段长度与 maxLength 校验中较小值
+	assert.Equal(t, "staff", schema.Get("properties.type.default"))
+	assert.Equal(t, "^1[3-9]\\d{9}$", schema.Get("properties.mobile.pattern"))
+	assert.Equal(t, true, schema.Get("properties.password.writeOnly"))
+	assert.Equal(t, 6, schema.Get("properties.password.minLength"))
+	assert.Equal(t, 18, schema.Get("properties.password.maxLength"))
+
+	raw := Select("user").JSONSchema()
+	properties := raw["properties"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"admin", "staff", "user"}, properties["type"].(map[string]interface{})["enum"])
+	assert.Equal(t, 4, len(properties["password"].(map[string]interface{})["allOf"].([]interface{})))
+	assert.Equal(t, []interface{}{"string", "null"}, properties["idcard"].(map[string]interface{})["type"])
+
+	required := raw["required"].([]string)
+	assert.Contains(t, required, "name")
+	assert.NotContains(t, required, "id")
+	assert.NotContains(t, required, "type")
+	assert.NotContains(t, required, "idcard")
+}
+
 func TestModelMigrate(t *testing.T) {
 	for name, mod := range Models {
 		utils.Dump(name)
@@ -39,6 +316,38 @@ func TestModelMustFind(t *testing.T) {
 	assert.Equal(t, user.Dot().Get("extra.sex"), "男")
 }
 
+func TestModelMustFresh(t *testing.T) {
+	user := Select("user")
+	row := maps.MapStr{"id": 1, "mobile": "changed"}
+	fresh := user.MustFresh(row, QueryParam{Withs: map[string]With{"manu": {}}})
+	assert.Equal(t, "13900001111", fresh.Get("mobile"))
+	assert.Equal(t, "男", fresh.Dot().Get("extra.sex"))
+	assert.NotNil(t, fresh.Dot().Get("manu.name"))
+	assert.Equal(t, "changed", row.Get("mobile"))
+
+	_, err := user.Fresh(maps.MapStr{"mobile": "13900001111"}, QueryParam{})
+	assert.Contains(t, err.Error(), "主键")
+}
+
+func TestModelMustPaginateMaxPageSize(t *testing.T) {
+	MaxPageSize, DefaultPageSize = 2, 1
+	defer func() { MaxPageSize, DefaultPageSize = 1000, 20 }()
+
+	user := Select("user")
+	res := user.MustPaginate(QueryParam{}, 1, 100)
+	assert.Equal(t, 2, res.Get("pagesize"))
+	assert.Equal(t, 2, len(res.Get("data").([]maps.MapStr)))
+
+	res = user.MustPaginate(QueryParam{}, 1, 0)
+	assert.Equal(t, 1, res.Get("pagesize"))
+	assert.Equal(t, 1, len(res.Get("data").([]maps.MapStr)))
+
+	user.MetaData.Option.MaxPageSize = 3
+	defer func() { user.MetaData.Option.MaxPageSize = 0 }()
+	res = user.MustPaginate(QueryParam{}, 1, 100)
+	assert.Equal(t, 3, res.Get("pagesize"))
+}
+
 func TestModelMustFindWiths(t *testing.T) {
 	user := Select("user").MustFind(1,
 		QueryParam{
@@ -102,6 +411,36 @@ func TestModelMustGetWiths(t *testing.T) {
 	assert.Equal(t, userDot.Get("data.1.id"), int64(2))
 }
 
+func TestModelMustRaw(t *testing.T) {
+	user := Select("user")
+	rows := user.MustRaw(fmt.Sprintf("SELECT id, name, extra FROM %s WHERE id = ?", user.MetaData.Table.Name), 1)
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, 1, any.Of(rows[0].Get("id")).CInt())
+	assert.Equal(t, "男", rows[0].Dot().Get("extra.sex"))
+
+	_, err := user.Raw("SELECT * FROM not_exists_table")
+	assert.NotNil(t, err)
+}
+
+func TestModelSlowQuery(t *testing.T) {
+	var buf bytes.Buffer
+	SetModelLogger(&buf, log.TraceLevel)
+	SetSlowQueryThreshold(time.Nanosecond)
+	defer func() {
+		SetSlowQueryThreshold(0)
+		SetModelLogger(os.Stdout, log.TraceLevel)
+	}()
+
+	Select("user").MustGet(QueryParam{Limit: 1})
+	assert.Contains(t, buf.String(), "慢查询")
+	assert.Contains(t, buf.String(), "duration")
+
+	buf.Reset()
+	SetSlowQueryThreshold(0)
+	Select("user").MustGet(QueryParam{Limit: 1})
+	assert.NotContains(t, buf.String(), "慢查询")
+}
+
 func TestModelMustPaginate(t *testing.T) {
 	user := Select("user").MustPaginate(QueryParam{}, 1, 2)
 	userDot := user.Dot()
@@ -112,6 +451,82 @@ func TestModelMustPaginate(t *testing.T) {
 	assert.Equal(t, userDot.Get("data.1.id"), int64(2))
 }
 
+func TestModelPaginateCtx(t *testing.T) {
+	user := Select("user")
+	param := QueryParam{Select: []interface{}{"id", "name"}, Withs: map[string]With{"addresses": {}}}
+	for _, page := range []int{1, 2} {
+		res, err := user.PaginateCtx(context.Background(), param, page, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, user.MustPaginate(param, page, 2), res)
+	}
+
+	PaginateSnapshot = true // 统计与数据查询在同一事务中执行
+	res, err := user.PaginateCtx(context.Background(), param, 1, 2)
+	PaginateSnapshot = false
+	assert.Nil(t, err)
+	assert.Equal(t, user.MustPaginate(param, 1, 2), res)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = user.PaginateCtx(ctx, param, 1, 2)
+	assert.Equal(t, context.Canceled, err)
+
+	// 统计查询后取消, 不再执行数据查询
+	stack := NewQueryStack(QueryParam{Model: "user"})
+	_, err = stack.PaginateCtx(&cancelAfter{Context: context.Background(), checks: 1}, 1, 2)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, stack.Statements)
+}
+
+func TestModelPaginateEstimate(t *testing.T) {
+	user := Select("user")
+
+	// 无查询条件时读取统计信息估算 (SQLite 不支持, 使用 COUNT 查询), 估算总数不小于已读取的数据
+	res := user.MustPaginate(QueryParam{Count: PaginateEstimate, WithTrashed: true}, 1, 2)
+	assert.Equal(t, 2, len(res.Get("data").([]maps.MapStrAny)))
+	assert.True(t, any.Of(res.Get("total")).CInt() > 2)
+	if user.Driver == "sqlite3" {
+		assert.Equal(t, 3, res.Get("total"))
+	}
+
+	// 有查询条件 (含软删除条件) 时精确统计
+	res = user.MustPaginate(QueryParam{Count: PaginateEstimate, Wheres: []QueryWhere{{Column: "id", OP: "le", Value: 2}}}, 1, 1)
+	assert.Equal(t, 2, res.Get("total"))
+	res = user.MustPaginate(QueryParam{Count: PaginateEstimate}, 1, 2)
+	assert.Equal(t, 3, res.Get("total"))
+}
+
+// cancelAfter 检查 checks 次后返回 context.Canceled
+type cancelAfter struct {
+	context.Context
+	checks int
+}
+
+func (ctx *cancelAfter) Err() error {
+	if ctx.checks <= 0 {
+		return context.Canceled
+	}
+	ctx.checks--
+	return nil
+}
+
+func TestModelMustPaginateFormat(t *testing.T) {
+	user := Select("user").MustPaginate(QueryParam{}, 2, 2)
+	assert.Equal(t, 2, user.Get("last_page"))
+	assert.Equal(t, 1, user.Get("prev"))
+	assert.Equal(t, 3, user.Get("from"))
+	assert.Equal(t, 3, user.Get("to"))
+
+	PaginateFormat = map[string]string{"pagesize": "per_page", "pagecnt": ""}
+	defer func() { PaginateFormat = map[string]string{} }()
+	user = Select("user").MustPaginate(QueryParam{}, 1, 2)
+	assert.Equal(t, 2, user.Get("per_page"))
+	assert.False(t, user.Has("pagesize"))
+	assert.False(t, user.Has("pagecnt"))
+	assert.Equal(t, 1, user.Get("from"))
+	assert.Equal(t, 2, user.Get("to"))
+}
+
 func TestModelMustPaginateWiths(t *testing.T) {
 	user := Select("user").MustPaginate(QueryParam{
 		Select: []interface{}{"id", "name", "mobile", "extra"},
@@ -202,6 +617,172 @@ func TestModelMustPaginateWithsWheresOrder(t *testing.T) {
 
 }
 
+func TestModelWhereGroups(t *testing.T) {
+	user := Select("user")
+	rows := user.MustGet(QueryParam{
+		Wheres: []QueryWhere{
+			Or(
+				QueryWhere{Column: "mobile", Value: "13900002222"},
+				QueryWhere{Column: "mobile", Value: "13900001111"},
+			),
+		},
+		Orders: []QueryOrder{{Column: "id"}},
+	})
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, int64(1), rows[0].Get("id"))
+	assert.Equal(t, int64(2), rows[1].Get("id"))
+
+	// (mobile = 13900002222 OR mobile = 13900001111) AND id = 1
+	rows = user.MustGet(QueryParam{
+		Wheres: []QueryWhere{
+			And(
+				Or(
+					QueryWhere{Column: "mobile", Value: "13900002222"},
+					QueryWhere{Column: "mobile", Value: "13900001111"},
+				),
+				QueryWhere{Column: "id", Value: 1},
+			),
+		},
+	})
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, int64(1), rows[0].Get("id"))
+
+	// (id = 1 AND mobile = 13900002222) OR id = 3
+	rows = user.MustGet(QueryParam{
+		Wheres: []QueryWhere{
+			Or(
+				And(
+					QueryWhere{Column: "id", Value: 1},
+					QueryWhere{Column: "mobile", Value: "13900002222"},
+				),
+				QueryWhere{Column: "id", Value: 3},
+			),
+		},
+	})
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, int64(3), rows[0].Get("id"))
+
+	where := Or(QueryWhere{Column: "id", Value: 1, Method: "orwhere"}, QueryWhere{Column: "id", Value: 2})
+	assert.Equal(t, "where", where.Wheres[0].Method)
+	assert.Equal(t, "orwhere", where.Wheres[1].Method)
+}
+
+func TestModelTopLevelOrWhere(t *testing.T) {
+	user := Select("user").Scope("id_one", func(param *QueryParam) {
+		param.Wheres = append(param.Wheres, QueryWhere{Column: "id", Value: 1})
+	})
+
+	// 顶层 orwhere
+	rows := user.MustGet(QueryParam{
+		Wheres: []QueryWhere{
+			{Column: "mobile", Value: "13900002222"},
+			{Column: "mobile", Method: "orwhere", Value: "13900001111"},
+		},
+		Orders: []QueryOrder{{Column: "id"}},
+	})
+	assert.Equal(t, 2, len(rows))
+
+	// 分组 orwhere
+	grouped := user.MustGet(QueryParam{
+		Wheres: []QueryWhere{
+			{Wheres: []QueryWhere{
+				{Column: "mobile", Value: "13900002222"},
+				{Column: "mobile", Method: "orwhere", Value: "13900001111"},
+			}},
+		},
+		Orders: []QueryOrder{{Column: "id"}},
+	})
+	assert.Equal(t, rows, grouped)
+
+	// 追加的查询范围与整组条件 AND 连接: (mobile = 13900002222 OR mobile = 13900001111) AND id = 1
+	rows = user.MustGet(QueryParam{
+		Wheres: []QueryWhere{
+			{Column: "mobile", Value: "13900002222"},
+			{Column: "mobile", Method: "orwhere", Value: "13900001111"},
+		},
+		Scopes: []string{"id_one"},
+	})
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, int64(1), rows[0].Get("id"))
+
+	// 软删除条件与整组条件 AND 连接
+	capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"deleted_at": time.Now()})
+	defer capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"deleted_at": nil})
+	rows = user.MustGet(QueryParam{
+		Wheres: []QueryWhere{
+			{Column: "mobile", Value: "13900002222"},
+			{Column: "mobile", Method: "orwhere", Value: "13900001111"},
+		},
+	})
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, int64(2), rows[0].Get("id"))
+}
+
+func TestModelWhereCoercion(t *testing.T) {
+	user := Select("user")
+	assert.Equal(t, int64(1), user.fliterWhereValue("id", "", "1"))
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, user.fliterWhereValue("id", "in", "1,2"))
+	assert.Equal(t, []interface{}{int64(1), "x"}, user.fliterWhereValue("id", "in", []string{"1", "x"}))
+	assert.Equal(t, int64(10), user.fliterWhereValue("balance", "", " 10 "))
+	assert.Equal(t, 1.5, (&Column{Type: "decimal"}).coerce("1.5"))
+	assert.Equal(t, true, (&Column{Type: "boolean"}).coerce("true"))
+	assert.Equal(t, "abc", user.fliterWhereValue("id", "", "abc"))
+	assert.Equal(t, "%1%", user.fliterWhereValue("id", "match", "%1%"))
+	assert.Equal(t, "13900001111", user.fliterWhereValue("mobile", "", "13900001111"))
+	assert.Equal(t, "2021-06-01 08:30:00", user.fliterWhereValue("created_at", "", "2021-06-01T08:30:00"))
+
+	rows := user.MustGet(QueryParam{Wheres: []QueryWhere{{Column: "id", Value: "1"}}})
+	assert.Equal(t, 1, len(rows))
+	rows = user.MustGet(QueryParam{Wheres: []QueryWhere{{Column: "id", OP: "in", Value: "1,2"}}})
+	assert.Equal(t, 2, len(rows))
+}
+
+func TestModelMustExists(t *testing.T) {
+	user := Select("user")
+	assert.True(t, user.MustExists(QueryParam{Wheres: []QueryWhere{{Column: "mobile", Value: "13900001111"}}}))
+	assert.False(t, user.MustExists(QueryParam{Wheres: []QueryWhere{{Column: "mobile", Value: "13900009999"}}}))
+	assert.True(t, Select("manu").MustExists(QueryParam{Wheres: []QueryWhere{{Column: "id", Value: 1}}}))
+
+	// 已软删除的记录
+	capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"deleted_at": time.Now()})
+	defer capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"deleted_at": nil})
+	assert.False(t, user.MustExists(QueryParam{Wheres: []QueryWhere{{Column: "id", Value: 1}}}))
+	assert.True(t, user.MustExists(QueryParam{Wheres: []QueryWhere{{Column: "id", Value: 1}}, WithTrashed: true}))
+}
+
+func TestModelMustPluck(t *testing.T) {
+	user := Select("user")
+	param := QueryParam{Orders: []QueryOrder{{Column: "id"}}}
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, user.MustPluck("id", param))
+	assert.Equal(t, []int{1, 2, 3}, user.MustPluckInt("id", param))
+	assert.Equal(t, []string{"13900001111", "13900002222"}, user.MustPluckString("mobile", QueryParam{
+		Wheres: []QueryWhere{{Column: "id", OP: "in", Value: []interface{}{1, 2}}},
+		Orders: []QueryOrder{{Column: "id"}},
+	}))
+
+	extras := user.MustPluck("extra", QueryParam{Wheres: []QueryWhere{{Column: "id", Value: 1}}})
+	assert.Equal(t, "男", maps.MapStr{"extra": extras[0]}.Dot().Get("extra.sex"))
+
+	_, err := user.Pluck("not_exists", param)
+	assert.NotNil(t, err)
+	_, err = user.PluckInt("name", param)
+	assert.NotNil(t, err)
+}
+
+func TestModelMustPluckMap(t *testing.T) {
+	user := Select("user")
+	mobiles := user.MustPluckMap("id", "mobile", QueryParam{})
+	assert.Equal(t, 3, len(mobiles))
+	assert.Equal(t, "13900001111", mobiles.Get("1"))
+	assert.Equal(t, "13900002222", mobiles.Get("2"))
+
+	ids := user.MustPluckMap("mobile", "id", QueryParam{Wheres: []QueryWhere{{Column: "id", Value: 1}}})
+	assert.Equal(t, maps.MapStr{"13900001111": int64(1)}, ids)
+
+	_, err := user.PluckMap("id", "not_exists", QueryParam{})
+	assert.NotNil(t, err)
+}
+
 func TestModelMustCreate(t *testing.T) {
 	user := Select("user")
 	id := user.MustCreate(maps.MapStr{
@@ -229,6 +810,177 @@ func TestModelMustCreate(t *testing.T) {
 
 }
 
+func TestModelPrepare(t *testing.T) {
+	user := Select("user")
+	input := maps.MapStr{
+		"name":     "预演用户",
+		"mobile":   "13900007777",
+		"password": "qV@uT1DI",
+		"extra":    maps.MapStr{"sex": "女"},
+		"unknown":  "ignored",
+	}
+	row, errs := user.Prepare("create", input)
+	assert.Empty(t, errs)
+	assert.Equal(t, "预演用户", row.Get("name"))
+	assert.NotEqual(t, "qV@uT1DI", row.Get("password"))
+	assert.False(t, row.Has("unknown"))
+	assert.True(t, row.Has("created_at"))
+	assert.Equal(t, "qV@uT1DI", input.Get("password"))
+
+	row, errs = user.Prepare("save", maps.MapStr{"id": 1, "balance": 200, "created_at": "2021-01-01 00:00:00"})
+	assert.Empty(t, errs)
+	assert.False(t, row.Has("id"))
+	assert.False(t, row.Has("created_at"))
+	assert.True(t, row.Has("updated_at"))
+
+	row, errs = user.Prepare("update", maps.MapStr{"type": 1})
+	assert.Nil(t, row)
+	assert.NotEmpty(t, errs)
+
+	assert.Panics(t, func() { user.Prepare("delete", maps.MapStr{}) })
+}
+
+func TestModelValidateRequired(t *testing.T) {
+	user := Select("user")
+	errs := user.ValidateRequired(maps.MapStr{"name": "必填校验", "password": "qV@uT1DI"})
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "mobile", errs[0].Column)
+	assert.Equal(t, []string{"手机号不能为空"}, errs[0].Messages)
+	errs = user.ValidateRequired(maps.MapStr{"name": "必填校验", "password": "qV@uT1DI", "mobile": nil}, "en")
+	assert.Equal(t, []string{"手机号 is required"}, errs[0].Messages)
+
+	assert.Panics(t, func() { user.Create(maps.MapStr{"name": "必填校验", "password": "qV@uT1DI"}) })
+	assert.Panics(t, func() { user.MustSave(maps.MapStr{"name": "必填校验", "password": "qV@uT1DI"}) })
+
+	// 更新数据允许只提供部分字段
+	assert.Nil(t, user.Update(1, maps.MapStr{"balance": 10}))
+	user.MustSave(maps.MapStr{"id": 1, "balance": 0})
+
+	_, errs = user.Prepare("create", maps.MapStr{"name": "必填校验"})
+	assert.Equal(t, 2, len(errs))
+}
+
+func TestModelErrors(t *testing.T) {
+	user := Select("user")
+	var notFound *ErrNotFound
+	_, err := user.Find(100, QueryParam{})
+	assert.True(t, errors.As(err, &notFound))
+	assert.Equal(t, 100, notFound.ID)
+	assert.Equal(t, 404, ErrorCode(err))
+	assert.True(t, errors.As(user.Update(100, maps.MapStr{"name": "不存在"}), &notFound))
+
+	// 校验失败抛出异常; 批量保存按行返回 ErrValidation
+	assert.Panics(t, func() { user.Create(maps.MapStr{"name": "错误类型", "mobile": 1024}) })
+	res := user.EachSaveResult([]map[string]interface{}{{"name": "错误类型", "mobile": 1024}})
+	assert.Equal(t, "mobile", res.Failed[0].Column)
+	_, err = user.save(maps.MapStr{"name": "错误类型", "mobile": 1024})
+	var validation *ErrValidation
+	assert.True(t, errors.As(err, &validation))
+	assert.Equal(t, 400, ErrorCode(err))
+	assert.Equal(t, "输入参数错误", err.Error())
+
+	row := func(mobile string) maps.MapStr {
+		return maps.MapStr{
+			"name":     "错误类型",
+			"manu_id":  2,
+			"type":     "user",
+			"idcard":   "23082619820207006X",
+			"mobile":   mobile,
+			"password": "qV@uT1DI",
+			"key":      "ErrKey01",
+			"secret":   "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN",
+		}
+	}
+	defer capsule.Query().Table(user.MetaData.Table.Name).Where("key", "ErrKey01").Delete()
+	user.MustCreate(row("13900005555"))
+	_, err = user.Create(row("13900006666")) // 数据库唯一键冲突
+	var conflict *ErrConflict
+	assert.True(t, errors.As(err, &conflict))
+	assert.NotNil(t, conflict.Err)
+	assert.Equal(t, 409, ErrorCode(err))
+
+	assert.Equal(t, 409, ErrorCode(&ErrStale{Model: "user", ID: 1}))
+	assert.Equal(t, 500, ErrorCode(fmt.Errorf("error")))
+}
+
+func TestModelMustCreateReturning(t *testing.T) {
+	user := Select("user")
+	row := user.MustCreateReturning(maps.MapStr{
+		"name":     "用户创建",
+		"manu_id":  2,
+		"type":     "user",
+		"idcard":   "23082619820207006X",
+		"mobile":   "13900004444",
+		"password": "qV@uT1DI",
+		"key":      "XZ12MiPp",
+		"secret":   "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN",
+		"extra":    maps.MapStr{"sex": "女"},
+	})
+
+	// 清空数据
+	capsule.Query().Table(user.MetaData.Table.Name).Where("id", row.Get("id")).Delete()
+
+	assert.NotNil(t, row.Get("id"))
+	assert.NotNil(t, row.Get("created_at"))
+	assert.Equal(t, "用户创建", row.Get("name"))
+	assert.Equal(t, "enabled", row.Get("status")) // 数据库默认值
+	assert.Equal(t, "女", row.Dot().Get("extra.sex"))
+}
+
+func TestModelMustFirstOrCreate(t *testing.T) {
+	user := Select("user")
+	match := maps.MapStr{"manu_id": 2, "mobile": "13900005555"}
+	defaults := maps.MapStr{
+		"name":     "用户创建",
+		"type":     "user",
+		"idcard":   "23082619820207006X",
+		"password": "qV@uT1DI",
+		"key":      "XZ12MiPq",
+		"secret":   "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN",
+	}
+	row, created := user.MustFirstOrCreate(match, defaults)
+	defer capsule.Query().Table(user.MetaData.Table.Name).Where("id", row.Get("id")).Delete()
+	assert.True(t, created)
+	assert.Equal(t, "用户创建", row.Get("name"))
+	assert.Equal(t, "13900005555", row.Get("mobile"))
+
+	again, created := user.MustFirstOrCreate(match, maps.MapStr{"name": "不会写入"})
+	assert.False(t, created)
+	assert.Equal(t, row.Get("id"), again.Get("id"))
+	assert.Equal(t, "用户创建", again.Get("name"))
+
+	updated, created := user.MustUpdateOrCreate(match, maps.MapStr{"name": "用户更新"})
+	assert.False(t, created)
+	assert.Equal(t, row.Get("id"), updated.Get("id"))
+	assert.Equal(t, "用户更新", updated.Get("name"))
+
+	_, _, err := user.FirstOrCreate(maps.MapStr{"not_exists": 1}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestModelMustUpdateReturning(t *testing.T) {
+	user := Select("user")
+	before := user.MustPluckMap("id", "name", QueryParam{})
+	defer func() {
+		for id, name := range before {
+			capsule.Query().Table(user.MetaData.Table.Name).Where("id", id).Update(maps.MapStr{"name": name})
+		}
+	}()
+
+	rows := user.MustUpdateReturning(QueryParam{
+		Wheres: []QueryWhere{{Column: "id", OP: "in", Value: []interface{}{1, 2}}},
+	}, maps.MapStr{"name": "批量更新"})
+	assert.Equal(t, 2, len(rows))
+	for _, row := range rows {
+		assert.Equal(t, "批量更新", row.Get("name"))
+		assert.NotNil(t, row.Get("updated_at"))
+	}
+	assert.Equal(t, before.Get("3"), user.MustFind(3, QueryParam{}).Get("name"))
+
+	rows = user.MustUpdateReturning(QueryParam{Wheres: []QueryWhere{{Column: "id", Value: 999}}}, maps.MapStr{"name": "不存在"})
+	assert.Equal(t, 0, len(rows))
+}
+
 func TestModelMustSaveNew(t *testing.T) {
 	user := Select("user")
 	id := user.MustSave(maps.MapStr{
@@ -279,6 +1031,126 @@ func TestModelMustUpdate(t *testing.T) {
 	assert.Equal(t, any.Of(row.Get("balance")).CInt(), 200)
 }
 
+func TestModelPartialUpdate(t *testing.T) {
+	user := Select("user")
+	before := user.MustFind(1, QueryParam{})
+	defer capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"balance": 0})
+
+	input := maps.MapStr{"balance": 99}
+	user.MustUpdate(1, input)
+	assert.Equal(t, maps.MapStr{"balance": 99}, input) // 不修改调用方数据
+
+	after := user.MustFind(1, QueryParam{})
+	assert.Equal(t, 99, any.Of(after.Get("balance")).CInt())
+	for name, value := range before {
+		if name == "balance" || name == "updated_at" {
+			continue
+		}
+		assert.Equal(t, value, after.Get(name), name)
+	}
+
+	input = maps.MapStr{"id": 1, "balance": 66}
+	user.MustSave(input)
+	assert.Equal(t, maps.MapStr{"id": 1, "balance": 66}, input)
+	after = user.MustFind(1, QueryParam{})
+	assert.Equal(t, 66, any.Of(after.Get("balance")).CInt())
+	assert.Equal(t, before.Get("name"), after.Get("name"))
+	assert.Equal(t, before.Get("mobile"), after.Get("mobile"))
+	assert.Equal(t, before.Dot().Get("extra.sex"), after.Dot().Get("extra.sex"))
+}
+
+func TestModelMustUpdateMany(t *testing.T) {
+	user := Select("user")
+	defer capsule.Query().Table(user.MetaData.Table.Name).WhereIn("id", []interface{}{1, 2, 3}).Update(maps.MapStr{"balance": 0})
+
+	effect := user.MustUpdateMany("id", map[interface{}]maps.MapStr{
+		1: {"balance": 10},
+		2: {"balance": 20},
+		3: {"balance": 10},
+	})
+	assert.Equal(t, 3, effect)
+
+	balances := user.MustPluckMap("id", "balance", QueryParam{Wheres: []QueryWhere{{Column: "id", OP: "in", Value: []interface{}{1, 2, 3}}}})
+	assert.Equal(t, 10, any.Of(balances.Get("1")).CInt())
+	assert.Equal(t, 20, any.Of(balances.Get("2")).CInt())
+	assert.Equal(t, 10, any.Of(balances.Get("3")).CInt())
+
+	effect = user.MustUpdateMany("id", map[interface{}]maps.MapStr{999: {"balance": 10}})
+	assert.Equal(t, 0, effect)
+
+	_, err := user.UpdateMany("not_exists", map[interface{}]maps.MapStr{1: {"balance": 10}})
+	assert.Error(t, err)
+}
+
+func TestModelMustTouch(t *testing.T) {
+	user := Select("user")
+	capsule.Query().Table(user.MetaData.Table.Name).WhereIn("id", []interface{}{1, 2, 3}).Update(maps.MapStr{"updated_at": "2000-01-01 00:00:00"})
+
+	user.MustTouch(1)
+	row := user.MustFind(1, QueryParam{})
+	assert.NotContains(t, fmt.Sprintf("%v", row.Get("updated_at")), "2000-01-01")
+	user.MustTouch(1) // 同一秒内重复更新
+
+	assert.Equal(t, 2, user.MustTouchWhere(QueryParam{Wheres: []QueryWhere{{Column: "id", OP: "in", Value: []interface{}{2, 3}}}}))
+	assert.Error(t, user.Touch(999))
+
+	mod := LoadModel(`{
+		"name": "无时间戳",
+		"table": { "name": "touch_test" },
+		"columns": [{ "name": "id", "type": "ID" }]
+	}`, "touch_test")
+	defer delete(Models, "touch_test")
+	assert.Contains(t, mod.Touch(1).Error(), "timestamps")
+}
+
+func TestModelStats(t *testing.T) {
+	ResetStats()
+	defer ResetStats()
+
+	user := Select("user")
+	rows := user.MustGet(QueryParam{Limit: 2})
+	stat := Stats()["user"]
+	assert.Equal(t, int64(1), stat.Queries)
+	assert.Equal(t, int64(len(rows)), stat.RowsRead)
+	assert.Equal(t, int64(0), stat.RowsWritten)
+
+	capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"updated_at": "2000-01-01 00:00:00"})
+	user.MustTouch(1)
+	stat = Stats()["user"]
+	assert.Equal(t, int64(2), stat.Queries)
+	assert.Equal(t, int64(1), stat.RowsWritten)
+	assert.True(t, stat.Duration > 0)
+
+	ResetStats()
+	assert.Equal(t, 0, len(Stats()))
+}
+
+func TestModelMustIncrement(t *testing.T) {
+	user := Select("user")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user.MustIncrement(1, "balance", 2)
+		}()
+	}
+	wg.Wait()
+	balance := any.Of(user.MustFind(1, QueryParam{}).Get("balance")).CInt()
+
+	user.MustDecrement(1, "balance", 5, maps.MapStr{"status": "enabled"})
+	row := user.MustFind(1, QueryParam{})
+
+	// 恢复数据
+	capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"balance": 0})
+	assert.Equal(t, 20, balance)
+	assert.Equal(t, 15, any.Of(row.Get("balance")).CInt())
+	assert.Equal(t, "enabled", row.Get("status"))
+
+	assert.NotNil(t, user.Increment(1, "undefined", 1))
+	assert.NotNil(t, user.Increment(1, "balance", "1; drop table user"))
+}
+
 func TestModelMustUpdateWhere(t *testing.T) {
 	user := Select("user")
 	effect := user.MustUpdateWhere(
@@ -415,16 +1287,395 @@ func TestModelMustDestroyWhere(t *testing.T) {
 	assert.Equal(t, effect, 3)
 }
 
-func TestModelMustEachSave(t *testing.T) {
+func TestModelDeleteMany(t *testing.T) {
+	columns := []string{"name", "manu_id", "type", "idcard", "mobile", "password", "key", "secret", "status"}
+	rows := [][]interface{}{
+		{"用户创建1", 5, "user", "23082619820207006X", "13900004444", "qV@uT1DI", "XZ12MiP1", "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN", "enabled"},
+		{"用户创建2", 5, "user", "33082619820207006X", "13900005555", "qV@uT1DI", "XZ12MiP2", "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN", "enabled"},
+		{"用户创建3", 5, "user", "43082619820207006X", "13900006666", "qV@uT1DI", "XZ12MiP3", "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN", "enabled"},
+	}
+
 	user := Select("user")
-	ids := user.MustEachSave([]map[string]interface{}{
-		{"id": 1, "balance": 200},
-		{
-			"name":     "用户创建",
-			"manu_id":  2,
-			"type":     "user",
-			"idcard":   "23082619820207006X",
-			"mobile":   "13900004444",
+	user.MustInsert(columns, rows)
+	defer capsule.Query().Table(user.MetaData.Table.Name).Where("name", "like", "用户创建%").Delete()
+
+	ids := []interface{}{}
+	for _, row := range user.MustGet(QueryParam{Select: []interface{}{"id"}, Wheres: []QueryWhere{{Column: "manu_id", Value: 5}}}) {
+		ids = append(ids, row.Get("id"))
+	}
+	assert.Equal(t, 3, len(ids))
+
+	// 空主键列表不删除任何数据
+	assert.Equal(t, 0, user.MustDeleteMany(nil))
+	assert.Equal(t, 0, user.MustDestroyMany([]interface{}{}))
+
+	assert.Equal(t, 2, user.MustDeleteMany(ids[:2]))
+	res := user.MustGet(QueryParam{Select: []interface{}{"id"}, Wheres: []QueryWhere{{Column: "manu_id", Value: 5}}})
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, ids[2], res[0].Get("id"))
+
+	user.MustDestroyMany(ids)
+	res = user.MustGet(QueryParam{Select: []interface{}{"id"}, Wheres: []QueryWhere{{Column: "manu_id", Value: 5}}, WithTrashed: true})
+	assert.Equal(t, 0, len(res))
+}
+
+func TestModelDefaultOrder(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "默认排序",
+		"table": { "name": "default_order_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "name", "type": "string", "length": 40 },
+			{ "name": "sort", "type": "integer" }
+		],
+		"default_order": "sort desc, id"
+	}`, "default_order_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("default_order_test")
+		delete(Models, "default_order_test")
+	}()
+
+	mod.MustInsert([]string{"name", "sort"}, [][]interface{}{{"a", 1}, {"b", 3}, {"c", 2}, {"d", 3}})
+	names := func(rows []maps.MapStr) []interface{} {
+		res := []interface{}{}
+		for _, row := range rows {
+			res = append(res, row.Get("name"))
+		}
+		return res
+	}
+
+	assert.Equal(t, []interface{}{"b", "d", "c", "a"}, names(mod.MustGet(QueryParam{})))
+	assert.Equal(t, []interface{}{"a", "b", "c", "d"}, names(mod.MustGet(QueryParam{Orders: []QueryOrder{{Column: "id"}}})))
+	page := mod.MustPaginate(QueryParam{}, 1, 2)
+	assert.Equal(t, []interface{}{"b", "d"}, names(page.Get("data").([]maps.MapStr)))
+
+	_, err := LoadModelReturn(`{"name": "默认排序", "table": { "name": "default_order_test" }, "columns": [{ "name": "id", "type": "ID" }], "default_order": "undefined desc"}`, "default_order_error")
+	assert.NotNil(t, err)
+	_, err = LoadModelReturn(`{"name": "默认排序", "table": { "name": "default_order_test" }, "columns": [{ "name": "id", "type": "ID" }], "default_order": "id down"}`, "default_order_error")
+	assert.NotNil(t, err)
+}
+
+func TestModelWebhook(t *testing.T) {
+	WebhookBackoff = 10 * time.Millisecond
+	defer func() { WebhookBackoff = time.Second }()
+
+	type request struct {
+		event     string
+		signature string
+		body      []byte
+	}
+	requests := make(chan request, 10)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 { // 首次请求失败, 重试后成功
+			w.WriteHeader(500)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{event: r.Header.Get(WebhookEventHeader), signature: r.Header.Get(WebhookSignatureHeader), body: body}
+	}))
+	defer server.Close()
+
+	mod := LoadModel(fmt.Sprintf(`{
+		"name": "数据变更通知",
+		"table": { "name": "webhook_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "name", "type": "string", "length": 40 },
+			{ "name": "token", "type": "string", "length": 40, "nullable": true, "hidden": true }
+		],
+		"webhooks": [{ "on": ["create"], "url": "%s", "secret": "webhook-secret" }]
+	}`, server.URL), "webhook_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("webhook_test")
+		delete(Models, "webhook_test")
+	}()
+
+	id := mod.MustCreate(maps.MapStr{"name": "a", "token": "secret-token"})
+	mod.MustUpdate(id, maps.MapStr{"name": "b"}) // 未订阅 update
+
+	select {
+	case req := <-requests:
+		assert.Equal(t, "webhook_test.create", req.event)
+		assert.Equal(t, Webhook{Secret: "webhook-secret"}.Sign(req.body), req.signature)
+		event := ChangeEvent{}
+		assert.Nil(t, jsoniter.Unmarshal(req.body, &event))
+		assert.Equal(t, EventCreate, event.Op)
+		assert.Equal(t, "a", event.After.Get("name"))
+		assert.False(t, event.After.Has("token"))
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "webhook not received")
+	}
+
+	select {
+	case req := <-requests:
+		assert.Fail(t, "unexpected webhook", req.event)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	_, err := LoadModelReturn(`{"name": "数据变更通知", "table": { "name": "webhook_test" }, "columns": [{ "name": "id", "type": "ID" }], "webhooks": [{ "url": "ftp://example.com" }]}`, "webhook_error")
+	assert.NotNil(t, err)
+	_, err = LoadModelReturn(`{"name": "数据变更通知", "table": { "name": "webhook_test" }, "columns": [{ "name": "id", "type": "ID" }], "webhooks": [{ "on": ["insert"], "url": "http://example.com" }]}`, "webhook_error")
+	assert.NotNil(t, err)
+}
+
+func TestModelFields(t *testing.T) {
+	user := Select("user")
+	keys := func(value interface{}) []string {
+		res := []string{}
+		row, ok := value.(maps.MapStr)
+		if !ok {
+			row = maps.MapStr(value.(map[string]interface{}))
+		}
+		for key := range row {
+			res = append(res, key)
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	rows := user.MustGet(QueryParam{Fields: []string{"id", "name", "manu.name", "addresses.location", "undefined"}, Limit: 2})
+	assert.Equal(t, 2, len(rows))
+	for _, row := range rows {
+		assert.Equal(t, []string{"addresses", "id", "manu", "name"}, keys(row))
+		assert.Equal(t, []string{"name"}, keys(row.Get("manu")))
+		switch addresses := row.Get("addresses").(type) {
+		case []maps.MapStr:
+			for _, address := range addresses {
+				assert.Equal(t, []string{"location"}, keys(address))
+			}
+		case []interface{}:
+			for _, address := range addresses {
+				assert.Equal(t, []string{"location"}, keys(address))
+			}
+		default:
+			assert.Fail(t, "addresses", "%T", addresses)
+		}
+	}
+
+	row := user.MustFind(1, QueryParam{Fields: []string{"manu.name"}})
+	assert.Equal(t, []string{"manu"}, keys(row))
+
+	res := user.MustPaginate(QueryParam{Fields: []string{"name"}}, 1, 2)
+	for _, row := range res.Get("data").([]maps.MapStr) {
+		assert.Equal(t, []string{"name"}, keys(row))
+	}
+
+	// 严格模式: 未知字段返回 400
+	FieldsStrict = true
+	defer func() { FieldsStrict = false }()
+	var validation *ErrValidation
+	_, err := user.Get(QueryParam{Fields: []string{"id", "undefined"}})
+	assert.True(t, errors.As(err, &validation))
+	_, err = user.Get(QueryParam{Fields: []string{"manu.undefined"}})
+	assert.True(t, errors.As(err, &validation))
+}
+
+func TestModelTruncate(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "清空数据",
+		"table": { "name": "truncate_test" },
+		"columns": [{ "name": "id", "type": "ID" }, { "name": "name", "type": "string", "length": 40 }],
+		"option": { "soft_deletes": true }
+	}`, "truncate_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("truncate_test")
+		delete(Models, "truncate_test")
+	}()
+
+	mod.MustInsert([]string{"name"}, [][]interface{}{{"a"}, {"b"}, {"c"}})
+	mod.MustDelete(1)
+
+	_, err := mod.DeleteAll(false)
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, mod.MustDeleteAll(true)) // 包含已软删除的数据
+	assert.False(t, mod.MustExists(QueryParam{WithTrashed: true}))
+
+	mod.MustInsert([]string{"name"}, [][]interface{}{{"d"}, {"e"}})
+	assert.NotNil(t, mod.Truncate(false))
+	assert.Equal(t, 2, len(mod.MustGet(QueryParam{})))
+	mod.MustTruncate(true)
+	assert.False(t, mod.MustExists(QueryParam{WithTrashed: true}))
+	assert.Equal(t, 1, mod.MustCreate(maps.MapStr{"name": "f"})) // 自增主键已重置
+
+	err = Transaction(func(m TxModels) error { return m.Select(mod.Name).Truncate(true) })
+	assert.NotNil(t, err)
+}
+
+func TestModelTree(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "树形结构",
+		"table": { "name": "tree_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "name", "type": "string", "length": 40 },
+			{ "name": "parent_id", "type": "integer", "nullable": true }
+		],
+		"relations": {
+			"parent": { "type": "hasOne", "model": "tree_test", "key": "id", "foreign": "parent_id" },
+			"children": { "type": "hasMany", "model": "tree_test", "key": "parent_id", "foreign": "id" }
+		},
+		"option": { "parent": "parent_id" }
+	}`, "tree_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("tree_test")
+		delete(Models, "tree_test")
+	}()
+	assert.Nil(t, LinkModels())
+
+	mod.MustInsert([]string{"name", "parent_id"}, [][]interface{}{{"a", nil}, {"b", 1}, {"c", 1}, {"d", 2}, {"e", 4}})
+	names := func(rows []maps.MapStr) []interface{} {
+		res := []interface{}{}
+		for _, row := range rows {
+			res = append(res, row.Get("name"))
+		}
+		return res
+	}
+	assert.Equal(t, []interface{}{"b", "c", "d", "e"}, names(mod.MustDescendants(1, 0)))
+	assert.Equal(t, []interface{}{"b", "c"}, names(mod.MustDescendants(1, 1)))
+	assert.Equal(t, []interface{}{"d", "b", "a"}, names(mod.MustAncestors(5)))
+	assert.Empty(t, mod.MustAncestors(1))
+
+	// 逐层查询
+	ids, err := mod.treeIterative(1, TreeMaxDepth, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(ids))
+	ids, err = mod.treeIterative(5, TreeMaxDepth, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(ids))
+
+	// 数据存在环时不会无限遍历
+	mod.MustUpdate(1, maps.MapStr{"parent_id": 5})
+	assert.Equal(t, []interface{}{"b", "c", "d", "e"}, names(mod.MustDescendants(1, 0)))
+	assert.Equal(t, []interface{}{"d", "b", "a"}, names(mod.MustAncestors(5)))
+
+	// 自关联查询
+	rows := mod.MustGet(QueryParam{
+		Select: []interface{}{"id", "name"},
+		Wheres: []QueryWhere{{Column: "id", Value: 4}},
+		Withs:  map[string]With{"parent": {}, "children": {}},
+	})
+	assert.Equal(t, "b", rows[0].Dot().Get("parent.name"))
+	assert.Equal(t, "e", rows[0].Dot().Get("children.0.name"))
+
+	_, err = Select("user").Descendants(1, 0)
+	assert.NotNil(t, err)
+}
+
+func TestModelTreePath(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "树形路径",
+		"table": { "name": "tree_path_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "name", "type": "string", "length": 40 },
+			{ "name": "parent_id", "type": "integer", "nullable": true },
+			{ "name": "path", "type": "string", "length": 200, "nullable": true, "index": true }
+		],
+		"option": { "parent": "parent_id", "path": "path" }
+	}`, "tree_path_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("tree_path_test")
+		delete(Models, "tree_path_test")
+	}()
+	assert.Nil(t, LinkModels())
+
+	path := func(id int) interface{} { return mod.MustFind(id, QueryParam{}).Get("path") }
+	names := func(rows []maps.MapStr) []interface{} {
+		res := []interface{}{}
+		for _, row := range rows {
+			res = append(res, row.Get("name"))
+		}
+		return res
+	}
+
+	mod.MustCreate(maps.MapStr{"name": "a"})
+	mod.MustCreate(maps.MapStr{"name": "b", "parent_id": 1})
+	mod.MustSave(maps.MapStr{"name": "c", "parent_id": 1})
+	mod.MustCreate(maps.MapStr{"name": "d", "parent_id": 2})
+	mod.MustInsert([]string{"name", "parent_id"}, [][]interface{}{{"e", 4}})
+	assert.Equal(t, "1", path(1))
+	assert.Equal(t, "1/3", path(3))
+	assert.Equal(t, "1/2/4/5", path(5))
+
+	assert.Equal(t, []interface{}{"b", "c", "d", "e"}, names(mod.MustDescendants(1, 0)))
+	assert.Equal(t, []interface{}{"b", "c"}, names(mod.MustDescendants(1, 1)))
+	assert.Equal(t, []interface{}{"d", "b", "a"}, names(mod.MustAncestors(5)))
+
+	// 移动子树
+	mod.MustMove(2, 3)
+	assert.Equal(t, "1/3/2", path(2))
+	assert.Equal(t, "1/3/2/4/5", path(5))
+	assert.NotNil(t, mod.Move(3, 4)) // 不能移动到下级
+	assert.Equal(t, "1/3", path(3))
+
+	// 修改上级字段
+	mod.MustUpdate(4, maps.MapStr{"name": "d2", "parent_id": nil})
+	assert.Equal(t, "4", path(4))
+	assert.Equal(t, "4/5", path(5))
+	assert.Equal(t, "d2", mod.MustFind(4, QueryParam{}).Get("name"))
+	mod.MustSave(maps.MapStr{"id": 5, "parent_id": 1})
+	assert.Equal(t, "1/5", path(5))
+
+	_, err := mod.UpdateWhere(QueryParam{Wheres: []QueryWhere{{Column: "id", Value: 5}}}, maps.MapStr{"parent_id": 2})
+	assert.NotNil(t, err)
+}
+
+func TestModelReplicate(t *testing.T) {
+	user := Select("user")
+	address := Select("address")
+	source := user.MustFind(1, QueryParam{})
+	count := len(address.MustGet(QueryParam{Wheres: []QueryWhere{{Column: "user_id", Value: 1}}}))
+
+	id := user.MustReplicate(1, maps.MapStr{"name": "复制用户", "mobile": "13900005555"}, "addresses")
+	defer capsule.Query().Table(user.MetaData.Table.Name).Where("id", id).Delete()
+	defer capsule.Query().Table(address.MetaData.Table.Name).Where("user_id", id).Delete()
+	assert.NotEqual(t, 1, id)
+
+	row := user.MustFind(id, QueryParam{})
+	assert.Equal(t, "复制用户", row.Get("name"))
+	assert.Equal(t, "13900005555", row.Get("mobile"))
+	assert.Equal(t, source.Get("idcard"), row.Get("idcard"))
+	assert.Equal(t, source.Get("password"), row.Get("password"))
+	assert.Nil(t, row.Get("key"))
+
+	rows := address.MustGet(QueryParam{Wheres: []QueryWhere{{Column: "user_id", Value: id}}})
+	assert.Equal(t, count, len(rows))
+
+	var notFound *ErrNotFound
+	_, err := user.Replicate(99999, nil)
+	assert.True(t, errors.As(err, &notFound))
+
+	var validation *ErrValidation
+	_, err = user.Replicate(1, maps.MapStr{"mobile": "abc"})
+	assert.True(t, errors.As(err, &validation))
+
+	// 仅支持 hasMany 关联, 失败时回滚已复制的数据
+	last := func() interface{} {
+		return user.MustGet(QueryParam{Select: []interface{}{"id"}, Orders: []QueryOrder{{Column: "id", Option: "desc"}}, Limit: 1})[0].Get("id")
+	}
+	before := last()
+	_, err = user.Replicate(1, maps.MapStr{"mobile": "13900006666"}, "manu")
+	assert.NotNil(t, err)
+	assert.Equal(t, before, last())
+}
+
+func TestModelMustEachSave(t *testing.T) {
+	user := Select("user")
+	ids := user.MustEachSave([]map[string]interface{}{
+		{"id": 1, "balance": 200},
+		{
+			"name":     "用户创建",
+			"manu_id":  2,
+			"type":     "user",
+			"idcard":   "23082619820207006X",
+			"mobile":   "13900004444",
 			"password": "qV@uT1DI",
 			"key":      "XZ12MiPp",
 			"secret":   "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN",
@@ -442,6 +1693,40 @@ func TestModelMustEachSave(t *testing.T) {
 	assert.Equal(t, any.Of(row.Get("balance")).CInt(), 200)
 }
 
+func TestModelEachSaveResult(t *testing.T) {
+	user := Select("user")
+	res := user.EachSaveResult([]map[string]interface{}{
+		{"id": 1, "balance": 200},
+		{
+			"name":     "用户创建",
+			"manu_id":  2,
+			"type":     "user",
+			"idcard":   "23082619820207006X",
+			"mobile":   "13900004444",
+			"password": "qV@uT1DI",
+			"key":      "XZ12MiPp",
+			"secret":   "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN",
+			"status":   "enabled",
+			"extra":    maps.MapStr{"sex": "女"},
+		},
+		{"name": "缺少必填字段"},
+	})
+
+	// 恢复数据
+	capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"balance": 0})
+	for _, id := range res.Created {
+		capsule.Query().Table(user.MetaData.Table.Name).Where("id", id).Delete()
+	}
+
+	assert.Equal(t, []int{1}, res.Updated)
+	assert.Equal(t, 1, len(res.Created))
+	assert.True(t, len(res.Failed) > 0)
+	for _, failed := range res.Failed {
+		assert.Equal(t, 2, failed.Line)
+		assert.True(t, len(failed.Messages) > 0)
+	}
+}
+
 func TestModelMustEachSaveWithIndex(t *testing.T) {
 	user := Select("user")
 	ids := user.MustEachSave([]map[string]interface{}{
@@ -479,3 +1764,1149 @@ func TestModelMustEachSaveWithIndex(t *testing.T) {
 	assert.Equal(t, any.Of(row.Get("balance")).CInt(), 0)
 	assert.Equal(t, any.Of(row1.Get("balance")).CInt(), 1)
 }
+
+func TestModelExportCSV(t *testing.T) {
+	user := Select("user")
+	buf := &bytes.Buffer{}
+	err := user.ExportCSV(buf, QueryParam{
+		Select: []interface{}{"id", "name", "extra"},
+		Orders: []QueryOrder{{Column: "id"}},
+	})
+	assert.Nil(t, err)
+
+	records, err := csv.NewReader(buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "name", "extra"}, records[0])
+	assert.Equal(t, "1", records[1][0])
+	assert.Equal(t, "管理员", records[1][1])
+	assert.Equal(t, `{"sex":"男"}`, records[1][2])
+}
+
+func TestModelExportCSVHidden(t *testing.T) {
+	user := Select("user")
+	user.Columns["secret"].Hidden = true
+	defer func() { user.Columns["secret"].Hidden = false }()
+
+	buf := &bytes.Buffer{}
+	err := user.ExportCSV(buf, QueryParam{Select: []interface{}{"id", "name", "secret"}})
+	assert.Nil(t, err)
+	records, err := csv.NewReader(buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "name"}, records[0])
+}
+
+func TestModelEach(t *testing.T) {
+	user := Select("user")
+	ids := []interface{}{}
+	err := user.Each(QueryParam{Select: []interface{}{"name"}}, 1, func(rows []maps.MapStr) error {
+		assert.Equal(t, 1, len(rows))
+		ids = append(ids, rows[0].Get("id"))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, ids)
+
+	ids = []interface{}{}
+	err = user.Each(QueryParam{Orders: []QueryOrder{{Column: "id", Option: "desc"}}}, 2, func(rows []maps.MapStr) error {
+		for _, row := range rows {
+			ids = append(ids, row.Get("id"))
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(3), int64(2), int64(1)}, ids)
+}
+
+func TestModelChunk(t *testing.T) {
+	user := Select("user")
+	ids := []interface{}{}
+	user.MustChunk(QueryParam{Select: []interface{}{"name"}, Wheres: []QueryWhere{{Column: "id", OP: "ge", Value: 2}}}, 1, func(rows []maps.MapStr) error {
+		ids = append(ids, rows[0].Get("id"))
+		return nil
+	})
+	assert.Equal(t, []interface{}{int64(2), int64(3)}, ids)
+
+	ids = []interface{}{}
+	err := user.Chunk(QueryParam{Orders: []QueryOrder{{Column: "id", Option: "desc"}}}, 2, func(rows []maps.MapStr) error {
+		for _, row := range rows {
+			ids = append(ids, row.Get("id"))
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(3), int64(2), int64(1)}, ids)
+
+	err = user.Chunk(QueryParam{Orders: []QueryOrder{{Column: "name"}}}, 2, func(rows []maps.MapStr) error { return nil })
+	assert.NotNil(t, err)
+}
+
+func TestModelExportExcel(t *testing.T) {
+	user := Select("user")
+	buf := &bytes.Buffer{}
+	err := user.ExportExcel(buf, QueryParam{
+		Select: []interface{}{"id", "name", "balance", "created_at"},
+		Orders: []QueryOrder{{Column: "id"}},
+	}, ExcelOptions{SheetName: "用户", FreezeHeader: true})
+	assert.Nil(t, err)
+	data := buf.Bytes()
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	assert.Nil(t, err)
+	name, _ := f.GetCellValue("用户", "B1")
+	value, _ := f.GetCellValue("用户", "B2")
+	assert.Equal(t, "姓名", name)
+	assert.Equal(t, "管理员", value)
+
+	// 冻结表头
+	sheetXML := xlsxPart(t, data, "xl/worksheets/sheet1.xml")
+	assert.Contains(t, sheetXML, `state="frozen"`)
+
+	sheet := struct {
+		Rows []struct {
+			Cells []struct {
+				R string `xml:"r,attr"`
+				S int    `xml:"s,attr"`
+				T string `xml:"t,attr"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}{}
+	styles := struct {
+		Xfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+			FontID   int `xml:"fontId,attr"`
+		} `xml:"cellXfs>xf"`
+		Fonts []struct {
+			B *struct{} `xml:"b"`
+		} `xml:"fonts>font"`
+	}{}
+	assert.Nil(t, xml.Unmarshal([]byte(sheetXML), &sheet))
+	assert.Nil(t, xml.Unmarshal([]byte(xlsxPart(t, data, "xl/styles.xml")), &styles))
+
+	cells := map[string]int{}
+	types := map[string]string{}
+	for _, row := range sheet.Rows {
+		for _, cell := range row.Cells {
+			cells[cell.R] = cell.S
+			types[cell.R] = cell.T
+		}
+	}
+
+	assert.NotNil(t, styles.Fonts[styles.Xfs[cells["A1"]].FontID].B) // 表头加粗
+	assert.Equal(t, "", types["C2"])                                 // balance 数值单元格
+	assert.Equal(t, "", types["D2"])                                 // created_at 日期单元格
+	assert.Equal(t, 22, styles.Xfs[cells["D2"]].NumFmtID)            // yyyy/m/d h:mm
+}
+
+// xlsxPart 读取 xlsx 文件中的 XML 部件
+func xlsxPart(t *testing.T, data []byte, name string) string {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		assert.Nil(t, err)
+		defer rc.Close()
+		content, err := ioutil.ReadAll(rc)
+		assert.Nil(t, err)
+		return string(content)
+	}
+	t.Fatalf("%s not found", name)
+	return ""
+}
+
+func TestModelSubscribe(t *testing.T) {
+	user := Select("user")
+	events := make(chan ChangeEvent, 10)
+	unsubscribe := user.Subscribe(func(event ChangeEvent) {
+		select {
+		case events <- event:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	user.MustUpdate(1, maps.MapStr{"balance": 200})
+	capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"balance": 0})
+
+	select {
+	case event := <-events:
+		assert.Equal(t, EventUpdate, event.Op)
+		assert.Equal(t, 1, any.Of(event.IDs[0]).CInt())
+		assert.Equal(t, 0, any.Of(event.Before.Get("balance")).CInt())
+		assert.Equal(t, 200, any.Of(event.After.Get("balance")).CInt())
+	case <-time.After(time.Second):
+		assert.Fail(t, "ChangeEvent not received")
+	}
+
+	// 批量更新, 一个事件包含全部 ID
+	where := QueryParam{Wheres: []QueryWhere{{Column: "id", OP: "in", Value: []interface{}{1, 2}}}}
+	user.MustUpdateWhere(where, maps.MapStr{"balance": 300})
+	capsule.Query().Table(user.MetaData.Table.Name).WhereIn("id", []interface{}{1, 2}).Update(maps.MapStr{"balance": 0})
+	select {
+	case event := <-events:
+		assert.Equal(t, EventUpdate, event.Op)
+		assert.Nil(t, event.Before)
+		assert.Equal(t, 300, any.Of(event.After.Get("balance")).CInt())
+		assert.ElementsMatch(t, []interface{}{int64(1), int64(2)}, event.IDs)
+	case <-time.After(time.Second):
+		assert.Fail(t, "ChangeEvent not received")
+	}
+
+	// 按记录发布, 每条记录一个事件
+	BulkEventRows = true
+	defer func() { BulkEventRows = false }()
+	user.MustUpdateWhere(where, maps.MapStr{"balance": 300})
+	capsule.Query().Table(user.MetaData.Table.Name).WhereIn("id", []interface{}{1, 2}).Update(maps.MapStr{"balance": 0})
+	ids := []interface{}{}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			assert.Equal(t, EventUpdate, event.Op)
+			assert.Equal(t, 0, any.Of(event.Before.Get("balance")).CInt())
+			assert.Equal(t, 300, any.Of(event.After.Get("balance")).CInt())
+			ids = append(ids, event.IDs...)
+		case <-time.After(time.Second):
+			assert.Fail(t, "ChangeEvent not received")
+		}
+	}
+	assert.ElementsMatch(t, []interface{}{int64(1), int64(2)}, ids)
+
+	// 取消订阅后不再投递
+	unsubscribe()
+	assert.False(t, user.hasSubscribers())
+}
+
+func TestModelCustomColumnType(t *testing.T) {
+	drivers := []string{}
+	RegisterColumnType("code", func(col Column, driver string) string {
+		drivers = append(drivers, driver)
+		if col.Nullable {
+			return "VARCHAR(32) NULL"
+		}
+		return "VARCHAR(32) NOT NULL DEFAULT ''"
+	})
+	defer delete(columnTypes, "code")
+
+	mod := LoadModel(`{
+		"name": "自定义类型",
+		"table": { "name": "column_type_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "code", "type": "code", "unique": true },
+			{ "name": "remark", "type": "code", "nullable": true }
+		],
+		"indexes": [
+			{ "name": "code_remark_index", "columns": ["code", "remark"], "type": "index" }
+		]
+	}`, "column_type_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("column_type_test")
+		delete(Models, "column_type_test")
+	}()
+
+	assert.Contains(t, drivers, mod.Driver)
+	id := mod.MustCreate(maps.MapStr{"code": "A001"})
+	row := mod.MustFind(id, QueryParam{})
+	assert.Equal(t, "A001", row.Get("code"))
+	assert.Nil(t, row.Get("remark"))
+	assert.Panics(t, func() { mod.MustCreate(maps.MapStr{"code": "A001"}) })
+
+	assert.Panics(t, func() { Column{Name: "foo", Type: "not_exists"}.SetType(nil) })
+}
+
+func TestModelMigrateUpgrade(t *testing.T) {
+	source := `{
+		"name": "数据表升级",
+		"table": { "name": "upgrade_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "code", "type": "string", "length": 20, "unique": true },
+			{ "name": "remark", "type": "string", "length": 20 }
+		]
+	}`
+	mod := LoadModel(source, "upgrade_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("upgrade_test")
+		delete(Models, "upgrade_test")
+	}()
+
+	id := mod.MustCreate(maps.MapStr{"code": "A001", "remark": "foo"})
+	assert.True(t, mod.SchemaTableDiff().Empty())
+	capsule.Query().DB().Exec("ALTER TABLE upgrade_test ADD COLUMN legacy VARCHAR(10)")
+	capsule.Query().Table("upgrade_test").Where("id", id).Update(maps.MapStr{"legacy": "old"})
+
+	// 修改字段长度和可空属性, 新增字段
+	mod = LoadModel(strings.Replace(source, `{ "name": "remark", "type": "string", "length": 20 }`,
+		`{ "name": "remark", "type": "string", "length": 200, "nullable": true },
+		{ "name": "status", "type": "string", "length": 20, "default": "enabled" }`, 1), "upgrade_test")
+	diff := mod.SchemaTableDiff()
+	assert.Equal(t, []string{"status"}, diff.Add)
+	assert.Equal(t, []string{"remark"}, diff.Change)
+	assert.Equal(t, []string{"legacy"}, diff.Extra)
+
+	mod.Migrate(false)
+	assert.True(t, mod.SchemaTableDiff().Empty())
+	row := mod.MustFind(id, QueryParam{})
+	assert.Equal(t, "A001", row.Get("code"))
+	assert.Equal(t, "foo", row.Get("remark"))
+	assert.Equal(t, "enabled", row.Get("status"))
+	legacy, _ := capsule.Query().Table("upgrade_test").Where("id", id).Value("legacy")
+	assert.Equal(t, "old", fmt.Sprintf("%s", legacy)) // 模型未定义的字段保留
+
+	// 可空、唯一索引生效
+	other := mod.MustCreate(maps.MapStr{"code": "A002"})
+	assert.Nil(t, mod.MustFind(other, QueryParam{}).Get("remark"))
+	assert.Panics(t, func() { mod.MustCreate(maps.MapStr{"code": "A001"}) })
+}
+
+func TestModelTablePrefix(t *testing.T) {
+	SetTablePrefix("pre_")
+	defer SetTablePrefix("")
+
+	owner := LoadModel(`{
+		"name": "数据表前缀",
+		"table": { "name": "prefix_owner" },
+		"columns": [{ "name": "id", "type": "ID" }, { "name": "name", "type": "string", "length": 40 }],
+		"relations": {
+			"items": { "type": "hasMany", "model": "prefix_item", "key": "owner_id", "foreign": "id" }
+		},
+		"option": { "soft_deletes": true }
+	}`, "prefix_owner")
+	item := LoadModel(`{
+		"name": "数据表前缀关联",
+		"table": { "name": "prefix_item" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "owner_id", "type": "integer", "index": true },
+			{ "name": "name", "type": "string", "length": 40 }
+		],
+		"relations": {
+			"owner": { "type": "hasOne", "model": "prefix_owner", "key": "id", "foreign": "owner_id" }
+		}
+	}`, "prefix_item")
+	owner.Migrate(true)
+	item.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("pre_prefix_owner")
+		capsule.Schema().DropTableIfExists("pre_prefix_item")
+		delete(Models, "prefix_owner")
+		delete(Models, "prefix_item")
+	}()
+
+	assert.Equal(t, "pre_prefix_owner", owner.TableName())
+	assert.True(t, capsule.Schema().MustHasTable("pre_prefix_owner"))
+	assert.False(t, capsule.Schema().MustHasTable("prefix_owner"))
+
+	id := owner.MustCreate(maps.MapStr{"name": "主表"})
+	item.MustInsert([]string{"owner_id", "name"}, [][]interface{}{{id, "关联1"}, {id, "关联2"}})
+
+	row := owner.MustFind(id, QueryParam{Withs: map[string]With{"items": {}}})
+	assert.Equal(t, 2, len(row.Get("items").([]maps.MapStr)))
+
+	rows := item.MustGet(QueryParam{
+		Withs:  map[string]With{"owner": {}},
+		Wheres: []QueryWhere{{Column: "name", Value: "关联1"}},
+	})
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "主表", rows[0].Dot().Get("owner.name"))
+
+	assert.Equal(t, 2, item.MustUpdateWhere(QueryParam{Wheres: []QueryWhere{{Column: "owner_id", Value: id}}}, maps.MapStr{"name": "更新"}))
+	owner.MustDelete(id)
+	assert.False(t, owner.MustExists(QueryParam{}))
+	assert.Equal(t, 2, item.MustDestroyWhere(QueryParam{Wheres: []QueryWhere{{Column: "owner_id", Value: id}}}))
+}
+
+func TestModelSearch(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "全文检索",
+		"table": { "name": "search_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "title", "type": "string", "length": 200, "fulltext": true },
+			{ "name": "summary", "type": "text", "nullable": true }
+		]
+	}`, "search_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("search_test")
+		delete(Models, "search_test")
+	}()
+
+	mod.MustInsert([]string{"title", "summary"}, [][]interface{}{
+		{"Gou data engine", "models and flows"},
+		{"Yao application", "low code engine"},
+		{"Widgets", "charts and tables"},
+	})
+
+	rows := mod.MustGet(QueryParam{Search: &QuerySearch{Keyword: "engine"}})
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "Gou data engine", rows[0].Get("title"))
+
+	rows = mod.MustGet(QueryParam{Search: &QuerySearch{Columns: []string{"title", "summary"}, Keyword: "engine"}})
+	assert.Equal(t, 2, len(rows))
+
+	rows = mod.MustGet(QueryParam{
+		Search: &QuerySearch{Columns: []string{"summary"}, Keyword: "and"},
+		Wheres: []QueryWhere{{Column: "title", Value: "Widgets"}},
+	})
+	assert.Equal(t, 1, len(rows))
+
+	assert.Panics(t, func() { mod.MustGet(QueryParam{Search: &QuerySearch{Columns: []string{"missing"}, Keyword: "x"}}) })
+}
+
+func TestModelView(t *testing.T) {
+	user := Select("user")
+	mod := LoadModel(fmt.Sprintf(`{
+		"name": "用户视图",
+		"view": true,
+		"table": { "name": "user_view", "sql": "SELECT id, name, status FROM %s WHERE status = 'enabled'" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "name", "type": "string" },
+			{ "name": "status", "type": "string" }
+		]
+	}`, user.MetaData.Table.Name), "user_view")
+	mod.Migrate(true)
+	mod.Migrate(false) // 重复迁移替换视图
+	defer func() {
+		capsule.Query().DB().Exec("DROP VIEW IF EXISTS user_view")
+		delete(Models, "user_view")
+	}()
+
+	rows := mod.MustGet(QueryParam{Orders: []QueryOrder{{Column: "id"}}})
+	assert.Equal(t, len(user.MustGet(QueryParam{Wheres: []QueryWhere{{Column: "status", Value: "enabled"}}})), len(rows))
+	row := mod.MustFind(1, QueryParam{})
+	assert.Equal(t, user.MustFind(1, QueryParam{}).Get("name"), row.Get("name"))
+	res := mod.MustPaginate(QueryParam{}, 1, 2)
+	assert.Equal(t, len(rows), res.Get("total"))
+
+	_, err := mod.Create(maps.MapStr{"name": "视图"})
+	assert.Contains(t, err.Error(), "read-only view")
+	assert.Error(t, mod.Update(1, maps.MapStr{"name": "视图"}))
+	assert.Error(t, mod.Delete(1))
+	_, err = mod.DestroyWhere(QueryParam{})
+	assert.Error(t, err)
+	assert.Panics(t, func() { mod.MustSave(maps.MapStr{"id": 1, "name": "视图"}) })
+}
+
+func TestModelSuggestIndexes(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "索引建议",
+		"table": { "name": "index_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "mobile", "type": "string", "length": 20 },
+			{ "name": "status", "type": "enum", "option": ["enabled", "disabled"] },
+			{ "name": "manu_id", "type": "integer", "index": true },
+			{ "name": "user_id", "type": "integer" },
+			{ "name": "score", "type": "integer" }
+		],
+		"relations": {
+			"manu": { "type": "hasOne", "model": "manu", "key": "id", "foreign": "manu_id" },
+			"user": { "type": "hasOne", "model": "user", "key": "id", "foreign": "user_id" }
+		}
+	}`, "index_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("index_test")
+		delete(Models, "index_test")
+		delete(scopes, "index_test")
+	}()
+
+	mod.Scope("enabled", func(param *QueryParam) {
+		param.Wheres = append(param.Wheres, QueryWhere{Column: "status", Value: "enabled"})
+	})
+	mod.Scope("mobile", func(param *QueryParam) {
+		param.Wheres = append(param.Wheres, QueryWhere{Column: "status", Value: "enabled"}, QueryWhere{Column: "mobile", Value: "13900001111"})
+	})
+	mod.Scope("top", func(param *QueryParam) {
+		param.Wheres = append(param.Wheres, QueryWhere{Column: "user_id", Value: 1})
+		param.Orders = append(param.Orders, QueryOrder{Column: "score", Option: "desc"})
+	})
+
+	suggestions := mod.SuggestIndexes()
+	assert.Equal(t, 3, len(suggestions))
+	assert.Equal(t, []string{"mobile", "status"}, suggestions[0].Columns)
+	assert.Equal(t, []string{"scope:mobile"}, suggestions[0].Reasons)
+	assert.Equal(t, []string{"user_id", "score"}, suggestions[1].Columns)
+	assert.Equal(t, []string{"user_id"}, suggestions[2].Columns)
+	assert.Equal(t, []string{"relation:user"}, suggestions[2].Reasons)
+
+	mod.MustCreateIndexes(suggestions)
+	assert.Equal(t, 0, len(mod.SuggestIndexes()))
+}
+
+func TestModelSeed(t *testing.T) {
+	parent := LoadModel(`{
+		"name": "初始数据",
+		"table": { "name": "seed_parent" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "code", "type": "string", "length": 40, "unique": true },
+			{ "name": "name", "type": "string", "length": 40 }
+		],
+		"relations": {
+			"children": { "type": "hasMany", "model": "seed_child", "key": "parent_id", "foreign": "id" }
+		}
+	}`, "seed_parent")
+	child := LoadModel(`{
+		"name": "初始数据关联",
+		"table": { "name": "seed_child" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "parent_id", "type": "integer" },
+			{ "name": "name", "type": "string", "length": 40 }
+		],
+		"indexes": [{ "name": "parent_name_unique", "columns": ["parent_id", "name"], "type": "unique" }]
+	}`, "seed_child")
+	parent.Migrate(true)
+	child.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("seed_parent")
+		capsule.Schema().DropTableIfExists("seed_child")
+		delete(Models, "seed_parent")
+		delete(Models, "seed_child")
+	}()
+
+	rows := []maps.MapStr{{"code": "a", "name": "甲"}, {"code": "b", "name": "乙"}}
+	parent.MustSeed(rows, []string{"code"})
+	parent.MustSeed([]maps.MapStr{{"code": "a", "name": "甲-更新"}, {"code": "b", "name": "乙"}}, []string{"code"})
+	assert.Equal(t, map[string]interface{}{"a": "甲-更新", "b": "乙"}, map[string]interface{}(parent.MustPluckMap("code", "name", QueryParam{})))
+	assert.Error(t, parent.Seed(rows, nil))
+	assert.Error(t, parent.Seed([]maps.MapStr{{"name": "丙"}}, []string{"code"}))
+
+	names, err := seedOrder(map[string][]maps.MapStr{"seed_child": nil, "seed_parent": nil})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"seed_parent", "seed_child"}, names)
+	assert.Equal(t, []string{"parent_id", "name"}, child.seedKeys())
+
+	data := map[string][]maps.MapStr{
+		"seed_child":  {{"parent_id": 1, "name": "子1"}, {"parent_id": 1, "name": "子2"}},
+		"seed_parent": {{"code": "c", "name": "丙"}},
+	}
+	MustSeedAll(data)
+	MustSeedAll(data)
+	assert.Equal(t, 3, len(parent.MustGet(QueryParam{})))
+	assert.Equal(t, 2, len(child.MustGet(QueryParam{})))
+
+	_, err = seedOrder(map[string][]maps.MapStr{"not_exists": nil})
+	assert.Error(t, err)
+}
+
+func TestModelOutputTransformer(t *testing.T) {
+	RegisterOutputTransformer("enum", func(value interface{}, col Column) interface{} {
+		labels := map[interface{}]string{"enabled": "启用", "disabled": "停用"}
+		if label, has := labels[value]; has {
+			return label
+		}
+		return value
+	})
+	RegisterOutputTransformer("json", func(value interface{}, col Column) interface{} {
+		if extra, ok := value.(map[string]interface{}); ok {
+			extra["column"] = col.Name
+		}
+		return value
+	})
+	defer delete(outputTransformers, "enum")
+	defer delete(outputTransformers, "json")
+
+	user := Select("user")
+	row := user.MustFind(1, QueryParam{})
+	assert.Equal(t, "启用", row.Get("status"))
+	assert.Equal(t, "男", row.Dot().Get("extra.sex"))
+	assert.Equal(t, "extra", row.Dot().Get("extra.column"))
+
+	rows := user.MustGet(QueryParam{Select: []interface{}{"id", "status"}, Limit: 1})
+	assert.Equal(t, "启用", rows[0].Get("status"))
+}
+
+func TestColumnTimeFormat(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	TimeFormat, TimeZone = time.RFC3339, shanghai
+	defer func() { TimeFormat, TimeZone = "", nil }()
+
+	column := Column{Name: "created_at", Type: "timestamp"}
+	local, _ := time.ParseInLocation("2006-01-02 15:04:05", "2022-01-02 03:04:05", time.Local)
+	row := maps.MapStrAny{"created_at": "2022-01-02 03:04:05"}
+	column.FliterOut(row["created_at"], row)
+	assert.Equal(t, local.In(shanghai).Format(time.RFC3339), row.Get("created_at"))
+
+	row = maps.MapStrAny{"created_at": local}
+	column.FliterOut(row["created_at"], row)
+	assert.Equal(t, local.In(shanghai).Format(time.RFC3339), row.Get("created_at"))
+
+	row = maps.MapStrAny{"created_at": nil}
+	column.FliterOut(nil, row)
+	assert.Nil(t, row.Get("created_at"))
+
+	row = maps.MapStrAny{"created_at": "2022-01-02T03:04:05+08:00"}
+	column.FliterIn(row["created_at"], row)
+	input, _ := time.Parse(time.RFC3339, "2022-01-02T03:04:05+08:00")
+	assert.Equal(t, input.In(time.Local).Format("2006-01-02 15:04:05"), row.Get("created_at"))
+
+	// 模型配置优先
+	address := Select("address")
+	address.MetaData.Option.TimeFormat = "2006/01/02 15:04"
+	address.MetaData.Option.TimeZone = "UTC"
+	defer func() { address.MetaData.Option.TimeFormat, address.MetaData.Option.TimeZone = "", "" }()
+	origin, _ := capsule.Query().Table(address.TableName()).Where("id", 1).First()
+	capsule.Query().Table(address.TableName()).Where("id", 1).Update(maps.MapStr{"created_at": "2022-01-02 03:04:05"})
+	defer capsule.Query().Table(address.TableName()).Where("id", 1).Update(maps.MapStr{"created_at": origin["created_at"]})
+	res := address.MustFind(1, QueryParam{Select: []interface{}{"id", "created_at"}})
+	assert.Equal(t, local.UTC().Format("2006/01/02 15:04"), res.Get("created_at"))
+}
+
+func TestModelWhereJSONContains(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "JSON数组",
+		"table": { "name": "json_contains_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "name", "type": "string", "length": 40 },
+			{ "name": "tags", "type": "json", "nullable": true }
+		]
+	}`, "json_contains_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("json_contains_test")
+		delete(Models, "json_contains_test")
+	}()
+
+	mod.MustCreate(maps.MapStr{"name": "a", "tags": []interface{}{"vip", "new"}})
+	mod.MustCreate(maps.MapStr{"name": "b", "tags": []interface{}{"vip"}})
+	mod.MustCreate(maps.MapStr{"name": "c", "tags": []interface{}{"new"}})
+
+	names := mod.MustPluckString("name", QueryParam{
+		Wheres: []QueryWhere{{Column: "tags", OP: "json_contains", Value: "vip"}},
+		Orders: []QueryOrder{{Column: "id"}},
+	})
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	names = mod.MustPluckString("name", QueryParam{
+		Wheres: []QueryWhere{{Column: "tags", OP: "json_contains", Value: []interface{}{"vip", "new"}}},
+	})
+	assert.Equal(t, []string{"a"}, names)
+
+	names = mod.MustPluckString("name", QueryParam{
+		Wheres: []QueryWhere{
+			{Column: "name", Value: "b"},
+			{Column: "tags", OP: "json_contains", Value: "new", Method: "orwhere"},
+		},
+		Orders: []QueryOrder{{Column: "id"}},
+	})
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	assert.Panics(t, func() {
+		mod.MustGet(QueryParam{Wheres: []QueryWhere{{Column: "name", OP: "json_contains", Value: "a"}}})
+	})
+}
+
+func TestModelReservedWordColumns(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "保留字字段",
+		"table": { "name": "reserved_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "order", "type": "integer", "default": 0 },
+			{ "name": "group", "type": "string", "length": 40, "index": true },
+			{ "name": "key", "type": "string", "length": 40, "unique": true }
+		],
+		"option": { "timestamps": true, "soft_deletes": true }
+	}`, "reserved_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("reserved_test")
+		delete(Models, "reserved_test")
+	}()
+
+	id := mod.MustCreate(maps.MapStr{"order": 1, "group": "a", "key": "k1"})
+	mod.MustCreate(maps.MapStr{"order": 2, "group": "b", "key": "k2"})
+	mod.MustUpdate(id, maps.MapStr{"group": "b"})
+	mod.MustIncrement(id, "order", 5)
+
+	row := mod.MustFind(id, QueryParam{})
+	assert.Equal(t, "b", row.Get("group"))
+	assert.Equal(t, "k1", row.Get("key"))
+	assert.Equal(t, 6, any.Of(row.Get("order")).CInt())
+
+	rows := mod.MustGet(QueryParam{
+		Wheres: []QueryWhere{{Column: "group", Value: "b"}},
+		Orders: []QueryOrder{{Column: "order", Option: "desc"}},
+	})
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "k1", rows[0].Get("key"))
+
+	mod.MustDelete(id)
+	assert.False(t, mod.MustExists(QueryParam{Wheres: []QueryWhere{{Column: "key", Value: "k1"}}}))
+	mod.MustRestore(id)
+	assert.Equal(t, "k1", mod.MustFind(id, QueryParam{}).Get("key"))
+	mod.MustDestroy(id)
+	assert.Equal(t, []string{"k2"}, mod.MustPluckString("key", QueryParam{}))
+}
+
+func TestModelSoftDeleteUniqueBackup(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "唯一数据备份",
+		"table": { "name": "unique_backup_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "code", "type": "string", "length": 40, "unique": true },
+			{ "name": "serial", "type": "bigInteger", "unique": true, "nullable": true },
+			{ "name": "extra", "type": "json", "nullable": true }
+		],
+		"option": { "soft_deletes": true }
+	}`, "unique_backup_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("unique_backup_test")
+		delete(Models, "unique_backup_test")
+	}()
+
+	id := mod.MustCreate(maps.MapStr{"code": "A001", "serial": int64(9007199254740993), "extra": maps.MapStr{"tags": []string{"a"}}})
+	mod.MustDelete(id)
+
+	// 删除后唯一数据已释放
+	other := mod.MustCreate(maps.MapStr{"code": "A001"})
+	mod.MustDestroy(other)
+
+	mod.MustRestore(id)
+	row := mod.MustFind(id, QueryParam{})
+	assert.Equal(t, "A001", row.Get("code"))
+	assert.Equal(t, "9007199254740993", fmt.Sprintf("%v", row.Get("serial"))) // 大整数不丢失精度
+	assert.Equal(t, "a", row.Dot().Get("extra.tags.0"))
+}
+
+func TestModelUniqueScope(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "范围唯一",
+		"table": { "name": "unique_scope_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "manu_id", "type": "integer" },
+			{ "name": "mobile", "type": "string", "length": 20, "unique": { "scope": ["manu_id"], "ignore_deleted": true } },
+			{ "name": "code", "type": "string", "length": 20, "unique": true }
+		],
+		"option": { "soft_deletes": true }
+	}`, "unique_scope_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("unique_scope_test")
+		delete(Models, "unique_scope_test")
+	}()
+
+	assert.Equal(t, []string{"manu_id"}, mod.Columns["mobile"].UniqueScope.Scope)
+	assert.False(t, mod.Columns["mobile"].Unique)
+	assert.True(t, mod.Columns["code"].Unique)
+	assert.Equal(t, []string{"code"}, mod.seedKeys())
+
+	id := mod.MustCreate(maps.MapStr{"manu_id": 1, "mobile": "13900001111", "code": "a"})
+	other := mod.MustCreate(maps.MapStr{"manu_id": 2, "mobile": "13900001111", "code": "b"})
+	assert.Panics(t, func() { mod.MustCreate(maps.MapStr{"manu_id": 1, "mobile": "13900001111", "code": "c"}) })
+	assert.Panics(t, func() { mod.MustUpdate(other, maps.MapStr{"manu_id": 1}) })
+	assert.Panics(t, func() { mod.MustSave(maps.MapStr{"id": other, "manu_id": 1}) })
+	mod.MustUpdate(other, maps.MapStr{"mobile": "13900002222"})
+
+	errs := mod.ValidateUnique(maps.MapStr{"manu_id": 1, "mobile": "13900001111"}, nil, "en")
+	assert.Equal(t, []string{"mobile 13900001111 already exists"}, errs[0].Messages)
+	assert.Empty(t, mod.ValidateUnique(maps.MapStr{"mobile": "13900001111"}, id))
+
+	// 组合唯一索引
+	assert.Panics(t, func() {
+		mod.MustInsert([]string{"manu_id", "mobile", "code"}, [][]interface{}{{1, "13900001111", "d"}})
+	})
+
+	// 软删除后释放
+	mod.MustDelete(id)
+	mod.MustCreate(maps.MapStr{"manu_id": 1, "mobile": "13900001111", "code": "e"})
+}
+
+func TestModelAudit(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "审计测试",
+		"table": { "name": "audit_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "name", "type": "string", "length": 80 }
+		],
+		"option": { "audit": true }
+	}`, "audit_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("audit_test")
+		capsule.Query().Table(AuditTable).Where("model", "audit_test").Delete()
+		delete(Models, "audit_test")
+	}()
+
+	handle := mod.WithContext(WithActor(context.Background(), "admin"))
+	id := handle.MustCreate(maps.MapStr{"name": "foo"})
+	handle.MustUpdate(id, maps.MapStr{"name": "bar"})
+
+	logs, err := capsule.Query().Table(AuditTable).Where("model", "audit_test").OrderBy("id", "asc").Get()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(logs))
+	assert.Equal(t, EventCreate, logs[0]["op"])
+	assert.Equal(t, EventUpdate, logs[1]["op"])
+	assert.Equal(t, "admin", logs[1]["actor"])
+
+	// 批量写入 (每条记录一条审计日志)
+	handle.MustInsert([]string{"name"}, [][]interface{}{{"a"}, {"b"}})
+	assert.Equal(t, 3, handle.MustUpdateWhere(QueryParam{}, maps.MapStr{"name": "c"}))
+	assert.Equal(t, 3, handle.MustDestroyWhere(QueryParam{}))
+
+	logs, err = capsule.Query().Table(AuditTable).Where("model", "audit_test").OrderBy("id", "asc").Get()
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(logs))
+	ops := []interface{}{}
+	for _, log := range logs[2:] {
+		ops = append(ops, log["op"])
+		assert.NotNil(t, log["record_id"])
+	}
+	assert.Equal(t, []interface{}{
+		EventCreate, EventCreate,
+		EventUpdate, EventUpdate, EventUpdate,
+		EventDestroy, EventDestroy, EventDestroy,
+	}, ops)
+}
+
+func TestTransaction(t *testing.T) {
+	user := Select("user")
+	manu := Select("manu")
+	name := user.MustFind(1, QueryParam{}).Get("name")
+	short := manu.MustFind(1, QueryParam{}).Get("short_name")
+
+	// 返回错误时回滚
+	err := Transaction(func(m TxModels) error {
+		m.Select("user").MustUpdate(1, maps.MapStr{"name": "事务"})
+		m.Select("manu").MustUpdate(1, maps.MapStr{"short_name": "事务"})
+		assert.Equal(t, "事务", m.Select("user").MustFind(1, QueryParam{}).Get("name"))
+		return fmt.Errorf("rollback")
+	})
+	assert.Equal(t, "rollback", err.Error())
+	assert.Equal(t, name, user.MustFind(1, QueryParam{}).Get("name"))
+	assert.Equal(t, short, manu.MustFind(1, QueryParam{}).Get("short_name"))
+
+	// 发生异常时回滚, 异常继续抛出
+	assert.Panics(t, func() {
+		Transaction(func(m TxModels) error {
+			m.Select("user").MustUpdate(1, maps.MapStr{"name": "事务"})
+			m.Select("manu").MustFind(999, QueryParam{})
+			return nil
+		})
+	})
+	assert.Equal(t, name, user.MustFind(1, QueryParam{}).Get("name"))
+
+	// 提交
+	err = Transaction(func(m TxModels) error {
+		return m.Select("user").Update(1, maps.MapStr{"name": "事务"})
+	})
+	defer user.MustUpdate(1, maps.MapStr{"name": name})
+	assert.Nil(t, err)
+	assert.Equal(t, "事务", user.MustFind(1, QueryParam{}).Get("name"))
+}
+
+func TestModelFindForUpdate(t *testing.T) {
+	if Select("user").Driver == "sqlite3" {
+		t.Skip("sqlite3 不支持 SELECT ... FOR UPDATE")
+	}
+
+	locked := make(chan bool)
+	acquired := make(chan bool, 1)
+	go func() {
+		<-locked
+		Transaction(func(m TxModels) error {
+			_, err := m.Select("user").FindForUpdate(1, QueryParam{})
+			acquired <- true
+			return err
+		})
+	}()
+
+	err := Transaction(func(m TxModels) error {
+		row, err := m.Select("user").FindForUpdate(1, QueryParam{})
+		assert.Equal(t, "管理员", row.Get("name"))
+		locked <- true
+
+		// 第一个事务提交前, 第二个事务无法获得行锁
+		select {
+		case <-acquired:
+			assert.Fail(t, "row lock acquired before the first transaction committed")
+		case <-time.After(200 * time.Millisecond):
+		}
+		return err
+	})
+	assert.Nil(t, err)
+
+	// 第一个事务提交后, 第二个事务获得行锁
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "row lock not acquired after the first transaction committed")
+	}
+
+	_, err = Select("user").FindForUpdate(1, QueryParam{})
+	assert.NotNil(t, err)
+}
+
+func TestModelMustFindMany(t *testing.T) {
+	users := Select("user").MustFindMany([]interface{}{3, 1, 99, 2}, QueryParam{
+		Select: []interface{}{"id", "name"},
+		Withs:  map[string]With{"manu": {}},
+	})
+	assert.Equal(t, 3, len(users))
+	assert.Equal(t, int64(3), users[0].Get("id"))
+	assert.Equal(t, int64(1), users[1].Get("id"))
+	assert.Equal(t, int64(2), users[2].Get("id"))
+	assert.Equal(t, "北京云道天成科技有限公司", users[1].Dot().Get("manu.name"))
+
+	// 未选择主键
+	users = Select("user").MustFindMany([]interface{}{2, 1}, QueryParam{Select: []interface{}{"name"}})
+	assert.Equal(t, 2, len(users))
+	assert.Equal(t, "员工", users[0].Get("name"))
+	assert.Equal(t, "管理员", users[1].Get("name"))
+	assert.False(t, users[0].Has("id"))
+}
+
+func TestModelScope(t *testing.T) {
+	user := Select("user").Scope("enabled", func(param *QueryParam) {
+		param.Wheres = append(param.Wheres, QueryWhere{Column: "status", Value: "enabled"})
+	})
+	user.Scope("latest", func(param *QueryParam) {
+		param.Orders = append(param.Orders, QueryOrder{Column: "id", Option: "desc"})
+	})
+
+	all := user.MustGet(QueryParam{})
+	enabled := user.MustGet(QueryParam{Scopes: []string{"enabled", "latest"}})
+	assert.True(t, len(enabled) > 0)
+	assert.True(t, len(enabled) <= len(all))
+	for _, row := range enabled {
+		assert.Equal(t, "enabled", row.Get("status"))
+	}
+	assert.True(t, enabled[0].Get("id").(int64) >= enabled[len(enabled)-1].Get("id").(int64))
+
+	assert.Panics(t, func() {
+		user.MustGet(QueryParam{Scopes: []string{"undefined"}})
+	})
+}
+
+type tenantKey struct{}
+
+func TestModelGlobalScope(t *testing.T) {
+	Select("user").GlobalScope("tenant", func(ctx context.Context, param *QueryParam) {
+		if manu, has := ctx.Value(tenantKey{}).(int); has {
+			param.Wheres = append(param.Wheres, QueryWhere{Column: "manu_id", Value: manu})
+		}
+	})
+	defer func() {
+		scopesLock.Lock()
+		delete(globalScopes, "user")
+		scopesLock.Unlock()
+	}()
+
+	user := Select("user").WithContext(context.WithValue(context.Background(), tenantKey{}, 99))
+	assert.Equal(t, 0, len(user.MustGet(QueryParam{})))
+	assert.Panics(t, func() { user.MustFind(1, QueryParam{}) })
+
+	// PaginateCtx 的 ctx 仅用于取消, 全局查询范围使用 WithContext 绑定的上下文
+	res, err := user.PaginateCtx(context.Background(), QueryParam{}, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(res.Get("data").([]maps.MapStr)))
+
+	// 写入同样受全局查询范围约束
+	assert.NotNil(t, user.Update(1, maps.MapStr{"balance": 99}))
+	user.MustSave(maps.MapStr{"id": 1, "balance": 99})
+	assert.Equal(t, 0, user.MustUpdateWhere(QueryParam{}, maps.MapStr{"balance": 99}))
+	assert.Nil(t, user.Delete(1))
+	assert.Nil(t, user.Destroy(1))
+
+	admin := user.WithoutGlobalScope("tenant")
+	assert.Equal(t, len(Select("user").MustGet(QueryParam{})), len(admin.MustGet(QueryParam{})))
+	row := admin.MustFind(1, QueryParam{})
+	assert.Equal(t, "管理员", row.Get("name"))
+	assert.Equal(t, 0, any.Of(row.Get("balance")).CInt())
+}
+
+func TestModelSoftDeleteFormats(t *testing.T) {
+	formats := map[string]string{
+		"timestamp": `{ "column": "removed_at" }`,
+		"boolean":   `{ "format": "boolean" }`,
+		"status":    `{ "format": "status", "column": "state", "value": "deleted", "restore": "normal" }`,
+	}
+
+	for format, strategy := range formats {
+		name := "soft_delete_" + format
+		mod := LoadModel(fmt.Sprintf(`{
+			"name": "软删除测试",
+			"table": { "name": "%s" },
+			"columns": [
+				{ "name": "id", "type": "ID" },
+				{ "name": "name", "type": "string", "length": 80 },
+				{ "name": "state", "type": "string", "length": 20, "default": "normal" }
+			],
+			"option": { "soft_deletes": true },
+			"soft_delete": %s
+		}`, name, strategy), name)
+		mod.Migrate(true)
+
+		sd := mod.softDelete()
+		row := maps.MapStr{"name": format}
+		switch sd.Format { // 写入数据中的删除标记被忽略
+		case SoftDeleteTimestamp:
+			row[sd.Column] = "2021-10-01 00:00:00"
+		case SoftDeleteBoolean:
+			row[sd.Column] = true
+		}
+		id := mod.MustCreate(row)
+		assert.Equal(t, 1, len(mod.MustGet(QueryParam{})), format)
+
+		mod.MustDelete(id)
+		assert.Equal(t, 0, len(mod.MustGet(QueryParam{})), format)
+		assert.Equal(t, 1, len(mod.MustGet(QueryParam{WithTrashed: true})), format)
+
+		mod.MustRestore(id)
+		rows := mod.MustGet(QueryParam{})
+		assert.Equal(t, 1, len(rows), format)
+		if sd.Format == SoftDeleteStatus {
+			assert.Equal(t, "normal", rows[0].Get("state"))
+		}
+		assert.NotNil(t, mod.Restore(id), format) // 未删除的数据不能恢复
+
+		capsule.Schema().DropTableIfExists(name)
+		delete(Models, name)
+	}
+}
+
+func TestSetPool(t *testing.T) {
+	assert.Nil(t, SetPool(10, 20, time.Hour))
+	defer SetPool(0, 2, 0)
+
+	Select("user").MustFind(1, QueryParam{})
+	stats := PoolStatus()
+	assert.Greater(t, len(stats), 0)
+	assert.Equal(t, "primary.0", stats[0].Name)
+	assert.Equal(t, 10, stats[0].MaxOpen)
+	assert.LessOrEqual(t, stats[0].Idle, 10)
+	assert.Greater(t, stats[0].Open, 0)
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	defer atomic.StoreInt32(&dbState, dbIdle)
+
+	start := time.Now()
+	err := ConnectWithRetry(DBConfig{Driver: "mysql", DSN: "root:123456@tcp(127.0.0.1:1)/gou"}, 3, 10*time.Millisecond)
+	assert.NotNil(t, err)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(30*time.Millisecond))
+	assert.Equal(t, dbFailed, atomic.LoadInt32(&dbState))
+
+	driver := "mysql"
+	if TestDriver == "sqlite3" {
+		driver = "sqlite3"
+	}
+	err = ConnectWithRetry(DBConfig{Driver: driver, DSN: TestDSN}, 3, 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, dbConnected, atomic.LoadInt32(&dbState))
+	Select("user").MustFind(1, QueryParam{})
+}
+
+func TestModelLoad(t *testing.T) {
+	user := Select("user")
+	param := QueryParam{Select: []interface{}{"id", "manu_id"}, Orders: []QueryOrder{{Column: "id"}}}
+	rows := user.MustGet(param)
+	ResetStats()
+	user.MustLoad(rows, map[string]With{
+		"manu":      {Query: QueryParam{Select: []interface{}{"id", "name"}}},
+		"addresses": {Query: QueryParam{Select: []interface{}{"id", "location"}, Limit: 1}},
+	})
+	assert.Equal(t, int64(1), Stats()["manu"].Queries)
+	assert.Equal(t, int64(1), Stats()["address"].Queries)
+
+	for _, row := range rows {
+		manu := user.MustFind(row.Get("id"), QueryParam{Select: []interface{}{"id"}, Withs: map[string]With{"manu": {Query: QueryParam{Select: []interface{}{"id", "name"}}}}})
+		assert.Equal(t, manu.Dot().Get("manu.name"), row.Dot().Get("manu.name"))
+		addresses, ok := row.Get("addresses").([]maps.MapStr)
+		assert.True(t, ok)
+		assert.LessOrEqual(t, len(addresses), 1)
+		for _, address := range addresses {
+			assert.Equal(t, any.Of(row.Get("id")).CInt(), any.Of(address.Get("user_id")).CInt())
+		}
+	}
+
+	assert.NotNil(t, user.Load(rows, map[string]With{"undefined": {}}))
+	assert.NotNil(t, user.Load([]maps.MapStr{{"id": 1}}, map[string]With{"manu": {}}))
+}
+
+func TestModelIDGenerator(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "主键生成",
+		"table": { "name": "idgen_test" },
+		"columns": [
+			{ "name": "id", "type": "uuid", "primary": true, "generate": "uuid" },
+			{ "name": "name", "type": "string", "length": 40 }
+		]
+	}`, "idgen_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("idgen_test")
+		delete(Models, "idgen_test")
+	}()
+
+	assert.Equal(t, "id", mod.PrimaryKey)
+	id := mod.MustCreateGetID(maps.MapStr{"name": "a"})
+	assert.Equal(t, 36, len(id.(string)))
+	assert.Equal(t, "a", mod.MustFind(id, QueryParam{}).Get("name"))
+	assert.Equal(t, 0, mod.MustCreate(maps.MapStr{"name": "b"}))
+
+	mod.MustInsert([]string{"name"}, [][]interface{}{{"c"}, {"d"}})
+	rows := mod.MustGet(QueryParam{})
+	assert.Equal(t, 4, len(rows))
+	assert.NotEqual(t, rows[2].Get("id"), rows[3].Get("id"))
+
+	// 模型主键生成器优先
+	next := 1000
+	SetIDGenerator("idgen_test", func() interface{} { next++; return fmt.Sprintf("%d", next) })
+	defer SetIDGenerator("idgen_test", nil)
+	assert.Equal(t, 1001, mod.MustCreate(maps.MapStr{"name": "e"}))
+	custom := NewUUID()
+	assert.Equal(t, custom, mod.MustCreateGetID(maps.MapStr{"id": custom, "name": "f"})) // 已提供主键时不生成
+}
+
+func TestModelFindCached(t *testing.T) {
+	user := Select("user")
+	table := user.MetaData.Table.Name
+	SetCacheStore(NewMemoryCache())
+	defer func() {
+		SetCacheStore(nil)
+		capsule.Query().Table(table).Where("id", 1).Update(maps.MapStr{"balance": 0})
+	}()
+
+	param := QueryParam{Select: []interface{}{"id", "balance"}}
+	row := user.MustFindCached(1, param, time.Minute)
+	assert.Equal(t, 0, any.Of(row.Get("balance")).CInt())
+
+	// 绕过模型写入数据库, 读取缓存数据
+	capsule.Query().Table(table).Where("id", 1).Update(maps.MapStr{"balance": 100})
+	row = user.MustFindCached(1, param, time.Minute)
+	assert.Equal(t, 0, any.Of(row.Get("balance")).CInt())
+
+	// 不同查询参数分别缓存
+	row = user.MustFindCached(1, QueryParam{Select: []interface{}{"id", "name", "balance"}}, time.Minute)
+	assert.Equal(t, 100, any.Of(row.Get("balance")).CInt())
+
+	// 通过模型更新时清除缓存
+	user.MustUpdate(1, maps.MapStr{"balance": 200})
+	row = user.MustFindCached(1, param, time.Minute)
+	assert.Equal(t, 200, any.Of(row.Get("balance")).CInt())
+
+	_, err := user.FindCached(100000, param, time.Minute)
+	var notFound *ErrNotFound
+	assert.True(t, errors.As(err, &notFound))
+
+	// 写入时清理已过期的缓存
+	cache := NewMemoryCache()
+	cache.Set("expired", []byte("1"), time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	cache.Set("key", []byte("1"), 0)
+	assert.Equal(t, 1, len(cache.items))
+}
@@ -0,0 +1,100 @@
+package gou
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/xun/capsule"
+)
+
+// PoolStats 数据库连接池统计
+type PoolStats struct {
+	Name              string        `json:"name"`                // 连接名称 (primary.0, readonly.0, ...)
+	MaxOpen           int           `json:"max_open"`            // 最大连接数 (0 为不限制)
+	Open              int           `json:"open"`                // 当前连接数
+	InUse             int           `json:"in_use"`              // 使用中的连接数
+	Idle              int           `json:"idle"`                // 空闲连接数
+	WaitCount         int64         `json:"wait_count"`          // 等待连接的累计次数
+	WaitDuration      time.Duration `json:"wait_duration"`       // 等待连接的累计时长
+	MaxIdleClosed     int64         `json:"max_idle_closed"`     // 超出最大空闲连接数关闭的连接数
+	MaxLifetimeClosed int64         `json:"max_lifetime_closed"` // 超出最长使用时间关闭的连接数
+}
+
+// poolConn 连接池中的数据库连接
+type poolConn struct {
+	name string
+	db   interface {
+		SetMaxOpenConns(n int)
+		SetMaxIdleConns(n int)
+		SetConnMaxLifetime(d time.Duration)
+		Stats() sql.DBStats
+		Ping() error
+	}
+}
+
+// SetPool 设定全部数据库连接 (主库和只读库) 的连接池: 最大连接数, 最大空闲连接数, 连接最长使用时间 (0 为不限制)
+func SetPool(maxOpen int, maxIdle int, maxLifetime time.Duration) error {
+	conns := poolConns()
+	if len(conns) == 0 {
+		return fmt.Errorf("数据库连接尚未创建")
+	}
+	if maxOpen > 0 && maxIdle > maxOpen {
+		maxIdle = maxOpen
+	}
+	for _, conn := range conns {
+		conn.db.SetMaxOpenConns(maxOpen)
+		conn.db.SetMaxIdleConns(maxIdle)
+		conn.db.SetConnMaxLifetime(maxLifetime)
+	}
+	return nil
+}
+
+// MustSetPool 设定全部数据库连接的连接池, 失败抛出异常
+func MustSetPool(maxOpen int, maxIdle int, maxLifetime time.Duration) {
+	err := SetPool(maxOpen, maxIdle, maxLifetime)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+}
+
+// PoolStatus 读取全部数据库连接的连接池统计 (用于监控和健康检查)
+func PoolStatus() []PoolStats {
+	res := []PoolStats{}
+	for _, conn := range poolConns() {
+		stats := conn.db.Stats()
+		res = append(res, PoolStats{
+			Name:              conn.name,
+			MaxOpen:           stats.MaxOpenConnections,
+			Open:              stats.OpenConnections,
+			InUse:             stats.InUse,
+			Idle:              stats.Idle,
+			WaitCount:         stats.WaitCount,
+			WaitDuration:      stats.WaitDuration,
+			MaxIdleClosed:     stats.MaxIdleClosed,
+			MaxLifetimeClosed: stats.MaxLifetimeClosed,
+		})
+	}
+	return res
+}
+
+// poolConns 全局数据库连接 (capsule.Global) 中的主库和只读库连接
+func poolConns() []poolConn {
+	return managerConns(capsule.Global)
+}
+
+// managerConns 数据库连接管理器中的主库和只读库连接
+func managerConns(manager *capsule.Manager) []poolConn {
+	conns := []poolConn{}
+	if manager == nil || manager.Pool == nil {
+		return conns
+	}
+	for i, conn := range manager.Pool.Primary {
+		conns = append(conns, poolConn{name: fmt.Sprintf("primary.%d", i), db: conn.DB})
+	}
+	for i, conn := range manager.Pool.Readonly {
+		conns = append(conns, poolConn{name: fmt.Sprintf("readonly.%d", i), db: conn.DB})
+	}
+	return conns
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"strings"
 
 	"github.com/yaoapp/gou/query/share"
 	"github.com/yaoapp/kun/any"
@@ -254,6 +255,21 @@ func (process *Process) ArgsStrings(index int) []string {
 	return columns
 }
 
+// argsIDs 读取主键列表参数值, 字符串参数按 "," 分割 (如 URL 参数 1,2,3)
+func (process *Process) argsIDs(index int) []interface{} {
+	process.ValidateArgNums(index + 1)
+	if value, ok := process.Args[index].(string); ok {
+		ids := []interface{}{}
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	return process.ArgsArray(index)
+}
+
 // ArgsArray 读取 []interface{} 参数值
 func (process *Process) ArgsArray(index int) []interface{} {
 	process.ValidateArgNums(index + 1)
@@ -1,6 +1,7 @@
 package gou
 
 import (
+	"context"
 	"strings"
 
 	"github.com/yaoapp/gou/session"
@@ -61,6 +62,21 @@ func (process *Process) WithGlobal(global map[string]interface{}) *Process {
 	return process
 }
 
+// WithContext 设定请求上下文
+func (process *Process) WithContext(ctx context.Context) *Process {
+	process.Context = ctx
+	return process
+}
+
+// model 读取处理器对应的模型, 设定请求上下文时绑定该上下文
+func (process *Process) model() *Model {
+	mod := Select(process.Class)
+	if process.Context != nil {
+		return mod.WithContext(process.Context)
+	}
+	return mod
+}
+
 // 解析方法
 func (process *Process) make() (err error) {
 	defer func() { err = exception.Catch(recover()) }()
@@ -141,7 +157,7 @@ func processPlugin(process *Process) interface{} {
 // processFlow 运行工作流
 func processFlow(process *Process) interface{} {
 	name := strings.TrimPrefix(process.Name, "flows.")
-	flow := SelectFlow(name).WithGlobal(process.Global).WithSID(process.Sid)
+	flow := SelectFlow(name).WithGlobal(process.Global).WithSID(process.Sid).WithContext(process.Context)
 	return flow.Exec(process.Args...)
 }
 
@@ -1,5 +1,7 @@
 package gou
 
+import "context"
+
 // Process 运行器
 type Process struct {
 	Name    string
@@ -9,6 +11,7 @@ type Process struct {
 	Args    []interface{}
 	Global  map[string]interface{} // 全局变量
 	Sid     string                 // 会话ID
+	Context context.Context        // 请求上下文 (模型处理器通过 Model.WithContext 绑定, 读取请求事务等)
 	Handler ProcessHandler
 }
 
@@ -1,6 +1,8 @@
 package gou
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	jsoniter "github.com/json-iterator/go"
@@ -289,6 +291,66 @@ func TestProcessDestroyWhere(t *testing.T) {
 	assert.Equal(t, effect, 3)
 }
 
+func TestProcessDestroyMany(t *testing.T) {
+	columns := []string{"name", "manu_id", "type", "idcard", "mobile", "password", "key", "secret", "status"}
+	rows := [][]interface{}{
+		{"用户创建1", 5, "user", "23082619820207006X", "13900004444", "qV@uT1DI", "XZ12MiP1", "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN", "enabled"},
+		{"用户创建2", 5, "user", "33082619820207006X", "13900005555", "qV@uT1DI", "XZ12MiP2", "wBeYjL7FjbcvpAdBrxtDFfjydsoPKhRN", "enabled"},
+	}
+
+	user := Select("user")
+	user.MustInsert(columns, rows)
+	defer capsule.Query().Table(user.MetaData.Table.Name).Where("name", "like", "用户创建%").Delete()
+
+	ids := []string{}
+	for _, row := range user.MustGet(QueryParam{Select: []interface{}{"id"}, Wheres: []QueryWhere{{Column: "manu_id", Value: 5}}}) {
+		ids = append(ids, fmt.Sprintf("%v", row.Get("id")))
+	}
+
+	assert.Equal(t, 0, NewProcess("models.user.DestroyMany", "").Run().(int))
+	assert.Equal(t, 2, NewProcess("models.user.DestroyMany", strings.Join(ids, ",")).Run().(int))
+}
+
+func TestProcessNamingStrategy(t *testing.T) {
+	assert.Equal(t, "manuId", camelCase("manu_id"))
+	assert.Equal(t, "__restore_data", camelCase("__restore_data"))
+	assert.Equal(t, "manu_id", snakeCase("manuId"))
+
+	SetNamingStrategy(CamelCase)
+	defer SetNamingStrategy(SnakeCase)
+
+	res := NewProcess("models.user.Find", 1, QueryParam{
+		Select: []interface{}{"id", "manuId", "createdAt", "extra"},
+		Withs:  map[string]With{"manu": {Query: QueryParam{Select: []interface{}{"id", "shortName"}}}},
+	}).Run().(maps.MapStr)
+	assert.Equal(t, 1, any.Of(res.Get("manuId")).CInt())
+	assert.True(t, res.Has("createdAt"))
+	assert.False(t, res.Has("manu_id"))
+	assert.Equal(t, "男", res.Dot().Get("extra.sex"))
+	assert.Equal(t, "云道天成", res.Dot().Get("manu.shortName"))
+
+	rows := NewProcess("models.user.Get", QueryParam{
+		Wheres: []QueryWhere{{Column: "manuId", Value: 1}},
+		Orders: []QueryOrder{{Column: "createdAt"}},
+	}).Run().([]maps.MapStr)
+	assert.Greater(t, len(rows), 0)
+	for _, row := range rows {
+		assert.Equal(t, 1, any.Of(row.Get("manuId")).CInt())
+	}
+
+	effect := NewProcess("models.user.UpdateWhere",
+		QueryParam{Wheres: []QueryWhere{{Column: "id", Value: 1}}},
+		maps.MapStr{"manuId": 2},
+	).Run().(int)
+	user := Select("user")
+	row := user.MustFind(1, QueryParam{})
+
+	// 恢复数据
+	capsule.Query().Table(user.MetaData.Table.Name).Where("id", 1).Update(maps.MapStr{"manu_id": 1})
+	assert.Equal(t, 1, effect)
+	assert.Equal(t, 2, any.Of(row.Get("manu_id")).CInt())
+}
+
 func TestProcessRegisterProcessHandler(t *testing.T) {
 
 	RegisterProcessHandler("charts", func(process *Process) interface{} {
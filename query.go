@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/xun/dbal"
 	"github.com/yaoapp/xun/dbal/query"
 )
 
@@ -15,6 +16,7 @@ var opmap map[string]string = map[string]string{
 	"lt":   "<",
 	"ge":   ">=",
 	"le":   "<=",
+	"ne":   "<>",
 }
 
 // NewQuery 新建查询栈
@@ -29,13 +31,18 @@ func (param QueryParam) Query(stack *QueryStack, stackParams ...QueryStackParam)
 		return stack
 	}
 	mod := Select(param.Model)
-	param.Table = mod.MetaData.Table.Name
+	param.groupWheres()
+	mod.applyScopes(&param)
+	param.Table = mod.TableName()
 	if param.Alias == "" {
-		param.Alias = param.Table
+		param.Alias = mod.MetaData.Table.Name
 	}
 
 	exportPrefix := param.Export
+	root := stack == nil
 	if stack == nil {
+		param.groupWheres()
+		mod.applyGlobalScopes(&param)
 		stack = MakeQueryStack()
 		stackParam := QueryStackParam{
 			QueryParam: param,
@@ -46,42 +53,71 @@ func (param QueryParam) Query(stack *QueryStack, stackParams ...QueryStackParam)
 
 		builder := QueryStackBuilder{
 			Model:     mod,
-			Query:     capsule.Query().Table(param.Table + " as " + param.Alias),
+			Query:     param.newQuery().Table(param.Table + " as " + param.Alias),
 			ColumnMap: map[string]ColumnMap{},
 		}
 
 		exportPrefix = ""
 		stack.Push(builder, stackParam)
+
+		// 行锁
+		param.lock(stack.Query())
 	}
 
 	// Select
 	if len(param.Select) == 0 {
-		param.Select = mod.ColumnNames // Select All
+		param.Select = mod.VisibleColumnNames() // Select All
 	}
+	param.applyExcept()
 
 	selects := mod.Filterselect(param.Alias, param.Select, stack.Builder().ColumnMap, exportPrefix)
 	stack.Query().SelectAppend(selects...)
 
+	// 关联数据计数 (相关子查询)
+	if root {
+		for _, name := range param.WithCount {
+			param.withCount(name, stack.Query(), mod)
+		}
+		for _, agg := range param.Aggregates {
+			param.withAggregate(agg, stack.Query(), mod)
+		}
+	}
+
 	// Where
 	for _, where := range param.Wheres {
 		param.Where(where, stack.Query(), mod)
 	}
 
+	// 全文检索
+	if param.Search != nil && param.Search.Keyword != "" {
+		sql, bindings := mod.search(param.Alias, *param.Search)
+		stack.Query().WhereRaw(sql, bindings...)
+	}
+
 	// 软删除
-	if mod.MetaData.Option.SoftDeletes {
-		param.Where(QueryWhere{Column: "deleted_at", OP: "null"}, stack.Query(), mod)
+	if mod.MetaData.Option.SoftDeletes && !param.WithTrashed {
+		param.Where(mod.softDelete().notDeleted(), stack.Query(), mod)
 	}
 
 	// Order
-	for _, order := range param.Orders {
+	orders := param.Orders
+	if root {
+		orders = param.tiebreak(mod)
+	}
+	for _, order := range orders {
 		param.Order(order, stack.Query(), mod)
 	}
 
-	// Limit
-	if param.Limit > 0 {
+	// Limit (hasMany 关联查询按每条上级记录生效, 见 QueryStack.runHasMany)
+	if param.Limit > 0 && !batchedRelation(stackParams) {
 		stack.Query().Limit(param.Limit)
 	}
 
+	// Offset
+	if param.offset > 0 {
+		stack.Query().Offset(param.offset)
+	}
+
 	// Withs
 	for name, with := range param.Withs {
 		param.With(name, stack, with, mod)
@@ -90,6 +126,47 @@ func (param QueryParam) Query(stack *QueryStack, stackParams ...QueryStackParam)
 	return stack
 }
 
+// groupWheres 顶层条件含 orwhere 时整体分组, 使后续追加的查询范围、软删除等条件与整组条件 AND 连接
+// 如 [a, orwhere b] 追加 c 后为 (a OR b) AND c, 而非 a OR b AND c
+func (param *QueryParam) groupWheres() {
+	for _, where := range param.Wheres {
+		if strings.ToLower(where.Method) == "orwhere" {
+			param.Wheres = []QueryWhere{{Wheres: param.Wheres}}
+			return
+		}
+	}
+}
+
+// batchedRelation 是否为批量读取的 hasMany 关联查询
+func batchedRelation(stackParams []QueryStackParam) bool {
+	if len(stackParams) == 0 {
+		return false
+	}
+	typ := stackParams[0].Relation.Type
+	return typ == "hasMany" || typ == "morphMany"
+}
+
+// lock 行锁 (需在事务中)
+func (param QueryParam) lock(qb query.Query) {
+	if param.Lock == "" {
+		return
+	}
+
+	if param.tx == nil {
+		exception.New("行锁(%s)需要在事务中使用", 400, param.Lock).Throw()
+	}
+
+	switch strings.ToLower(param.Lock) {
+	case "update":
+		qb.LockForUpdate()
+		return
+	case "share":
+		qb.SharedLock()
+		return
+	}
+	exception.New("行锁类型(%s)错误, 应为 update 或 share", 400, param.Lock).Throw()
+}
+
 // With 关联查询
 func (param QueryParam) With(name string, stack *QueryStack, with With, mod *Model) {
 	rel, has := mod.MetaData.Relations[name]
@@ -102,14 +179,21 @@ func (param QueryParam) With(name string, stack *QueryStack, with With, mod *Mod
 	case "hasOne":
 		param.Export = rel.Name
 		param.withHasOne(stack, rel, with)
+		stack.Builder().withDefault(rel.Name, with.Default)
 		return
 	case "hasOneThrough":
 		param.withHasOneThrough(stack, rel, with)
+		stack.Builder().withDefault(rel.Name, with.Default)
 		return
 	case "hasMany":
 		param.withHasMany(stack, rel, with)
 		return
-
+	case "morphMany":
+		param.withMorphMany(stack, rel, with)
+		return
+	case "morphTo":
+		param.withMorphTo(stack, rel, with)
+		return
 	}
 
 }
@@ -140,8 +224,13 @@ func (param QueryParam) withHasOne(stack *QueryStack, rel Relation, with With) {
 	withModel := Select(rel.Model)
 	withParam := with.Query
 	withParam.Model = rel.Model
-	withParam.Table = withModel.MetaData.Table.Name
-	withParam.Alias = withParam.Table + "__rel__" // 临时BUG修复，这里整个逻辑需要优化
+	withParam.Table = withModel.TableName()
+	withParam.Alias = withModel.MetaData.Table.Name + "__rel__" // 临时BUG修复，这里整个逻辑需要优化
+
+	// 自关联 (如 parent), 按关联名称区分别名, 避免与查询表别名或其他自关联冲突
+	if rel.Model == param.Model && rel.Name != "" {
+		withParam.Alias = rel.Name + "__rel__"
+	}
 	if param.Alias != "" {
 		withParam.Alias = param.Alias + "_" + withParam.Alias
 	}
@@ -161,7 +250,7 @@ func (param QueryParam) withHasOne(stack *QueryStack, rel Relation, with With) {
 		foreignLen := len(foreignArr)
 		tab := strings.Join(foreignArr[0:foreignLen-1], ".")
 		field := foreignArr[foreignLen-1]
-		if tab != param.Table {
+		if tablePrefix+tab != param.Table {
 			foreign = tab + "__rel__" + "." + field
 		}
 		fmt.Println(tab, param.Table, rel.Foreign, foreign)
@@ -185,16 +274,16 @@ func (param QueryParam) withHasOne(stack *QueryStack, rel Relation, with With) {
 
 			sub.Table(withSubParam.Table)
 
-			// Select
-			if len(withParam.Select) == 0 {
+			// Select (含计算字段时读取全部字段, 计算字段在外层查询中计算)
+			if len(withParam.Select) == 0 || withParam.hasSelectExpr() {
 				withSubParam.Select = withModel.ColumnNames // Select All
 			} else if !withParam.hasSelectColumn(rel.Key) {
 				withSubParam.Select = append(withParam.Select, rel.Key)
 			}
 
 			// 软删除
-			if withModel.MetaData.Option.SoftDeletes && !withSubParam.hasSelectColumn("deleted_at") {
-				withSubParam.Select = append(withSubParam.Select, "deleted_at")
+			if column := withModel.softDelete().Column; withModel.MetaData.Option.SoftDeletes && !withSubParam.hasSelectColumn(column) {
+				withSubParam.Select = append(withSubParam.Select, column)
 			}
 
 			selects := withModel.Filterselect("", withSubParam.Select, nil, "")
@@ -223,6 +312,29 @@ func (param QueryParam) withHasOne(stack *QueryStack, rel Relation, with With) {
 	withParam.Query(stack)
 }
 
+// tiebreak 排序字段不含主键、唯一字段或完整的唯一索引时, 追加主键排序作为最后的排序条件, 使相同排序值的数据顺序 (分页边界) 确定
+// 未指定排序或 NoTiebreak 时不追加
+func (param QueryParam) tiebreak(mod *Model) []QueryOrder {
+	if param.NoTiebreak || len(param.Orders) == 0 || mod.PrimaryKey == "" {
+		return param.Orders
+	}
+
+	names := []string{}
+	for _, order := range param.Orders {
+		if order.Rel != "" {
+			continue
+		}
+		if order.Column == mod.PrimaryKey {
+			return param.Orders
+		}
+		names = append(names, order.Column)
+	}
+	if mod.uniqueMatch(names) {
+		return param.Orders
+	}
+	return append(append([]QueryOrder{}, param.Orders...), QueryOrder{Column: mod.PrimaryKey})
+}
+
 // Order 排序条件
 func (param QueryParam) Order(order QueryOrder, qb query.Query, mod *Model) {
 
@@ -281,6 +393,74 @@ func (param QueryParam) Order(order QueryOrder, qb query.Query, mod *Model) {
 	qb.OrderBy(column, order.Option)
 }
 
+// windowOrder 窗口函数的排序子句 (按排序条件, 最后按主键), 含关联数据排序或加密字段排序时返回 false
+func (param QueryParam) windowOrder(mod *Model) (string, bool) {
+	orders := []string{}
+	for _, order := range param.Orders {
+		if order.Rel != "" {
+			return "", false
+		}
+		column, ok := mod.FliterWhere(param.Alias, order.Column).(string)
+		if !ok {
+			return "", false
+		}
+		option := "ASC"
+		if strings.ToLower(order.Option) == "desc" {
+			option = "DESC"
+		}
+		orders = append(orders, mod.quote(column)+" "+option)
+	}
+	orders = append(orders, mod.quote(param.Alias+"."+mod.PrimaryKey))
+	return strings.Join(orders, ", "), true
+}
+
+// And 分组查询条件, 组内条件以 AND 连接, 如 And(Or(a, b), c) => (a OR b) AND c
+func And(wheres ...QueryWhere) QueryWhere {
+	return whereGroup("where", wheres)
+}
+
+// Or 分组查询条件, 组内条件以 OR 连接, 如 Or(And(a, b), c) => (a AND b) OR c
+func Or(wheres ...QueryWhere) QueryWhere {
+	return whereGroup("orwhere", wheres)
+}
+
+// DateBetween 日期范围查询条件, 包含开始日期和结束日期全天 (按模型时区计算), 日期为空时不限制, 如 DateBetween("created_at", "2021-01-01", "2021-01-31")
+func DateBetween(column string, start string, end string) QueryWhere {
+	return QueryWhere{Column: column, OP: "date_between", Value: []interface{}{start, end}}
+}
+
+// whereDateBetween 日期范围查询条件 (>= 开始, < 结束日期次日), 开始和结束日期均为空时返回 nil, 日期格式错误时抛出异常
+func (mod *Model) whereDateBetween(column interface{}, where QueryWhere) func(query.Query) {
+	start, end, err := mod.dateBetween(where.Column, where.Value)
+	if err != nil {
+		exception.Err(err, 400).Throw()
+	}
+	if start == "" && end == "" {
+		return nil
+	}
+	return func(sub query.Query) {
+		if start != "" {
+			sub.Where(column, ">=", start)
+		}
+		if end != "" {
+			sub.Where(column, "<", end)
+		}
+	}
+}
+
+// whereGroup 分组查询条件, 组内第二个及之后的条件按 method 连接
+func whereGroup(method string, wheres []QueryWhere) QueryWhere {
+	res := QueryWhere{Wheres: []QueryWhere{}}
+	for i, where := range wheres {
+		where.Method = "where"
+		if i > 0 {
+			where.Method = method
+		}
+		res.Wheres = append(res.Wheres, where)
+	}
+	return res
+}
+
 // Where 查询条件
 func (param QueryParam) Where(where QueryWhere, qb query.Query, mod *Model) {
 
@@ -337,14 +517,24 @@ func (param QueryParam) Where(where QueryWhere, qb query.Query, mod *Model) {
 
 	// Sub wheres
 	if where.Wheres != nil {
-		qb.Where(func(sub query.Query) {
+		group := func(sub query.Query) {
 			for _, subwhere := range where.Wheres {
 				param.Where(subwhere, sub, m)
 			}
-		})
+		}
+		if strings.ToLower(where.Method) == "orwhere" {
+			qb.OrWhere(group)
+			return
+		}
+		qb.Where(group)
 		return
 	}
 
+	if err := m.whereColumn(where.Column); err != nil {
+		exception.Err(err, 400).Throw()
+	}
+
+	where.Value = m.fliterWhereValue(where.Column, where.OP, where.Value)
 	column := m.FliterWhere(alias, where.Column)
 	switch strings.ToLower(where.Method) {
 	case "where":
@@ -360,12 +550,25 @@ func (param QueryParam) Where(where QueryWhere, qb query.Query, mod *Model) {
 				qb.Where(column, "like", "%"+value+"%")
 			}
 			break
+		case "ilike", "imatch":
+			sql, bindings := m.ilike(column, where.OP, where.Value)
+			qb.WhereRaw(sql, bindings...)
+			break
 		case "in":
 			if value, ok := where.Value.(string); ok {
 				where.Value = strings.Split(value, ",")
 			}
 			qb.WhereIn(column, where.Value)
 			break
+		case "json_contains":
+			sql, bindings := m.jsonContains(where.Column, column, where.Value)
+			qb.WhereRaw(sql, bindings...)
+			break
+		case "date_between":
+			if group := m.whereDateBetween(column, where); group != nil {
+				qb.Where(group)
+			}
+			break
 		default:
 			op, has := opmap[where.OP]
 			if !has {
@@ -387,11 +590,22 @@ func (param QueryParam) Where(where QueryWhere, qb query.Query, mod *Model) {
 				qb.Where(column, "like", "%"+value+"%")
 			}
 			break
+		case "ilike", "imatch":
+			sql, bindings := m.ilike(column, where.OP, where.Value)
+			qb.OrWhereRaw(sql, bindings...)
+			break
 		case "in":
 			if value, ok := where.Value.(string); ok {
 				where.Value = strings.Split(value, ",")
 			}
 			qb.OrWhereIn(column, where.Value)
+		case "json_contains":
+			sql, bindings := m.jsonContains(where.Column, column, where.Value)
+			qb.OrWhereRaw(sql, bindings...)
+		case "date_between":
+			if group := m.whereDateBetween(column, where); group != nil {
+				qb.OrWhere(group)
+			}
 		default:
 			op, has := opmap[where.OP]
 			if !has {
@@ -409,19 +623,18 @@ func (param QueryParam) withHasMany(stack *QueryStack, rel Relation, with With)
 	withModel := Select(rel.Model)
 	withParam := with.Query
 	withParam.Model = rel.Model
-	withParam.Table = withModel.MetaData.Table.Name
-	withParam.Alias = withParam.Table
-	withParam.Alias = withParam.Table
+	withParam.Table = withModel.TableName()
+	withParam.Alias = withModel.MetaData.Table.Name
+	withParam.tx, withParam.ctx, withParam.without = param.tx, param.ctx, param.without
 	if param.Alias != "" {
 		withParam.Alias = param.Alias + "_" + withParam.Alias
 	}
 
 	// Select & 添加关联主键
 	if len(withParam.Select) == 0 {
-		withParam.Select = withModel.ColumnNames // Select all
-	} else if !withParam.hasSelectColumn(rel.Key) {
-		withParam.Select = append(withParam.Select, rel.Key) // 添加关联主键
+		withParam.Select = withModel.VisibleColumnNames() // Select all
 	}
+	withParam.keepColumn(rel.Key)
 
 	// 添加关联外键
 	if !param.hasSelectColumn(rel.Foreign) {
@@ -433,11 +646,199 @@ func (param QueryParam) withHasMany(stack *QueryStack, rel Relation, with With)
 	stackParam := QueryStackParam{
 		QueryParam: withParam,
 		Relation:   rel,
+		HideKeys:   with.HideKeys,
 	}
 	newStack := withParam.Query(nil, stackParam)
 	stack.Merge(newStack)
 }
 
+// withMorphMany morphMany 多态关联查询 (按类型字段过滤的 hasMany)
+func (param QueryParam) withMorphMany(stack *QueryStack, rel Relation, with With) {
+	value := rel.MorphValue
+	if value == "" {
+		value = param.Model
+	}
+	wheres := append([]QueryWhere{}, with.Query.Wheres...)
+	with.Query.Wheres = append(wheres, QueryWhere{Column: rel.Morph, Value: value})
+	param.withHasMany(stack, rel, with)
+}
+
+// withMorphTo morphTo 多态关联查询 (关联模型由类型字段决定, 执行查询栈时按类型分组读取)
+func (param QueryParam) withMorphTo(stack *QueryStack, rel Relation, with With) {
+
+	// 添加类型字段和关联外键
+	mod := Select(param.Model)
+	columns := []interface{}{}
+	for _, column := range []string{rel.Morph, rel.Foreign} {
+		if !param.hasSelectColumn(column) {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) > 0 {
+		selects := mod.Filterselect(param.Alias, columns, stack.Builder().ColumnMap, "")
+		stack.Query().SelectAppend(selects...)
+	}
+
+	withParam := with.Query
+	withParam.tx, withParam.ctx, withParam.without = param.tx, param.ctx, param.without
+	newStack := MakeQueryStack()
+	newStack.Push(QueryStackBuilder{ColumnMap: map[string]ColumnMap{}}, QueryStackParam{
+		QueryParam: withParam,
+		Relation:   rel,
+	})
+	stack.Merge(newStack)
+}
+
+// withCount 添加关联数据计数字段 name_count
+// (SELECT COUNT(*) FROM related WHERE related.key = alias.foreign) AS name_count
+func (param QueryParam) withCount(name string, qb query.Query, mod *Model) {
+	withModel, wheres := param.correlate(name, name+"__count", mod)
+	qb.SelectAppend(dbal.Raw(fmt.Sprintf(
+		"(SELECT COUNT(*) FROM %s AS %s WHERE %s) AS %s",
+		mod.quote(withModel.TableName()), mod.quote(name+"__count"), strings.Join(wheres, " AND "), mod.quote(name+"_count"),
+	)))
+}
+
+// WithMin 关联数据最小值, 结果字段为 {name}_min_{column}
+func WithMin(name string, column string) WithAggregate {
+	return WithAggregate{Rel: name, Func: "min", Column: column}
+}
+
+// WithMax 关联数据最大值, 结果字段为 {name}_max_{column}
+func WithMax(name string, column string) WithAggregate {
+	return WithAggregate{Rel: name, Func: "max", Column: column}
+}
+
+// WithSum 关联数据合计, 结果字段为 {name}_sum_{column}
+func WithSum(name string, column string) WithAggregate {
+	return WithAggregate{Rel: name, Func: "sum", Column: column}
+}
+
+// WithAvg 关联数据平均值, 结果字段为 {name}_avg_{column}
+func WithAvg(name string, column string) WithAggregate {
+	return WithAggregate{Rel: name, Func: "avg", Column: column}
+}
+
+// WithLatest 最新一条关联数据 (按主键倒序) 的字段值, 结果字段为 {name}_latest (关联模型主键) 或 {name}_latest_{column}
+func WithLatest(name string, column ...string) WithAggregate {
+	agg := WithAggregate{Rel: name, Func: "latest"}
+	if len(column) > 0 {
+		agg.Column = column[0]
+	}
+	return agg
+}
+
+// withAggregate 添加关联数据聚合字段
+// (SELECT MAX(related.column) FROM related WHERE related.key = alias.foreign) AS name_max_column
+func (param QueryParam) withAggregate(agg WithAggregate, qb query.Query, mod *Model) {
+	fn := strings.ToLower(agg.Func)
+	alias := agg.Rel + "__" + fn
+	withModel, wheres := param.correlate(agg.Rel, alias, mod)
+
+	column := agg.Column
+	as := agg.Rel + "_" + fn + "_" + column
+	if fn == "latest" && column == "" {
+		column = withModel.PrimaryKey
+		as = agg.Rel + "_latest"
+	}
+	if _, has := withModel.Columns[column]; !has {
+		exception.New("模型 %s 字段 %s 不存在", 400, withModel.Name, column).Throw()
+	}
+
+	var sql string
+	switch fn {
+	case "min", "max", "sum", "avg":
+		sql = fmt.Sprintf(
+			"(SELECT %s(%s) FROM %s AS %s WHERE %s) AS %s",
+			strings.ToUpper(fn), mod.quote(alias+"."+column),
+			mod.quote(withModel.TableName()), mod.quote(alias), strings.Join(wheres, " AND "), mod.quote(as),
+		)
+	case "latest":
+		sql = fmt.Sprintf(
+			"(SELECT %s FROM %s AS %s WHERE %s ORDER BY %s DESC LIMIT 1) AS %s",
+			mod.quote(alias+"."+column),
+			mod.quote(withModel.TableName()), mod.quote(alias), strings.Join(wheres, " AND "),
+			mod.quote(alias+"."+withModel.PrimaryKey), mod.quote(as),
+		)
+	default:
+		exception.New("不支持的关联聚合函数 %s", 400, agg.Func).Throw()
+	}
+	qb.SelectAppend(dbal.Raw(sql))
+}
+
+// correlate 关联数据相关子查询的关联条件 (关联字段、多态类型、软删除)
+func (param QueryParam) correlate(name string, alias string, mod *Model) (*Model, []string) {
+	rel, has := mod.MetaData.Relations[name]
+	if !has {
+		exception.New("模型 %s 未定义关联 %s", 400, mod.Name, name).Throw()
+	}
+
+	switch rel.Type {
+	case "hasOne", "hasMany", "morphMany":
+	default:
+		exception.New("关联 %s (%s) 不支持计数和聚合", 400, name, rel.Type).Throw()
+	}
+
+	withModel := Select(rel.Model)
+	wheres := []string{fmt.Sprintf("%s = %s", mod.quote(alias+"."+rel.Key), mod.quote(param.Alias+"."+rel.Foreign))}
+	if rel.Type == "morphMany" {
+		value := rel.MorphValue
+		if value == "" {
+			value = mod.Name
+		}
+		wheres = append(wheres, fmt.Sprintf("%s = %s", mod.quote(alias+"."+rel.Morph), sqlString(value)))
+	}
+	if withModel.MetaData.Option.SoftDeletes {
+		sd := withModel.softDelete()
+		wheres = append(wheres, sd.notDeletedSQL(mod.quote(alias+"."+sd.Column)))
+	}
+	return withModel, wheres
+}
+
+// applyExcept 从查询字段中移除 Except 中的字段
+func (param *QueryParam) applyExcept() {
+	if len(param.Except) == 0 {
+		return
+	}
+	except := map[string]bool{}
+	for _, name := range param.Except {
+		except[name] = true
+	}
+	selects := []interface{}{}
+	for _, col := range param.Select {
+		if name, ok := col.(string); ok && except[name] {
+			continue
+		}
+		selects = append(selects, col)
+	}
+	param.Select = selects
+	param.Except = nil
+}
+
+// keepColumn 确保读取字段 (关联键等): 从 Except 中移除, 已指定查询字段时追加
+func (param *QueryParam) keepColumn(column string) {
+	except := []string{}
+	for _, name := range param.Except {
+		if name != column {
+			except = append(except, name)
+		}
+	}
+	param.Except = except
+	if len(param.Select) > 0 && !param.hasSelectColumn(column) {
+		param.Select = append(append([]interface{}{}, param.Select...), column)
+	}
+}
+
+// hasSelectExpr 是否含计算字段
+func (param QueryParam) hasSelectExpr() bool {
+	for _, col := range param.Select {
+		if _, _, ok := selectExpr(col); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // hasSelectColumn 检查字段是否已存在
 func (param QueryParam) hasSelectColumn(column interface{}) bool {
 	for _, col := range param.Select {
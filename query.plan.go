@@ -0,0 +1,81 @@
+package gou
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// QueryPlanCache 是否缓存查询计划 (字段解析结果), 适用于高频调用相同结构查询的接口
+// 查询计划按查询结构 (模型, 别名, 选择字段) 缓存, 不包含查询条件数值; 查询构造器携带绑定数值, 每次查询仍重新构造
+// 载入或重新载入模型时自动清空
+var QueryPlanCache = false
+
+// selectPlan 字段解析结果
+type selectPlan struct {
+	selects []interface{}
+	columns map[string]ColumnMap
+}
+
+var queryPlans sync.Map
+var queryPlanHits, queryPlanMisses int64
+
+// QueryPlanStats 查询计划缓存命中和未命中次数
+func QueryPlanStats() (hits int64, misses int64) {
+	return atomic.LoadInt64(&queryPlanHits), atomic.LoadInt64(&queryPlanMisses)
+}
+
+// ResetQueryPlans 清空查询计划缓存和统计
+func ResetQueryPlans() {
+	clearQueryPlans()
+	atomic.StoreInt64(&queryPlanHits, 0)
+	atomic.StoreInt64(&queryPlanMisses, 0)
+}
+
+// clearQueryPlans 清空查询计划缓存 (模型定义变更)
+func clearQueryPlans() {
+	queryPlans.Range(func(key, value interface{}) bool {
+		queryPlans.Delete(key)
+		return true
+	})
+}
+
+// selectPlanKey 查询计划键名, 选择字段含表达式或计算字段时不缓存
+func (mod *Model) selectPlanKey(alias string, columns []interface{}, exportPrefix string) (string, bool) {
+	if !QueryPlanCache {
+		return "", false
+	}
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		name, ok := col.(string)
+		if !ok {
+			return "", false
+		}
+		names = append(names, name)
+	}
+	return strings.Join([]string{mod.Name, alias, exportPrefix, strings.Join(names, ",")}, "\x00"), true
+}
+
+// selectPlan 读取缓存的字段解析结果, 写入字段映射表
+func (mod *Model) selectPlan(key string, cmap map[string]ColumnMap) ([]interface{}, bool) {
+	value, has := queryPlans.Load(key)
+	if !has {
+		atomic.AddInt64(&queryPlanMisses, 1)
+		return nil, false
+	}
+	atomic.AddInt64(&queryPlanHits, 1)
+	plan := value.(*selectPlan)
+	for name, column := range plan.columns {
+		column.Model = mod // 事务或上下文中的模型副本
+		cmap[name] = column
+	}
+	return append([]interface{}{}, plan.selects...), true
+}
+
+// storeSelectPlan 缓存字段解析结果
+func storeSelectPlan(key string, selects []interface{}, columns map[string]ColumnMap) {
+	queryPlans.Store(key, &selectPlan{
+		selects: append([]interface{}{}, selects...),
+		columns: columns,
+	})
+}
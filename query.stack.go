@@ -1,17 +1,51 @@
 package gou
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/kun/any"
 	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/kun/maps"
 	"github.com/yaoapp/xun"
+	"github.com/yaoapp/xun/dbal"
 	"github.com/yaoapp/xun/dbal/query"
 )
 
+// PaginateFormat 分页结果字段名称映射, 如 {"pagesize": "per_page"}
+// 可用字段: data, total, page, pagesize, pagecnt, last_page, next, prev, from, to; 映射为空字符串时不输出该字段
+var PaginateFormat = map[string]string{}
+
+// PaginateEstimate 分页总数按数据库统计信息估算 (QueryParam.Count)
+const PaginateEstimate = "estimate"
+
+// MaxPageSize 分页查询每页最大记录数, 超出时按最大记录数查询 (模型可通过 option.max_pagesize 单独设定), 为 0 时不限制
+var MaxPageSize = 1000
+
+// DefaultPageSize 分页查询未指定每页记录数 (pagesize <= 0) 时的默认值
+var DefaultPageSize = 20
+
+// PaginateSnapshot Model.PaginateCtx 未绑定事务时是否在同一事务中执行统计与数据查询 (数据库支持时读取同一快照), 默认不使用事务
+var PaginateSnapshot = false
+
+// HasManyLimit hasMany, morphMany 关联查询未指定 Limit 时每条上级记录最多读取的记录数, 为 0 时不限制
+var HasManyLimit = 100
+
+// rowNumber 关联数据按上级记录限制数量时的行号字段
+const rowNumber = "__row_number__"
+
+// windowUnsupported 不支持窗口函数的数据库驱动 (首次查询失败后记录)
+var windowUnsupported = sync.Map{}
+
 // QueryStack 查询栈
 type QueryStack struct {
-	Builders []QueryStackBuilder
-	Params   []QueryStackParam
-	Current  int
+	Builders   []QueryStackBuilder
+	Params     []QueryStackParam
+	Current    int
+	Statements int // 已执行的SQL语句数量
 }
 
 // QueryStackBuilder 查询构建器
@@ -19,6 +53,7 @@ type QueryStackBuilder struct {
 	Model     *Model
 	Query     query.Query
 	ColumnMap map[string]ColumnMap
+	Defaults  map[string]maps.MapStr // 关联数据默认值 (关联名称 => 默认值)
 }
 
 // QueryStackParam QueryStack 查询参数
@@ -26,6 +61,8 @@ type QueryStackParam struct {
 	QueryParam   QueryParam
 	Relation     Relation
 	ExportPrefix string // 字段导出前缀
+	Parent       int    // 上级查询器位置 (关联查询结果归集到上级查询结果)
+	HideKeys     bool   // 关联数据归集后移除关联键
 }
 
 // MakeQueryStack 创建查询栈
@@ -42,6 +79,50 @@ func NewQueryStack(param QueryParam) *QueryStack {
 	return param.Query(nil)
 }
 
+// withDefault 设定关联数据默认值
+func (builder *QueryStackBuilder) withDefault(name string, value maps.MapStr) {
+	if builder == nil || value == nil {
+		return
+	}
+	if builder.Defaults == nil {
+		builder.Defaults = map[string]maps.MapStr{}
+	}
+	builder.Defaults[name] = value
+}
+
+// fillDefaults 关联数据不存在 (LEFT JOIN 字段均为 NULL) 时使用默认值
+func (builder QueryStackBuilder) fillDefaults(row maps.MapStr) maps.MapStr {
+	for name, value := range builder.Defaults {
+		if !emptyRelation(row[name]) {
+			continue
+		}
+		row[name] = copyRow(value)
+	}
+	return row
+}
+
+// emptyRelation 关联数据是否为空 (不存在或字段均为 NULL)
+func emptyRelation(value interface{}) bool {
+	var fields map[string]interface{}
+	switch v := value.(type) {
+	case nil:
+		return true
+	case maps.MapStr:
+		fields = v
+	case map[string]interface{}:
+		fields = v
+	default:
+		return false
+	}
+
+	for _, field := range fields {
+		if field != nil && !emptyRelation(field) {
+			return false
+		}
+	}
+	return true
+}
+
 // Push 添加查询器
 func (stack *QueryStack) Push(builder QueryStackBuilder, param QueryStackParam) {
 	stack.Builders = append(stack.Builders, builder)
@@ -49,12 +130,19 @@ func (stack *QueryStack) Push(builder QueryStackBuilder, param QueryStackParam)
 	stack.Current = len(stack.Builders) - 1
 }
 
-// Merge 合并 Stack
+// Merge 合并 Stack, 被合并 Stack 的第一个查询器归属于当前查询器
 func (stack *QueryStack) Merge(new *QueryStack) {
 	curr := stack.Current
+	offset := len(stack.Builders)
 	for i, builder := range new.Builders {
+		param := new.Params[i]
+		if i == 0 {
+			param.Parent = curr
+		} else {
+			param.Parent = param.Parent + offset
+		}
 		stack.Builders = append(stack.Builders, builder)
-		stack.Params = append(stack.Params, new.Params[i])
+		stack.Params = append(stack.Params, param)
 	}
 	stack.Current = curr
 }
@@ -114,15 +202,19 @@ func (stack *QueryStack) Next() int {
 	return -1
 }
 
-// Run 执行查询栈
+// Run 执行查询栈 (每个查询器执行一次, 关联数据按上级查询结果批量读取)
 func (stack *QueryStack) Run() []maps.MapStrAny {
 	res := [][]maps.MapStrAny{}
+	stack.Current = 0
 	for i, qb := range stack.Builders {
 		param := stack.Params[i]
 		switch param.Relation.Type {
-		case "hasMany":
+		case "hasMany", "morphMany":
 			stack.runHasMany(&res, qb, param)
 			break
+		case "morphTo":
+			stack.runMorphTo(&res, qb, param)
+			break
 		default:
 			stack.run(&res, qb, param)
 		}
@@ -136,43 +228,227 @@ func (stack *QueryStack) Run() []maps.MapStrAny {
 
 // Paginate 执行查询栈(分页查询)
 func (stack *QueryStack) Paginate(page int, pagesize int) maps.MapStrAny {
+	response, _ := stack.paginatePage(nil, page, pagesize)
+	return response
+}
+
+// PaginateCtx 分页查询, 统计查询与数据查询之间、各关联查询之前检查 ctx, 已取消时返回 ctx.Err()
+func (stack *QueryStack) PaginateCtx(ctx context.Context, page int, pagesize int) (maps.MapStrAny, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return stack.paginatePage(ctx, page, pagesize)
+}
+
+// paginatePage 分页查询 (ctx 为 nil 时不检查)
+func (stack *QueryStack) paginatePage(ctx context.Context, page int, pagesize int) (maps.MapStrAny, error) {
 	res := [][]maps.MapStrAny{}
 	var pageInfo xun.P
+	stack.Current = 0
 	for i, qb := range stack.Builders {
 		param := stack.Params[i]
 		if i == 0 {
-			pageInfo = stack.paginate(page, pagesize, &res, qb, param)
+			if ctx == nil && param.QueryParam.Count != PaginateEstimate {
+				pageInfo = stack.paginate(page, pagesize, &res, qb, param)
+				continue
+			}
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			info, err := stack.paginateCtx(ctx, page, pagesize, &res, qb, param)
+			if err != nil {
+				return nil, err
+			}
+			pageInfo = info
 			continue
 		}
+		if ctx != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		switch param.Relation.Type {
-		case "hasMany":
+		case "hasMany", "morphMany":
 			stack.runHasMany(&res, qb, param)
 			break
+		case "morphTo":
+			stack.runMorphTo(&res, qb, param)
+			break
 		default:
 			stack.run(&res, qb, param)
 		}
 	}
 
 	if len(res) < 0 {
-		return nil
+		return nil, nil
+	}
+
+	from, to := 0, 0
+	if len(res[0]) > 0 {
+		from = (pageInfo.CurrentPage-1)*pageInfo.PageSize + 1
+		to = from + len(res[0]) - 1
+	}
+
+	values := map[string]interface{}{
+		"data":      res[0],
+		"total":     pageInfo.Total,
+		"page":      pageInfo.CurrentPage,
+		"pagesize":  pageInfo.PageSize,
+		"pagecnt":   pageInfo.TotalPages,
+		"last_page": pageInfo.TotalPages,
+		"next":      pageInfo.NextPage,
+		"prev":      pageInfo.PreviousPage,
+		"from":      from,
+		"to":        to,
 	}
 
 	response := maps.MapStrAny{}
-	response["data"] = res[0]
-	response["pagesize"] = pageInfo.PageSize
-	response["pagecnt"] = pageInfo.TotalPages
-	response["pagesize"] = pageInfo.PageSize
-	response["page"] = pageInfo.CurrentPage
-	response["next"] = pageInfo.NextPage
-	response["prev"] = pageInfo.PreviousPage
-	response["total"] = pageInfo.Total
-	return response
+	for field, value := range values {
+		key := field
+		if name, has := PaginateFormat[field]; has {
+			key = name
+		}
+		if key == "" { // 设置为空时不输出
+			continue
+		}
+		response[key] = value
+	}
+	return response, nil
 }
 
 func (stack *QueryStack) paginate(page int, pagesize int, res *[][]maps.MapStrAny, builder QueryStackBuilder, param QueryStackParam) xun.P {
 
-	rows := []xun.R{}
+	start := time.Now()
 	pageRes := builder.Query.MustPaginate(pagesize, page)
+	slowQuery("QueryStack paginate()", start, builder.Query)
+	builder.Model.stat(start, 2, len(pageRes.Items), 0)
+	stack.Statements = stack.Statements + 2 // count + select
+	stack.paginateRows(pageRes, res, builder)
+	return pageRes
+}
+
+// paginateCtx 分别执行统计查询和数据查询, 两次查询之间检查 ctx, 已取消时不再执行数据查询
+func (stack *QueryStack) paginateCtx(ctx context.Context, page int, pagesize int, res *[][]maps.MapStrAny, builder QueryStackBuilder, param QueryStackParam) (xun.P, error) {
+	mod := builder.Model
+	if page < 1 {
+		page = 1
+	}
+
+	total, estimated := 0, false
+	if param.QueryParam.Count == PaginateEstimate {
+		total, estimated = stack.estimateTotal(builder, param.QueryParam)
+	}
+
+	if !estimated {
+		qb := param.QueryParam.newQuery().SQL(fmt.Sprintf(
+			"SELECT COUNT(*) AS %s FROM (%s) AS %s",
+			mod.quote("__total__"), builder.Query.ToSQL(), mod.quote("__count__"),
+		), builder.Query.GetBindings()...)
+		start := time.Now()
+		counts, err := qb.Get()
+		slowQuery("QueryStack paginate()", start, qb)
+		stack.Statements++
+		if err != nil {
+			return xun.P{}, err
+		}
+		mod.stat(start, 1, 0, 0)
+		if len(counts) > 0 {
+			total = any.Of(counts[0]["__total__"]).CInt()
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return xun.P{}, err
+	}
+
+	start := time.Now()
+	rows, err := builder.Query.Limit(pagesize).Offset((page - 1) * pagesize).Get()
+	slowQuery("QueryStack paginate()", start, builder.Query)
+	stack.Statements++
+	if err != nil {
+		return xun.P{}, err
+	}
+	mod.stat(start, 1, len(rows), 0)
+
+	if estimated { // 统计信息滞后时估算总数可能小于实际数量, 按已读取的数据修正, 当前页已满时保留下一页
+		if read := (page-1)*pagesize + len(rows); total < read {
+			total = read
+		}
+		if len(rows) == pagesize && total <= page*pagesize {
+			total = page*pagesize + 1
+		}
+	}
+
+	items := []interface{}{}
+	for _, row := range rows {
+		items = append(items, row)
+	}
+	totalPages := (total + pagesize - 1) / pagesize
+	next, prev := page+1, page-1
+	if next > totalPages {
+		next = -1
+	}
+	if prev < 1 {
+		prev = -1
+	}
+	pageRes := xun.P{
+		Items:        items,
+		Total:        total,
+		TotalPages:   totalPages,
+		PageSize:     pagesize,
+		CurrentPage:  page,
+		NextPage:     next,
+		PreviousPage: prev,
+	}
+	stack.paginateRows(pageRes, res, builder)
+	return pageRes, nil
+}
+
+// estimateTotal 读取数据库统计信息估算数据表记录数 (MySQL information_schema.TABLES.TABLE_ROWS, PostgreSQL pg_class.reltuples)
+// 仅用于无查询条件的查询 (含查询范围、全文检索和软删除条件); 有查询条件、SQLite 或统计信息不可用时返回 false, 使用 COUNT 查询
+func (stack *QueryStack) estimateTotal(builder QueryStackBuilder, param QueryParam) (int, bool) {
+	mod := builder.Model
+	if len(param.Wheres) > 0 || (param.Search != nil && param.Search.Keyword != "") ||
+		(mod.MetaData.Option.SoftDeletes && !param.WithTrashed) {
+		return 0, false
+	}
+
+	sql := ""
+	bindings := []interface{}{}
+	switch mod.Driver {
+	case "mysql":
+		schema, table := "DATABASE()", mod.TableName()
+		if i := strings.Index(table, "."); i > 0 {
+			schema, table = "?", table[i+1:]
+			bindings = append(bindings, mod.TableName()[:i])
+		}
+		sql = fmt.Sprintf("SELECT TABLE_ROWS AS %s FROM information_schema.TABLES WHERE TABLE_SCHEMA = %s AND TABLE_NAME = ?", mod.quote("__total__"), schema)
+		bindings = append(bindings, table)
+	case "postgres":
+		sql = fmt.Sprintf("SELECT reltuples::bigint AS %s FROM pg_class WHERE oid = to_regclass(?)", mod.quote("__total__"))
+		bindings = append(bindings, mod.TableName())
+	default:
+		return 0, false
+	}
+
+	qb := param.newQuery().SQL(sql, bindings...)
+	start := time.Now()
+	rows, err := qb.Get()
+	slowQuery("QueryStack estimateTotal()", start, qb)
+	stack.Statements++
+	if err != nil || len(rows) == 0 || rows[0]["__total__"] == nil {
+		return 0, false
+	}
+	mod.stat(start, 1, 0, 0)
+
+	total := any.Of(rows[0]["__total__"]).CInt()
+	if total < 0 { // PostgreSQL 未执行过 ANALYZE 时为 -1
+		return 0, false
+	}
+	return total, true
+}
+
+// paginateRows 格式化分页数据并追加到查询结果
+func (stack *QueryStack) paginateRows(pageRes xun.P, res *[][]maps.MapStrAny, builder QueryStackBuilder) {
+	rows := []xun.R{}
 	for _, item := range pageRes.Items {
 		rows = append(rows, xun.MakeR(item))
 	}
@@ -189,11 +465,10 @@ func (stack *QueryStack) paginate(page int, pagesize int, res *[][]maps.MapStrAn
 			fmtRow[key] = value
 		}
 
-		fmtRows = append(fmtRows, fmtRow.UnDot())
+		fmtRows = append(fmtRows, builder.fillDefaults(fmtRow.UnDot()))
 	}
 	*res = append(*res, fmtRows)
 	stack.Next()
-	return pageRes
 }
 
 func (stack *QueryStack) run(res *[][]maps.MapStrAny, builder QueryStackBuilder, param QueryStackParam) {
@@ -209,7 +484,11 @@ func (stack *QueryStack) run(res *[][]maps.MapStrAny, builder QueryStackBuilder,
 			"bindings": builder.Query.Limit(limit).GetBindings()}).
 		Trace("QueryStack run()")
 
+	start := time.Now()
 	rows := builder.Query.Limit(limit).MustGet()
+	slowQuery("QueryStack run()", start, builder.Query)
+	builder.Model.stat(start, 1, len(rows), 0)
+	stack.Statements++
 	fmtRows := []maps.MapStr{}
 	for _, row := range rows {
 		fmtRow := maps.MapStr{}
@@ -221,7 +500,7 @@ func (stack *QueryStack) run(res *[][]maps.MapStrAny, builder QueryStackBuilder,
 			}
 			fmtRow[key] = value
 		}
-		fmtRows = append(fmtRows, fmtRow.UnDot())
+		fmtRows = append(fmtRows, builder.fillDefaults(fmtRow.UnDot()))
 	}
 	*res = append(*res, fmtRows)
 	stack.Next()
@@ -229,12 +508,19 @@ func (stack *QueryStack) run(res *[][]maps.MapStrAny, builder QueryStackBuilder,
 
 func (stack *QueryStack) runHasMany(res *[][]maps.MapStrAny, builder QueryStackBuilder, param QueryStackParam) {
 
-	// 获取上次查询结果，拼接结果集ID
-	rel := stack.Relation()
+	defer stack.Next()
+
+	// 获取上级查询结果，拼接结果集ID
+	rel := param.Relation
 	foreignIDs := []interface{}{}
-	prevRows := (*res)[len(*res)-1]
+	prevRows := (*res)[param.Parent]
+	exists := map[interface{}]bool{}
 	for _, row := range prevRows {
 		id := row.Get(rel.Foreign)
+		if id == nil || exists[id] {
+			continue
+		}
+		exists[id] = true
 		foreignIDs = append(foreignIDs, id)
 	}
 
@@ -254,12 +540,22 @@ func (stack *QueryStack) runHasMany(res *[][]maps.MapStrAny, builder QueryStackB
 		return
 	}
 
-	limit := 100
-	if param.QueryParam.Limit > 0 {
-		limit = param.QueryParam.Limit
+	// 批量读取全部上级记录的关联数据, Limit 按每条上级记录分别生效 (未指定时为 HasManyLimit)
+	limit := param.QueryParam.Limit
+	if limit <= 0 {
+		limit = HasManyLimit
+	}
+	var rows []xun.R
+	if limit > 0 {
+		rows = stack.limitedRows(builder, param, name, foreignIDs, limit)
+	} else {
+		builder.Query.WhereIn(name, foreignIDs)
+		start := time.Now()
+		rows = builder.Query.MustGet()
+		slowQuery("QueryStack runHasMany()", start, builder.Query)
+		builder.Model.stat(start, 1, len(rows), 0)
+		stack.Statements++
 	}
-	builder.Query.WhereIn(name, foreignIDs).Limit(limit)
-	rows := builder.Query.MustGet()
 
 	// 格式化数据
 	fmtRowMap := map[interface{}][]maps.MapStr{}
@@ -277,7 +573,10 @@ func (stack *QueryStack) runHasMany(res *[][]maps.MapStrAny, builder QueryStackB
 		relKey := rel.Key
 		relVal := fmtRow.Get(relKey)
 		if relVal != nil {
-			unDotRows := fmtRow.UnDot()
+			if limit > 0 && len(fmtRowMap[relVal]) >= limit {
+				continue
+			}
+			unDotRows := builder.fillDefaults(fmtRow.UnDot())
 			fmtRows = append(fmtRows, unDotRows)
 			if _, has := fmtRowMap[relVal]; !has {
 				fmtRowMap[relVal] = []maps.MapStr{}
@@ -286,6 +585,16 @@ func (stack *QueryStack) runHasMany(res *[][]maps.MapStrAny, builder QueryStackB
 		}
 	}
 
+	// 归集后移除关联键
+	if param.HideKeys {
+		for _, row := range fmtRows {
+			delete(row, rel.Key)
+			if rel.Type == RelMorphMany {
+				delete(row, rel.Morph)
+			}
+		}
+	}
+
 	// 追加到上一层
 	varname := rel.Name
 	// utils.Dump(fmtRows, rel.Foreign, varname, fmtRowMap, prevRows)
@@ -301,3 +610,141 @@ func (stack *QueryStack) runHasMany(res *[][]maps.MapStrAny, builder QueryStackB
 
 	*res = append(*res, fmtRows)
 }
+
+// limitedRows 读取关联数据, 每条上级记录最多 limit 条 (按关联查询的排序条件)
+// 使用窗口函数 ROW_NUMBER() OVER (PARTITION BY 外键) 一次读取; 数据库不支持窗口函数或按关联数据排序时,
+// 指定 Limit 按上级记录分别查询, 未指定 Limit (HasManyLimit) 时一次读取全部关联数据后按上级记录截取
+func (stack *QueryStack) limitedRows(builder QueryStackBuilder, param QueryStackParam, name string, foreignIDs []interface{}, limit int) []xun.R {
+	mod := builder.Model
+	_, unsupported := windowUnsupported.Load(mod.Driver)
+	if over, ok := param.QueryParam.windowOrder(mod); ok && !unsupported {
+		builder.Query.WhereIn(name, foreignIDs)
+		builder.Query.SelectAppend(dbal.Raw(fmt.Sprintf(
+			"ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS %s",
+			mod.quote(name), over, mod.quote(rowNumber),
+		)))
+		qb := mod.newQuery().SQL(fmt.Sprintf(
+			"SELECT * FROM (%s) AS %s WHERE %s <= %d ORDER BY %s",
+			builder.Query.ToSQL(), mod.quote("__window__"), mod.quote(rowNumber), limit, mod.quote(rowNumber),
+		), builder.Query.GetBindings()...)
+
+		start := time.Now()
+		rows, err := qb.Get()
+		slowQuery("QueryStack runHasMany()", start, qb)
+		stack.Statements++
+		if err == nil {
+			mod.stat(start, 1, len(rows), 0)
+			for _, row := range rows {
+				delete(row, rowNumber)
+			}
+			return rows
+		}
+		windowUnsupported.Store(mod.Driver, true)
+		log.Warn("QueryStack runHasMany() 不支持窗口函数, 按上级记录分别查询: %s", err.Error())
+	}
+
+	if param.QueryParam.Limit <= 0 {
+		qb := param.QueryParam.Query(nil, param).FirstQuery()
+		qb.WhereIn(name, foreignIDs)
+		start := time.Now()
+		rows := qb.MustGet()
+		slowQuery("QueryStack runHasMany()", start, qb)
+		mod.stat(start, 1, len(rows), 0)
+		stack.Statements++
+		return rows
+	}
+
+	rows := []xun.R{}
+	for _, id := range foreignIDs {
+		qb := param.QueryParam.Query(nil, param).FirstQuery()
+		qb.Where(name, id).Limit(limit)
+		start := time.Now()
+		items := qb.MustGet()
+		slowQuery("QueryStack runHasMany()", start, qb)
+		mod.stat(start, 1, len(items), 0)
+		stack.Statements++
+		rows = append(rows, items...)
+	}
+	return rows
+}
+
+// runMorphTo 多态关联查询
+// 上级查询结果按类型字段分组, 每个类型值执行一次 WhereIn 查询 (关联模型的 Withs 另计),
+// 查询次数与结果集中不同类型值的数量成正比, 与记录数量无关。类型较多时建议限制每页记录数量。
+func (stack *QueryStack) runMorphTo(res *[][]maps.MapStrAny, builder QueryStackBuilder, param QueryStackParam) {
+
+	defer stack.Next()
+
+	rel := param.Relation
+	prevRows := (*res)[param.Parent]
+	key := rel.Key
+	if key == "" {
+		key = "id"
+	}
+
+	// 按类型分组
+	types := []string{}
+	groups := map[string][]interface{}{}
+	for _, row := range prevRows {
+		typ := row.Get(rel.Morph)
+		id := row.Get(rel.Foreign)
+		if typ == nil || id == nil {
+			continue
+		}
+		name := fmt.Sprintf("%v", typ)
+		if _, has := groups[name]; !has {
+			types = append(types, name)
+		}
+		groups[name] = append(groups[name], id)
+	}
+
+	fmtRows := []maps.MapStr{}
+	related := map[string]map[interface{}]maps.MapStr{}
+	for _, typ := range types {
+		name := typ
+		if len(rel.Models) > 0 { // 已设置类型映射时, 仅读取映射中的模型
+			model, has := rel.Models[typ]
+			if !has {
+				log.With(log.F{"relation": rel.Name, "type": typ}).Warn("QueryStack runMorphTo() 类型 %s 未在 models 中定义", typ)
+				continue
+			}
+			name = model
+		}
+		if _, has := Models[name]; !has {
+			log.With(log.F{"relation": rel.Name, "type": typ}).Warn("QueryStack runMorphTo() 模型 %s 不存在", name)
+			continue
+		}
+
+		withParam := param.QueryParam
+		withParam.Model = name
+		withParam.Alias = ""
+		withParam.Wheres = append([]QueryWhere{}, withParam.Wheres...)
+		withParam.Wheres = append(withParam.Wheres, QueryWhere{Column: key, OP: "in", Value: groups[typ]})
+		withParam.keepColumn(key)
+		withParam.Limit = len(groups[typ])
+
+		typeStack := withParam.Query(nil)
+		rows := typeStack.Run()
+		stack.Statements = stack.Statements + typeStack.Statements
+
+		related[typ] = map[interface{}]maps.MapStr{}
+		for _, row := range rows {
+			fmtRows = append(fmtRows, row)
+			related[typ][row.Get(key)] = row
+		}
+	}
+
+	// 追加到上一层
+	varname := rel.Name
+	for idx, prow := range prevRows {
+		typ := prow.Get(rel.Morph)
+		if typ == nil {
+			continue
+		}
+		if row, has := related[fmt.Sprintf("%v", typ)][prow.Get(rel.Foreign)]; has {
+			prevRows[idx][varname] = row
+		}
+	}
+
+	*res = append(*res, fmtRows)
+}
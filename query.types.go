@@ -1,24 +1,60 @@
 package gou
 
+import (
+	"context"
+
+	"github.com/yaoapp/kun/maps"
+)
+
 // QueryParam 数据查询器参数
 type QueryParam struct {
-	Model    string          `json:"model,omitempty"`
-	Table    string          `json:"table,omitempty"`
-	Alias    string          `json:"alias,omitempty"`
-	Export   string          `json:"export,omitempty"` // 导出前缀
-	Select   []interface{}   `json:"select,omitempty"` // string | dbal.Raw
-	Wheres   []QueryWhere    `json:"wheres,omitempty"`
-	Orders   []QueryOrder    `json:"orders,omitempty"`
-	Limit    int             `json:"limit,omitempty"`
-	Page     int             `json:"page,omitempty"`
-	PageSize int             `json:"pagesize,omitempty"`
-	Withs    map[string]With `json:"withs,omitempty"`
+	Model       string          `json:"model,omitempty"`
+	Table       string          `json:"table,omitempty"`
+	Alias       string          `json:"alias,omitempty"`
+	Export      string          `json:"export,omitempty"` // 导出前缀
+	Select      []interface{}   `json:"select,omitempty"` // string | dbal.Raw | {"expr": "balance * 100", "as": "balance_cents"} (计算字段)
+	Except      []string        `json:"except,omitempty"` // 不读取的字段 (未指定 Select 时从全部可见字段中移除)
+	Fields      []string        `json:"fields,omitempty"` // 稀疏字段集 (如 id, name, manu.name), 覆盖 Select 并自动加载关联, 结果仅保留指定字段 (Find, Get, Paginate)
+	Wheres      []QueryWhere    `json:"wheres,omitempty"`
+	Orders      []QueryOrder    `json:"orders,omitempty"`
+	Limit       int             `json:"limit,omitempty"`
+	Page        int             `json:"page,omitempty"`
+	PageSize    int             `json:"pagesize,omitempty"`
+	Withs       map[string]With `json:"withs,omitempty"`
+	WithCount   []string        `json:"with_count,omitempty"`   // 关联数据计数, 结果字段为 {name}_count
+	Aggregates  []WithAggregate `json:"aggregates,omitempty"`   // 关联数据聚合, 结果字段为 {name}_{func}_{column}
+	Scopes      []string        `json:"scopes,omitempty"`       // 命名查询范围 (Model.Scope 注册)
+	WithTrashed bool            `json:"with_trashed,omitempty"` // 包含软删除的数据
+	Lock        string          `json:"lock,omitempty"`         // 行锁 update (FOR UPDATE), share (共享锁), 仅在事务中有效
+	Search      *QuerySearch    `json:"search,omitempty"`       // 全文检索 (与查询条件 AND 连接)
+	Count       string          `json:"count,omitempty"`        // 分页总数统计方式 exact (默认, COUNT 查询), estimate (无查询条件时读取数据库统计信息估算)
+	NoTiebreak  bool            `json:"no_tiebreak,omitempty"`  // 排序字段不含唯一字段时不追加主键排序 (默认追加, 保证分页边界确定)
+	offset      int             // 读取偏移量 (Model.Each 分批读取)
+	tx          *Tx             // 绑定的事务
+	ctx         context.Context // 绑定的上下文 (全局查询范围读取租户等信息)
+	without     []string        // 不应用的全局查询范围
+}
+
+// QuerySearch 全文检索, 任一字段匹配关键词即满足条件
+// 字段设置了全文索引 (fulltext) 时使用 MySQL MATCH ... AGAINST, PostgreSQL to_tsvector @@ plainto_tsquery, 否则 (含 SQLite) 使用 LIKE %keyword%
+type QuerySearch struct {
+	Columns []string `json:"columns,omitempty"` // 检索字段, 默认为全部设置全文索引的字段
+	Keyword string   `json:"keyword"`           // 关键词
 }
 
 // With relations 关联查询
 type With struct {
-	Name  string     `json:"name"`
-	Query QueryParam `json:"query,omitempty"`
+	Name     string      `json:"name"`
+	Query    QueryParam  `json:"query,omitempty"`
+	Default  maps.MapStr `json:"default,omitempty"`   // hasOne, hasOneThrough 关联数据不存在时的默认值; 未设置时返回字段均为 null 的对象
+	HideKeys bool        `json:"hide_keys,omitempty"` // hasMany, morphMany 关联数据不输出关联键 (如 addresses 中的 user_id) 和多态类型字段
+}
+
+// WithAggregate 关联数据聚合 (相关子查询), 支持 hasOne, hasMany, morphMany 关联
+type WithAggregate struct {
+	Rel    string `json:"rel"`              // 关联名称
+	Func   string `json:"func"`             // min, max, sum, avg, latest (最新一条关联数据的字段值, 按主键倒序)
+	Column string `json:"column,omitempty"` // 聚合字段, latest 默认为关联模型主键
 }
 
 // QueryWhere Where 查询条件
@@ -27,7 +63,7 @@ type QueryWhere struct {
 	Column interface{}  `json:"column,omitempty"`
 	Value  interface{}  `json:"value,omitempty"`
 	Method string       `json:"method,omitempty"` // where,orwhere, wherein, orwherein...
-	OP     string       `json:"op,omitempty"`     // 操作 eq/gt/lt/ge/le/like...
+	OP     string       `json:"op,omitempty"`     // 操作 eq/gt/lt/ge/le/like/match/ilike/imatch...
 	Wheres []QueryWhere `json:"wheres,omitempty"` // 分组查询
 }
 
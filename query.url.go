@@ -1,14 +1,16 @@
 package gou
 
 import (
+	"fmt"
 	"net/url"
+	"reflect"
 	"regexp"
 	"strings"
 
 	jsoniter "github.com/json-iterator/go"
 )
 
-const reURLWhereStr = "(where|orwhere|wherein|orwherein)\\.(.+)\\.(eq|gt|lt|ge|le|like|match|in|null|notnull)"
+const reURLWhereStr = "(where|orwhere|wherein|orwherein)\\.(.+)\\.(eq|gt|lt|ge|le|like|match|ilike|imatch|in|null|notnull|date_between)"
 
 var reURLWhere = regexp.MustCompile("^" + reURLWhereStr + "$")
 var reURLGroupWhere = regexp.MustCompile("^group\\.([a-zA-Z_]{1}[0-9a-zA-Z_]+)\\." + reURLWhereStr + "$")
@@ -34,6 +36,100 @@ func AnyToQueryParam(v interface{}) (QueryParam, bool) {
 	return params, true
 }
 
+// queryStructOPs 结构体标签支持的查询条件
+var queryStructOPs = map[string]bool{
+	"eq": true, "gt": true, "lt": true, "ge": true, "le": true, "ne": true,
+	"like": true, "match": true, "ilike": true, "imatch": true, "in": true, "null": true, "notnull": true, "json_contains": true,
+}
+
+// QueryParamFromStruct 按结构体字段标签生成查询条件, 标签格式 `query:"字段,条件,选项..."`
+// 字段默认为结构体字段名称的 snake_case, 关联字段为 manu.name; 条件默认为 eq (切片为 in); 未设置标签或标签为 - 的字段忽略
+// 选项: zero 零值字段也作为查询条件 (默认忽略零值字段, nil 指针始终忽略), or 以 orwhere 连接
+// 如 Mobile string `query:"mobile,like"`, Status *string `query:"status"`
+func QueryParamFromStruct(v interface{}) (QueryParam, error) {
+	param := QueryParam{Wheres: []QueryWhere{}}
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return param, fmt.Errorf("QueryParamFromStruct 参数应为结构体, 实际为 %T", v)
+	}
+
+	err := param.setStructWheres(value)
+	return param, err
+}
+
+// setStructWheres 按结构体字段添加查询条件 (含匿名嵌入结构体)
+func (param *QueryParam) setStructWheres(value reflect.Value) error {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, has := field.Tag.Lookup("query")
+		if !has && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := param.setStructWheres(value.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !has || tag == "-" || field.PkgPath != "" { // 未导出字段
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		column := strings.TrimSpace(parts[0])
+		if column == "" {
+			column = snakeCase(field.Name)
+		}
+
+		op := ""
+		if len(parts) > 1 {
+			op = strings.TrimSpace(parts[1])
+		}
+		if op != "" && !queryStructOPs[op] {
+			return fmt.Errorf("字段 %s: 不支持的查询条件 %s", field.Name, op)
+		}
+
+		zero, method := false, "where"
+		for j := 2; j < len(parts); j++ {
+			switch strings.TrimSpace(parts[j]) {
+			case "zero":
+				zero = true
+			case "or":
+				method = "orwhere"
+			}
+		}
+
+		fieldValue := value.Field(i)
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue, zero = fieldValue.Elem(), true
+		}
+		if !zero && fieldValue.IsZero() {
+			continue
+		}
+		if op == "" && (fieldValue.Kind() == reflect.Slice || fieldValue.Kind() == reflect.Array) {
+			op = "in"
+		}
+
+		rel := ""
+		if dot := strings.LastIndex(column, "."); dot > 0 {
+			rel, column = column[:dot], column[dot+1:]
+		}
+
+		param.Wheres = append(param.Wheres, QueryWhere{
+			Rel:    rel,
+			Column: column,
+			Method: method,
+			OP:     op,
+			Value:  fieldValue.Interface(),
+		})
+	}
+	return nil
+}
+
 // URLToQueryParam url.Values 转换为 QueryParams
 func URLToQueryParam(values url.Values) QueryParam {
 	param := QueryParam{
@@ -46,6 +142,12 @@ func URLToQueryParam(values url.Values) QueryParam {
 		if name == "select" {
 			param.setSelect(values.Get(name))
 			continue
+		} else if name == "except" {
+			param.setExcept(values.Get(name))
+			continue
+		} else if name == "fields" {
+			param.setFields(values.Get(name))
+			continue
 		} else if name == "order" {
 			param.setOrder(name, values.Get(name))
 			continue
@@ -94,6 +196,26 @@ func (param *QueryParam) setSelect(value string) {
 	param.Select = selects
 }
 
+// "except", "extra,secret" -> []string{"extra", "secret"}
+func (param *QueryParam) setExcept(value string) {
+	except := []string{}
+	for _, column := range strings.Split(value, ",") {
+		except = append(except, strings.TrimSpace(column))
+	}
+	param.Except = except
+}
+
+// "fields", "id,name,manu.name" -> []string{"id", "name", "manu.name"}
+func (param *QueryParam) setFields(value string) {
+	fields := []string{}
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	param.Fields = fields
+}
+
 // "group.types.where.type.eq", "admin"
 func (param *QueryParam) setGroupWhere(groups map[string][]QueryWhere, name string, value interface{}) {
 
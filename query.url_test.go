@@ -22,9 +22,74 @@ func TestQueryUrlValues(t *testing.T) {
 	params.Add("group.types.where.type.eq", "admin")
 	params.Add("group.types.orwhere.type.eq", "staff")
 	params.Add("order", "id.desc,name")
+	params.Add("except", "secret, extra")
 	param := URLToQueryParam(params)
 	assert.Equal(t, param.Select, []interface{}{"name", "secret", "status", "type"})
+	assert.Equal(t, []string{"secret", "extra"}, param.Except)
 	assert.Equal(t, len(param.Wheres), 7)
 	assert.Equal(t, len(param.Withs), 2)
 	assert.Equal(t, len(param.Orders), 2)
+
+	param = URLToQueryParam(url.Values{"where.key.imatch": []string{"fb3"}})
+	assert.Equal(t, []QueryWhere{{Method: "where", OP: "imatch", Column: "key", Value: "fb3"}}, param.Wheres)
+
+	param = URLToQueryParam(url.Values{"fields": []string{"id, name,manu.name,"}})
+	assert.Equal(t, []string{"id", "name", "manu.name"}, param.Fields)
+}
+
+type userFilterBase struct {
+	Status string `query:"status"`
+}
+
+type userFilter struct {
+	userFilterBase
+	Mobile   string  `query:"mobile,like"`
+	Type     *string `query:"type"`
+	IDs      []int   `query:"id"`
+	Balance  int     `query:",ge,zero"`
+	ManuName string  `query:"manu.name,eq,or"`
+	Secret   bool    `query:"secret,notnull"`
+	Ignored  string
+	Skipped  string   `query:"-"`
+	Empty    []string `query:"empty"`
+}
+
+func TestQueryParamFromStruct(t *testing.T) {
+	typ := ""
+	param, err := QueryParamFromStruct(&userFilter{
+		userFilterBase: userFilterBase{Status: "enabled"},
+		Mobile:         "1390000%",
+		Type:           &typ,
+		IDs:            []int{1, 2},
+		ManuName:       "北京",
+		Ignored:        "ignored",
+		Skipped:        "skipped",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []QueryWhere{
+		{Column: "status", Method: "where", Value: "enabled"},
+		{Column: "mobile", Method: "where", OP: "like", Value: "1390000%"},
+		{Column: "type", Method: "where", Value: ""},
+		{Column: "id", Method: "where", OP: "in", Value: []int{1, 2}},
+		{Column: "balance", Method: "where", OP: "ge", Value: 0},
+		{Rel: "manu", Column: "name", Method: "orwhere", OP: "eq", Value: "北京"},
+	}, param.Wheres)
+
+	res := Select("user").MustGet(mustQueryParamFromStruct(t, userFilter{IDs: []int{1, 2}}))
+	assert.Equal(t, 2, len(res))
+
+	_, err = QueryParamFromStruct("status")
+	assert.NotNil(t, err)
+	_, err = QueryParamFromStruct(struct {
+		Name string `query:"name,contains"`
+	}{Name: "x"})
+	assert.Contains(t, err.Error(), "contains")
+}
+
+func mustQueryParamFromStruct(t *testing.T, v interface{}) QueryParam {
+	param, err := QueryParamFromStruct(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return param
 }
@@ -1,9 +1,16 @@
 package gou
 
 import (
+	"fmt"
+	"net/url"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/maps"
 	"github.com/yaoapp/kun/utils"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal"
 )
 
 func TestQueryWhere(t *testing.T) {
@@ -56,6 +63,46 @@ func TestQueryOrWhere(t *testing.T) {
 	stack.Run()
 }
 
+func TestQueryWhereCaseInsensitive(t *testing.T) {
+	user := Select("user")
+	rows := user.MustGet(QueryParam{Select: []interface{}{"id", "key"}, Wheres: []QueryWhere{{Column: "key", OP: "imatch", Value: "3FXce"}}})
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "FB3fxCeQ", rows[0].Get("key"))
+
+	rows = user.MustGet(QueryParam{Select: []interface{}{"id"}, Wheres: []QueryWhere{
+		{Column: "key", OP: "ilike", Value: "fb3%"},
+		{Column: "key", OP: "ilike", Value: "jdh2%", Method: "orwhere"},
+	}})
+	assert.Equal(t, 2, len(rows))
+
+	sql, bindings := user.ilike("user.key", "ilike", "FB3%")
+	if user.Driver == "postgres" {
+		assert.Equal(t, `"user"."key" ILIKE ?`, sql)
+	} else {
+		assert.Contains(t, sql, "LOWER(")
+	}
+	assert.Equal(t, []interface{}{"FB3%"}, bindings)
+}
+
+func TestQueryHasOneDefault(t *testing.T) {
+	user := Select("user")
+	manuID := user.MustFind(1, QueryParam{Select: []interface{}{"id", "manu_id"}}).Get("manu_id")
+	capsule.Query().Table(user.TableName()).Where("id", 1).Update(maps.MapStr{"manu_id": 999})
+	defer capsule.Query().Table(user.TableName()).Where("id", 1).Update(maps.MapStr{"manu_id": manuID})
+
+	withs := map[string]With{"manu": {Name: "manu", Query: QueryParam{Select: []interface{}{"id", "name"}}}}
+	row := user.MustFind(1, QueryParam{Select: []interface{}{"id", "name"}, Withs: withs})
+	assert.Nil(t, row.Dot().Get("manu.name")) // 未设置默认值, 字段均为 null
+
+	withs["manu"] = With{Name: "manu", Query: QueryParam{Select: []interface{}{"id", "name"}}, Default: maps.MapStr{"name": "未知"}}
+	row = user.MustFind(1, QueryParam{Select: []interface{}{"id", "name"}, Withs: withs})
+	assert.Equal(t, "未知", row.Dot().Get("manu.name"))
+	assert.Nil(t, row.Dot().Get("manu.id"))
+
+	row = user.MustFind(2, QueryParam{Select: []interface{}{"id", "name"}, Withs: withs})
+	assert.NotEqual(t, "未知", row.Dot().Get("manu.name"))
+}
+
 func TestQueryHasOne(t *testing.T) {
 	param := QueryParam{
 		Model: "user",
@@ -409,3 +456,483 @@ func TestQueryHasManyAndOnePaginateOrder(t *testing.T) {
 	res := stack.Paginate(1, 2)
 	utils.Dump(res)
 }
+
+func TestQueryHasManyStatements(t *testing.T) {
+	param := QueryParam{
+		Model: "user",
+		Withs: map[string]With{
+			"manu":      {},
+			"addresses": {},
+		},
+		Select: []interface{}{"id", "name"},
+	}
+	stack := NewQueryStack(param)
+	res := stack.Run()
+	assert.Equal(t, 3, len(res))
+	assert.Equal(t, 2, stack.Statements) // 主查询 + addresses (批量读取)
+
+	for _, row := range res {
+		addresses, has := row["addresses"]
+		if !has {
+			continue
+		}
+		for _, address := range addresses.([]maps.MapStr) {
+			assert.Equal(t, row.Get("id"), address.Get("user_id"))
+		}
+	}
+
+	stack = NewQueryStack(param)
+	stack.Paginate(1, 2)
+	assert.Equal(t, 3, stack.Statements) // count + 主查询 + addresses
+}
+
+func TestQueryMorph(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "评论",
+		"table": { "name": "comment_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "commentable_type", "type": "string", "length": 40 },
+			{ "name": "commentable_id", "type": "bigInteger" },
+			{ "name": "content", "type": "string", "length": 200 }
+		],
+		"relations": {
+			"commentable": {
+				"type": "morphTo",
+				"key": "id",
+				"foreign": "commentable_id",
+				"morph": "commentable_type",
+				"query": { "select": ["id", "name"] }
+			}
+		}
+	}`, "comment_test")
+	mod.Migrate(true)
+	Select("user").MetaData.Relations["comments"] = Relation{
+		Type:    "morphMany",
+		Model:   "comment_test",
+		Key:     "commentable_id",
+		Foreign: "id",
+		Morph:   "commentable_type",
+	}
+	defer func() {
+		delete(Select("user").MetaData.Relations, "comments")
+		capsule.Schema().DropTableIfExists("comment_test")
+		delete(Models, "comment_test")
+	}()
+
+	mod.MustInsert(
+		[]string{"commentable_type", "commentable_id", "content"},
+		[][]interface{}{
+			{"user", 1, "用户评论"},
+			{"manu", 1, "厂商评论"},
+			{"user", 2, "用户评论2"},
+		})
+
+	stack := NewQueryStack(QueryParam{
+		Model:  "comment_test",
+		Withs:  map[string]With{"commentable": {}},
+		Orders: []QueryOrder{{Column: "id"}},
+	})
+	comments := stack.Run()
+	assert.Equal(t, 3, len(comments))
+	assert.Equal(t, 3, stack.Statements) // 主查询 + user + manu
+	assert.Equal(t, "管理员", comments[0].Dot().Get("commentable.name"))
+	assert.Equal(t, "北京云道天成科技有限公司", comments[1].Dot().Get("commentable.name"))
+	assert.Equal(t, int64(2), comments[2].Dot().Get("commentable.id"))
+
+	// 设置类型映射后, 未映射的类型不读取
+	rel := mod.MetaData.Relations["commentable"]
+	rel.Models = map[string]string{"user": "user"}
+	mod.MetaData.Relations["commentable"] = rel
+	comments = NewQueryStack(QueryParam{
+		Model:  "comment_test",
+		Withs:  map[string]With{"commentable": {}},
+		Orders: []QueryOrder{{Column: "id"}},
+	}).Run()
+	assert.Equal(t, "管理员", comments[0].Dot().Get("commentable.name"))
+	assert.Nil(t, comments[1].Get("commentable"))
+
+	user := Select("user").MustFind(1, QueryParam{Withs: map[string]With{"comments": {}}})
+	assert.Equal(t, 1, len(user.Get("comments").([]maps.MapStr)))
+	assert.Equal(t, "用户评论", user.Dot().Get("comments.0.content"))
+}
+
+func TestQueryNestedHasManyStatements(t *testing.T) {
+	Select("manu").MetaData.Relations["users"] = Relation{
+		Type:    "hasMany",
+		Model:   "user",
+		Key:     "manu_id",
+		Foreign: "id",
+	}
+	defer delete(Select("manu").MetaData.Relations, "users")
+
+	stack := NewQueryStack(QueryParam{
+		Model:  "manu",
+		Select: []interface{}{"id", "name"},
+		Wheres: []QueryWhere{{Column: "id", OP: "in", Value: []interface{}{1, 2}}},
+		Orders: []QueryOrder{{Column: "id"}},
+		Withs: map[string]With{
+			"users": {Query: QueryParam{
+				Select: []interface{}{"id", "name"},
+				Withs:  map[string]With{"addresses": {}},
+			}},
+		},
+	})
+	res := stack.Run()
+	assert.Equal(t, 3, stack.Statements) // 关联深度 + 1
+
+	assert.Equal(t, 2, len(res))
+	users := res[0].Get("users").([]maps.MapStr)
+	assert.Equal(t, 2, len(users))
+	total := 0
+	for _, manu := range res {
+		for _, user := range manu.Get("users").([]maps.MapStr) {
+			assert.Equal(t, manu.Get("id"), user.Get("manu_id"))
+			addresses, _ := user.Get("addresses").([]maps.MapStr)
+			for _, address := range addresses {
+				assert.Equal(t, user.Get("id"), address.Get("user_id"))
+			}
+			total = total + len(addresses)
+		}
+	}
+	assert.Equal(t, 4, total)
+}
+
+func TestQueryHasManyLimitPerParent(t *testing.T) {
+	res := NewQueryStack(QueryParam{
+		Model:  "user",
+		Select: []interface{}{"id"},
+		Orders: []QueryOrder{{Column: "id"}},
+		Withs:  map[string]With{"addresses": {Query: QueryParam{Limit: 1}}},
+	}).Run()
+	for _, row := range res {
+		addresses, _ := row.Get("addresses").([]maps.MapStr)
+		assert.Equal(t, 1, len(addresses))
+	}
+}
+
+func TestQueryHasManyDefaultLimit(t *testing.T) {
+	HasManyLimit = 1
+	defer func() { HasManyLimit = 100 }()
+	stack := NewQueryStack(QueryParam{
+		Model:  "user",
+		Select: []interface{}{"id"},
+		Withs:  map[string]With{"addresses": {}},
+	})
+	res := stack.Run()
+	assert.Equal(t, 2, stack.Statements)
+	for _, row := range res {
+		addresses, _ := row.Get("addresses").([]maps.MapStr)
+		assert.LessOrEqual(t, len(addresses), 1)
+	}
+}
+
+func TestQueryOrderTiebreak(t *testing.T) {
+	user := Select("user")
+	assert.Equal(t, []QueryOrder{{Column: "status"}, {Column: "id"}}, QueryParam{Orders: []QueryOrder{{Column: "status"}}}.tiebreak(user))
+	assert.Equal(t, 1, len(QueryParam{Orders: []QueryOrder{{Column: "key"}}}.tiebreak(user)))                         // 唯一字段
+	assert.Equal(t, 2, len(QueryParam{Orders: []QueryOrder{{Column: "manu_id"}, {Column: "mobile"}}}.tiebreak(user))) // 唯一索引
+	assert.Equal(t, 1, len(QueryParam{Orders: []QueryOrder{{Column: "status"}}, NoTiebreak: true}.tiebreak(user)))
+	assert.Empty(t, QueryParam{}.tiebreak(user))
+
+	rows := user.MustGet(QueryParam{Select: []interface{}{"id", "status"}, Orders: []QueryOrder{{Column: "status", Option: "desc"}}})
+	for i := 1; i < len(rows); i++ {
+		if rows[i].Get("status") == rows[i-1].Get("status") {
+			assert.True(t, any.Of(rows[i].Get("id")).CInt() > any.Of(rows[i-1].Get("id")).CInt())
+		}
+	}
+}
+
+func TestQueryHasManyLimitPerParentOrder(t *testing.T) {
+	res := NewQueryStack(QueryParam{
+		Model:  "user",
+		Select: []interface{}{"id"},
+		Orders: []QueryOrder{{Column: "id"}},
+		Withs: map[string]With{"addresses": {Query: QueryParam{
+			Limit:  1,
+			Orders: []QueryOrder{{Column: "id", Option: "desc"}},
+		}}},
+	}).Run()
+
+	address := Select("address")
+	for _, row := range res {
+		addresses, _ := row.Get("addresses").([]maps.MapStr)
+		assert.Equal(t, 1, len(addresses))
+		latest := address.MustGet(QueryParam{
+			Wheres: []QueryWhere{{Column: "user_id", Value: row.Get("id")}},
+			Orders: []QueryOrder{{Column: "id", Option: "desc"}},
+			Limit:  1,
+		})
+		assert.Equal(t, any.Of(latest[0].Get("id")).CInt(), any.Of(addresses[0].Get("id")).CInt())
+		assert.Nil(t, addresses[0].Get(rowNumber))
+	}
+}
+
+func TestQueryHasManyHideKeys(t *testing.T) {
+	param := QueryParam{
+		Model:  "user",
+		Select: []interface{}{"id"},
+		Orders: []QueryOrder{{Column: "id"}},
+		Withs:  map[string]With{"addresses": {Query: QueryParam{Select: []interface{}{"id", "location"}}}},
+	}
+	res := NewQueryStack(param).Run()
+	total := 0
+	for _, row := range res {
+		addresses, _ := row.Get("addresses").([]maps.MapStr)
+		for _, address := range addresses {
+			assert.True(t, address.Has("user_id"))
+		}
+		total = total + len(addresses)
+	}
+
+	param.Withs = map[string]With{"addresses": {Query: QueryParam{Select: []interface{}{"id", "location"}}, HideKeys: true}}
+	res = NewQueryStack(param).Run()
+	hidden := 0
+	for _, row := range res {
+		addresses, _ := row.Get("addresses").([]maps.MapStr)
+		for _, address := range addresses {
+			assert.False(t, address.Has("user_id"))
+			assert.NotNil(t, address.Get("id"))
+		}
+		hidden = hidden + len(addresses)
+	}
+	assert.Equal(t, total, hidden)
+	assert.Greater(t, hidden, 0)
+}
+
+func TestQuerySelectExpr(t *testing.T) {
+	res := NewQueryStack(QueryParam{
+		Model: "user",
+		Select: []interface{}{"id", "balance",
+			map[string]interface{}{"expr": "balance * 100 + 1", "as": "balance_cents"},
+			map[string]interface{}{"expr": "coalesce(balance, 0)", "as": "total"},
+		},
+		Orders: []QueryOrder{{Column: "id"}},
+	}).Run()
+	assert.Greater(t, len(res), 0)
+	for _, row := range res {
+		balance := any.Of(row.Get("balance")).CInt()
+		assert.Equal(t, balance*100+1, any.Of(row.Get("balance_cents")).CInt())
+		assert.Equal(t, balance, any.Of(row.Get("total")).CInt())
+	}
+
+	// 计算字段在关联查询 (hasOne) 中输出到关联数据
+	row := Select("user").MustFind(1, QueryParam{
+		Select: []interface{}{"id"},
+		Withs: map[string]With{"manu": {Query: QueryParam{
+			Select: []interface{}{"id", map[string]interface{}{"expr": "id * 10", "as": "id10"}},
+			Wheres: []QueryWhere{{Column: "id", OP: "ge", Value: 0}},
+		}}},
+	})
+	manu := row.Dot()
+	assert.Equal(t, any.Of(manu.Get("manu.id")).CInt()*10, any.Of(manu.Get("manu.id10")).CInt())
+
+	for _, expr := range []maps.MapStr{
+		{"expr": "balance * 100", "as": "balance"},          // 与模型字段重名
+		{"expr": "balance * 100", "as": "cents; drop"},      // 别名无效
+		{"expr": "password", "as": "p"},                     // 加密字段
+		{"expr": "sleep(1)", "as": "s"},                     // 函数不可用
+		{"expr": "balance -- comment", "as": "c"},           // 注释
+		{"expr": "concat(name, '\\') + balance", "as": "c"}, // 转义字符
+	} {
+		assert.Panics(t, func() {
+			NewQueryStack(QueryParam{Model: "user", Select: []interface{}{"id", expr}}).Run()
+		})
+	}
+}
+
+func TestQueryExcept(t *testing.T) {
+	res := NewQueryStack(QueryParam{
+		Model:  "user",
+		Except: []string{"extra", "mobile"},
+		Withs:  map[string]With{"addresses": {Query: QueryParam{Except: []string{"user_id", "location"}}}},
+	}).Run()
+	assert.Greater(t, len(res), 0)
+	addresses := 0
+	for _, row := range res {
+		assert.False(t, row.Has("extra"))
+		assert.False(t, row.Has("mobile"))
+		assert.True(t, row.Has("name"))
+		for _, address := range row.Get("addresses").([]maps.MapStr) {
+			assert.False(t, address.Has("location"))
+			assert.True(t, address.Has("city"))
+			addresses++
+		}
+	}
+	assert.Greater(t, addresses, 0) // 关联键不受 Except 影响
+
+	row := Select("user").MustFind(1, QueryParam{Select: []interface{}{"id", "name", "mobile"}, Except: []string{"mobile"}})
+	assert.Equal(t, 2, len(row))
+}
+
+func TestQueryWithCount(t *testing.T) {
+	stack := NewQueryStack(QueryParam{
+		Model:     "user",
+		Select:    []interface{}{"id", "name"},
+		WithCount: []string{"addresses"},
+		Orders:    []QueryOrder{{Column: "id"}},
+	})
+	res := stack.Run()
+	assert.Equal(t, 1, stack.Statements)
+	assert.Equal(t, 3, len(res))
+	assert.Equal(t, 2, any.Of(res[0].Get("addresses_count")).CInt())
+	assert.Equal(t, 1, any.Of(res[1].Get("addresses_count")).CInt())
+	assert.Nil(t, res[0].Get("addresses"))
+
+	assert.Panics(t, func() {
+		NewQueryStack(QueryParam{Model: "user", WithCount: []string{"undefined"}})
+	})
+}
+
+func TestQueryWithAggregates(t *testing.T) {
+	stack := NewQueryStack(QueryParam{
+		Model:  "user",
+		Select: []interface{}{"id", "name"},
+		Aggregates: []WithAggregate{
+			WithMax("addresses", "id"),
+			WithMin("addresses", "id"),
+			WithLatest("addresses"),
+			WithLatest("addresses", "location"),
+		},
+		Orders: []QueryOrder{{Column: "id"}},
+	})
+	res := stack.Run()
+	assert.Equal(t, 1, stack.Statements)
+	assert.Equal(t, 3, len(res))
+
+	latest := Select("address").MustGet(QueryParam{
+		Wheres: []QueryWhere{{Column: "user_id", Value: 1}},
+		Orders: []QueryOrder{{Column: "id", Option: "desc"}},
+		Limit:  1,
+	})[0]
+	assert.Equal(t, any.Of(latest.Get("id")).CInt(), any.Of(res[0].Get("addresses_max_id")).CInt())
+	assert.Equal(t, any.Of(latest.Get("id")).CInt(), any.Of(res[0].Get("addresses_latest")).CInt())
+	assert.Equal(t, latest.Get("location"), fmt.Sprintf("%s", res[0].Get("addresses_latest_location")))
+	assert.Less(t, any.Of(res[0].Get("addresses_min_id")).CInt(), any.Of(res[0].Get("addresses_max_id")).CInt())
+
+	assert.Panics(t, func() {
+		NewQueryStack(QueryParam{Model: "user", Aggregates: []WithAggregate{WithMax("addresses", "undefined")}})
+	})
+	assert.Panics(t, func() {
+		NewQueryStack(QueryParam{Model: "user", Aggregates: []WithAggregate{{Rel: "addresses", Func: "median", Column: "id"}}})
+	})
+}
+
+func TestQueryWhereColumn(t *testing.T) {
+	assert.Panics(t, func() {
+		NewQueryStack(QueryParam{Model: "user", Wheres: []QueryWhere{{Column: "id = 1 OR 1", Value: 1}}})
+	})
+	assert.Panics(t, func() {
+		NewQueryStack(QueryParam{Model: "user", Wheres: []QueryWhere{{Wheres: []QueryWhere{{Column: "undefined", Value: 1}}}}})
+	})
+	assert.Panics(t, func() {
+		NewQueryStack(QueryParam{Model: "user", Wheres: []QueryWhere{{Rel: "manu", Column: "mobile", Value: 1}}})
+	})
+	assert.Panics(t, func() {
+		NewQueryStack(QueryParam{Model: "user", Wheres: []QueryWhere{{Column: map[string]interface{}{"raw": "1"}, Value: 1}}})
+	})
+
+	// 关联模型字段, 代码中显式传入的原始表达式
+	res := NewQueryStack(QueryParam{
+		Model:  "user",
+		Select: []interface{}{"id"},
+		Wheres: []QueryWhere{
+			{Rel: "manu", Column: "name", OP: "notnull"},
+			{Column: dbal.Raw("1"), Value: 1},
+		},
+		Withs: map[string]With{"manu": {}},
+	}).Run()
+	assert.Greater(t, len(res), 0)
+}
+
+func TestQueryDateBetween(t *testing.T) {
+	mod := LoadModel(`{
+		"name": "日期范围",
+		"table": { "name": "date_between_test" },
+		"columns": [
+			{ "name": "id", "type": "ID" },
+			{ "name": "name", "type": "string", "length": 40 },
+			{ "name": "happened_at", "type": "datetime" },
+			{ "name": "day", "type": "date" }
+		],
+		"option": { "timezone": "Asia/Tokyo" }
+	}`, "date_between_test")
+	mod.Migrate(true)
+	defer func() {
+		capsule.Schema().DropTableIfExists("date_between_test")
+		delete(Models, "date_between_test")
+	}()
+
+	// 东京时间 (+09:00) 输入, 按本地时区写入数据库
+	mod.MustInsert([]string{"name", "happened_at", "day"}, [][]interface{}{
+		{"a", "2020-12-31T23:59:59+09:00", "2020-12-31"},
+		{"b", "2021-01-01T00:00:00+09:00", "2021-01-01"},
+		{"c", "2021-01-31T23:59:59+09:00", "2021-01-31"},
+		{"d", "2021-02-01T00:00:00+09:00", "2021-02-01"},
+	})
+	names := func(wheres ...QueryWhere) []interface{} {
+		res := []interface{}{}
+		for _, row := range mod.MustGet(QueryParam{Wheres: wheres, Orders: []QueryOrder{{Column: "id"}}}) {
+			res = append(res, row.Get("name"))
+		}
+		return res
+	}
+
+	assert.Equal(t, []interface{}{"b", "c"}, names(DateBetween("happened_at", "2021-01-01", "2021-01-31")))
+	assert.Equal(t, []interface{}{"b", "c"}, names(DateBetween("day", "2021-01-01", "2021-01-31")))
+	assert.Equal(t, []interface{}{"c", "d"}, names(QueryWhere{Column: "happened_at", OP: "date_between", Value: "2021-01-02,"}))
+	assert.Equal(t, []interface{}{"a", "b", "c", "d"}, names(DateBetween("happened_at", "", "")))
+	assert.Equal(t, []interface{}{"a", "b"}, names(Or(DateBetween("happened_at", "2021-01-01", "2021-01-01"), QueryWhere{Column: "name", Value: "a"})))
+
+	param := URLToQueryParam(url.Values{"where.happened_at.date_between": []string{"2021-01-01,2021-01-31"}})
+	assert.Equal(t, "date_between", param.Wheres[0].OP)
+	assert.Equal(t, []interface{}{"b", "c"}, names(param.Wheres...))
+
+	assert.Panics(t, func() { names(DateBetween("happened_at", "2021/01/01", "")) })
+	assert.Panics(t, func() { names(QueryWhere{Column: "happened_at", OP: "date_between", Value: "2021-01-01"}) })
+}
+
+func TestQueryPlanCache(t *testing.T) {
+	QueryPlanCache = true
+	ResetQueryPlans()
+	defer func() {
+		QueryPlanCache = false
+		ResetQueryPlans()
+	}()
+
+	param := func(status string) QueryParam {
+		return QueryParam{
+			Select: []interface{}{"id", "name", "mobile"},
+			Withs:  map[string]With{"manu": {}},
+			Wheres: []QueryWhere{{Column: "status", Value: status}},
+			Orders: []QueryOrder{{Column: "id"}},
+		}
+	}
+
+	QueryPlanCache = false
+	expected := Select("user").MustGet(param("enabled"))
+	QueryPlanCache = true
+
+	// 相同结构, 不同数值时复用查询计划
+	first := Select("user").MustGet(param("enabled"))
+	_, misses := QueryPlanStats()
+	assert.Equal(t, expected, first)
+	assert.True(t, misses > 0)
+
+	second := Select("user").MustGet(param("enabled"))
+	hits, _ := QueryPlanStats()
+	assert.Equal(t, expected, second)
+	assert.True(t, hits > 0)
+
+	assert.NotEqual(t, expected, Select("user").MustGet(param("disabled")))
+
+	// 重新载入模型时清空
+	plans := func() int {
+		n := 0
+		queryPlans.Range(func(key, value interface{}) bool { n++; return true })
+		return n
+	}
+	assert.True(t, plans() > 0)
+	Select("user").Reload()
+	assert.Equal(t, 0, plans())
+}
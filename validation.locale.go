@@ -0,0 +1,123 @@
+package gou
+
+import (
+	"context"
+	"strings"
+)
+
+// defaultLocale 默认语言, 模型描述中的校验消息 (message) 使用默认语言
+var defaultLocale = "zh"
+
+// localeMessages 多语言消息 locale => key => 消息模板
+var localeMessages = map[string]map[string]string{
+	"zh": {
+		"validation.typeof":    "{{input}}类型错误, {{label}}应为{{arg}}",
+		"validation.min":       "{{label}}不能小于{{arg}}",
+		"validation.max":       "{{label}}不能大于{{arg}}",
+		"validation.enum":      "{{input}}不在许可范围",
+		"validation.pattern":   "{{input}}格式错误",
+		"validation.minLength": "{{label}}长度不能小于{{arg}}",
+		"validation.maxLength": "{{label}}长度不能大于{{arg}}",
+		"validation.email":     "{{input}}不是有效的邮箱地址",
+		"validation.mobile":    "{{input}}不是有效的手机号",
+		"validation.required":  "{{label}}不能为空",
+		"validation.unique":    "{{label}} {{input}} 已存在",
+	},
+	"en": {
+		"validation.typeof":    "{{input}} has a wrong type, {{label}} should be {{arg}}",
+		"validation.min":       "{{label}} must be at least {{arg}}",
+		"validation.max":       "{{label}} must be at most {{arg}}",
+		"validation.enum":      "{{input}} is not an allowed value",
+		"validation.pattern":   "{{input}} has an invalid format",
+		"validation.minLength": "{{label}} must be at least {{arg}} characters",
+		"validation.maxLength": "{{label}} must be at most {{arg}} characters",
+		"validation.email":     "{{input}} is not a valid email address",
+		"validation.mobile":    "{{input}} is not a valid mobile number",
+		"validation.required":  "{{label}} is required",
+		"validation.unique":    "{{label}} {{input}} already exists",
+	},
+}
+
+type localeKey struct{}
+
+// SetDefaultLocale 设定默认语言 (默认 zh), 翻译缺失时使用默认语言的消息
+func SetDefaultLocale(locale string) {
+	defaultLocale = locale
+}
+
+// RegisterMessages 注册 (合并) 语言消息, 内置校验消息的键为 validation.{method}
+// 模型描述中校验消息 (message) 为已注册的键时, 按语言翻译
+func RegisterMessages(locale string, messages map[string]string) {
+	if _, has := localeMessages[locale]; !has {
+		localeMessages[locale] = map[string]string{}
+	}
+	for key, message := range messages {
+		localeMessages[locale][key] = message
+	}
+}
+
+// WithLocale 返回绑定语言的上下文 (Model.WithContext 后数据校验消息使用该语言)
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleOf 读取上下文中的语言
+func LocaleOf(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// translate 读取语言消息, 依次查找 locale (如 en-US), 基础语言 (en), fallback 为 true 时查找默认语言
+func translate(locale string, key string, fallback bool) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+
+	locales := []string{locale}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locales = append(locales, locale[:i])
+	}
+	if fallback {
+		locales = append(locales, defaultLocale)
+	}
+
+	for _, name := range locales {
+		if message, has := localeMessages[name][key]; has {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// message 按语言读取校验消息模板, 未指定语言时使用默认语言
+// 优先级: 描述中对应语言的消息 (messages), 消息翻译 (message 为已注册的键), 描述中的消息 (默认语言), 内置校验消息, 描述中的消息
+func (v Validation) message(locale string) string {
+	if locale == "" {
+		locale = defaultLocale
+	}
+
+	if message, has := v.Messages[locale]; has {
+		return message
+	}
+
+	if message, has := translate(locale, v.Message, true); has {
+		return message
+	}
+
+	if v.Message != "" && locale == defaultLocale {
+		return v.Message
+	}
+
+	if v.Message == "" {
+		message, _ := translate(locale, "validation."+v.Method, true)
+		return message
+	}
+
+	if message, has := translate(locale, "validation."+v.Method, false); has {
+		return message
+	}
+	return v.Message
+}
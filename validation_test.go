@@ -1,9 +1,11 @@
 package gou
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/maps"
 )
 
 func TestValidationTypeof(t *testing.T) {
@@ -110,3 +112,33 @@ func TestValidationMobile(t *testing.T) {
 	assert.False(t, ValidationMobile("xiang", nil))
 	assert.False(t, ValidationMobile(1, nil))
 }
+
+func TestValidationLocale(t *testing.T) {
+	user := Select("user")
+	row := maps.MapStr{"type": "guest"}
+
+	res := user.Validate(row)
+	assert.Equal(t, []string{"guest不在许可范围, 类型应该为 admin/staff/user"}, res[0].Messages)
+
+	res = user.Validate(row, "en")
+	assert.Equal(t, []string{"guest is not an allowed value"}, res[0].Messages)
+
+	res = user.WithContext(WithLocale(context.Background(), "en-US")).Validate(row)
+	assert.Equal(t, []string{"guest is not an allowed value"}, res[0].Messages)
+
+	res = user.Validate(row, "fr")
+	assert.Equal(t, []string{"guest不在许可范围, 类型应该为 admin/staff/user"}, res[0].Messages)
+
+	RegisterMessages("en", map[string]string{"user.type.enum": "{{label}} must be one of admin/staff/user"})
+	RegisterMessages("zh", map[string]string{"user.type.enum": "{{label}}应该为 admin/staff/user"})
+	defer delete(localeMessages["en"], "user.type.enum")
+	defer delete(localeMessages["zh"], "user.type.enum")
+
+	v := Validation{Method: "enum", Message: "user.type.enum", Messages: map[string]string{"ja": "{{input}}は無効です"}}
+	assert.Equal(t, "{{label}} must be one of admin/staff/user", v.message("en"))
+	assert.Equal(t, "{{label}}应该为 admin/staff/user", v.message(""))
+	assert.Equal(t, "{{label}}应该为 admin/staff/user", v.message("fr"))
+	assert.Equal(t, "{{input}}は無効です", v.message("ja"))
+	assert.Equal(t, "{{input}} has an invalid format", Validation{Method: "pattern"}.message("en"))
+	assert.Equal(t, "{{input}}格式错误", Validation{Method: "pattern"}.message(""))
+}
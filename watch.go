@@ -0,0 +1,156 @@
+package gou
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+)
+
+// DevMode 开发模式, 仅在开发模式下 WatchDefinitions 生效 (默认关闭, 生产环境请勿开启)
+var DevMode = false
+
+// WatchDelay 文件变更后重新加载的延迟 (延迟内的连续变更只重新加载一次)
+var WatchDelay = 300 * time.Millisecond
+
+var watcher *fsnotify.Watcher
+var watchTimers = map[string]*time.Timer{}
+var watchLock sync.Mutex
+
+// WatchDefinitions 监听目录 (含子目录) 中的模型和 API 描述文件, 文件变更后重新加载对应的模型或 API
+// 仅在开发模式 (DevMode) 下可用; API 重新加载后更新 APIs, 已注册的路由需重启服务生效
+func WatchDefinitions(dirs ...string) error {
+	if !DevMode {
+		return fmt.Errorf("WatchDefinitions 仅在开发模式 (DevMode) 下可用")
+	}
+
+	watchLock.Lock()
+	defer watchLock.Unlock()
+	if watcher == nil {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		watcher = w
+		go watch(w)
+	}
+
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return watcher.Add(name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopWatchDefinitions 停止监听描述文件
+func StopWatchDefinitions() {
+	watchLock.Lock()
+	defer watchLock.Unlock()
+	if watcher == nil {
+		return
+	}
+	watcher.Close()
+	watcher = nil
+	for name, timer := range watchTimers {
+		timer.Stop()
+		delete(watchTimers, name)
+	}
+}
+
+// watch 处理文件变更事件
+func watch(w *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			filename, err := filepath.Abs(event.Name)
+			if err != nil {
+				continue
+			}
+			watchLock.Lock()
+			if timer, has := watchTimers[filename]; has {
+				timer.Stop()
+			}
+			watchTimers[filename] = time.AfterFunc(WatchDelay, func() {
+				watchLock.Lock()
+				delete(watchTimers, filename)
+				watchLock.Unlock()
+				reloadDefinition(filename)
+			})
+			watchLock.Unlock()
+
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Error("WatchDefinitions: %s", err.Error())
+		}
+	}
+}
+
+// reloadDefinition 重新加载来源为指定文件的模型和 API
+func reloadDefinition(filename string) {
+	for name, mod := range Models {
+		if sourceFile(mod.Source) != filename {
+			continue
+		}
+		err := reloadModel(mod)
+		if err != nil {
+			log.Error("模型 %s 重新加载失败 (%s): %s", name, filename, err.Error())
+			continue
+		}
+		log.Info("模型 %s 已重新加载 (%s)", name, filename)
+	}
+
+	for name, api := range APIs {
+		if sourceFile(api.Source) != filename {
+			continue
+		}
+		_, err := LoadAPIReturn(api.Source, name)
+		if err != nil {
+			log.Error("API %s 重新加载失败 (%s): %s", name, filename, err.Error())
+			continue
+		}
+		log.Info("API %s 已重新加载 (%s)", name, filename)
+	}
+}
+
+// reloadModel 重新加载模型, 返回加载错误
+func reloadModel(mod *Model) (err error) {
+	defer func() { err = exception.Catch(recover()) }()
+	mod.Reload()
+	return nil
+}
+
+// sourceFile 描述来源 (file://) 对应的文件绝对路径, 非文件来源返回空字符串
+func sourceFile(source string) string {
+	if !strings.HasPrefix(source, "file://") {
+		return ""
+	}
+	filename, err := filepath.Abs(strings.TrimPrefix(source, "file://"))
+	if err != nil {
+		return ""
+	}
+	return filename
+}